@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/secureta/s2http-request/internal/config"
+	"github.com/secureta/s2http-request/internal/parser"
+)
+
+// sendBaseline はmeta.baselineのベースラインを送信してレスポンスを返す。
+// meta.baselineがない場合やドライランの場合はnilを返す。
+// ベースラインの送信に失敗した場合は、差分を記録せずに組み合わせの送信を続ける。
+func (d *dispatcher) sendBaseline(requests *parser.RequestIterator, requestConfig *config.RequestConfig) (*config.ResponseData, error) {
+	if requestConfig.Meta == nil || requestConfig.Meta.Baseline == nil || d.renderer != nil {
+		return nil, nil
+	}
+
+	request, err := requests.Baseline()
+	if err != nil {
+		return nil, err
+	}
+	d.applyUserAgent(request)
+
+	response, err := d.send(request)
+	if err != nil {
+		log.Printf("Failed to send baseline request (%s %s): %v", request.Method, maskQuery(request.URL, request.Secrets), err)
+		return nil, nil
+	}
+	if d.cliConfig.Verbose {
+		fmt.Fprintf(d.verboseOut, "Baseline: %s %s -> %d\n", request.Method, maskQuery(request.URL, request.Secrets), response.StatusCode)
+	}
+	return response, nil
+}
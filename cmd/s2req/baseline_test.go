@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+	httpClient "github.com/secureta/s2http-request/internal/http"
+	"github.com/secureta/s2http-request/internal/parser"
+)
+
+func TestProcessRequestConfigs_ComparesWithBaseline(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		queries = append(queries, q)
+		if strings.Contains(q, "'") {
+			w.Header().Set("X-Error", "sql")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("You have an error in your SQL syntax"))
+			return
+		}
+		_, _ = w.Write([]byte("<html><body>No results for " + q + "</body></html>"))
+	}))
+	defer server.Close()
+
+	client, err := httpClient.NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	content := `method: GET
+path: /search
+query:
+  q: {$dict: payload}
+dict:
+  payload: ["book", "' OR 1=1--"]
+meta:
+  baseline:
+    values:
+      payload: hello
+`
+	p := parser.NewParser()
+	configs, err := p.ParseMultiple([]byte(content), ".yaml", "baseline.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	d, err := newDispatcher(client, &config.CLIConfig{Host: server.URL, Timeout: 5 * time.Second, Concurrency: 1}, "")
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	var results []*config.Result
+	if err := processRequestConfigs(p, d, configs, "baseline.yaml", nil, func(result *config.Result) {
+		results = append(results, result)
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// ベースラインは組み合わせより先に送信され、結果には含まれない
+	if strings.Join(queries, ",") != "hello,book,' OR 1=1--" {
+		t.Errorf("Unexpected request order: %q", queries)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	reflected := results[0].Baseline
+	if reflected == nil || reflected.Flagged || reflected.StatusChanged || reflected.LengthDelta != -1 {
+		t.Errorf("Expected reflected payload not to be flagged, got %+v", reflected)
+	}
+
+	injected := results[1].Baseline
+	if injected == nil || !injected.Flagged || !injected.StatusChanged || injected.Status != 200 {
+		t.Fatalf("Expected SQL error to be flagged, got %+v", injected)
+	}
+	if len(injected.HeadersAdded) != 1 || injected.HeadersAdded[0] != "X-Error" {
+		t.Errorf("Expected X-Error to be reported as added, got %v", injected.HeadersAdded)
+	}
+}
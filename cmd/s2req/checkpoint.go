@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// checkpointEntry は完了した1件のリクエストを識別する。
+// Combinationはdictのキー順で決まる組み合わせのインデックス（dictなしの場合は0）。
+type checkpointEntry struct {
+	File        string `json:"file"`
+	Document    int    `json:"document"`
+	Combination int    `json:"combination"`
+}
+
+// checkpoint は完了したリクエストを1行1件のJSONとして追記記録する。
+// 終了時にまとめて出力するフォーマットでは、記録した結果をresultSpoolに退避しておく。
+type checkpoint struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[checkpointEntry]bool
+	err  error
+}
+
+// openCheckpoint はチェックポイントファイルを開く。
+// resumeが有効な場合は既存の記録を読み込み、無効な場合は記録を破棄して開始する。
+func openCheckpoint(path string, resume bool) (*checkpoint, error) {
+	done := make(map[checkpointEntry]bool)
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if resume {
+		entries, err := loadCheckpoint(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			done[entry] = true
+		}
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	// The CLI intentionally writes progress to a user-supplied checkpoint path.
+	file, err := os.OpenFile(path, flags, 0600) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint file: %w", err)
+	}
+	return &checkpoint{
+		file: file,
+		done: done,
+	}, nil
+}
+
+// loadCheckpoint はチェックポイントファイルから完了済みの記録を読み込む。
+// ファイルが存在しない場合は空として扱う。
+func loadCheckpoint(path string) ([]checkpointEntry, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+	return parseCheckpoint(bytes.NewReader(data))
+}
+
+// parseCheckpoint はチェックポイントの各行を解析する。
+// 書き込み途中で中断された最終行は読み飛ばす。
+func parseCheckpoint(r io.Reader) ([]checkpointEntry, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+
+	entries := make([]checkpointEntry, 0, len(lines))
+	for i, line := range lines {
+		var entry checkpointEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return nil, fmt.Errorf("invalid checkpoint entry at line %d: %w", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// readLines は空行を除いた各行を返す
+func readLines(r io.Reader) ([][]byte, error) {
+	var lines [][]byte
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			lines = append(lines, line)
+		}
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// completed は記録済みの件数を返す
+func (c *checkpoint) completed() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.done)
+}
+
+// isDone はリクエストが前回の実行で完了しているかを返す
+func (c *checkpoint) isDone(entry checkpointEntry) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done[entry]
+}
+
+// markDone はリクエストの完了を記録する。書き込みエラーは記録され、Closeで返される。
+func (c *checkpoint) markDone(entry checkpointEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.done[entry] = true
+	c.write(entry)
+}
+
+func (c *checkpoint) write(entry checkpointEntry) {
+	if c.err != nil {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		c.err = fmt.Errorf("failed to format checkpoint entry: %w", err)
+		return
+	}
+	// 1行を1回の書き込みで追記し、中断時に壊れた行が残りにくいようにする
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		c.err = fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+}
+
+// Close はチェックポイントファイルを閉じる
+func (c *checkpoint) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.file.Close(); err != nil && c.err == nil {
+		c.err = fmt.Errorf("failed to close checkpoint file: %w", err)
+	}
+	return c.err
+}
+
+// checkpointSource は完了済みの組み合わせを読み飛ばすrequestSource
+type checkpointSource struct {
+	requestSource
+	checkpoint *checkpoint
+	file       string
+	document   int
+}
+
+// filter はrequestsから完了済みの組み合わせを除いたrequestSourceを返す
+func (c *checkpoint) filter(requests requestSource, file string, document int) requestSource {
+	return &checkpointSource{
+		requestSource: requests,
+		checkpoint:    c,
+		file:          file,
+		document:      document,
+	}
+}
+
+func (s *checkpointSource) Next() bool {
+	for s.requestSource.Next() {
+		entry := checkpointEntry{File: s.file, Document: s.document, Combination: s.requestSource.Index()}
+		if !s.checkpoint.isDone(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// resultSpool は終了時にまとめて出力するフォーマット（json、csv、table）で、出力前の結果を1行1件のJSONとして退避する。
+// 途中で強制終了しても、チェックポイントに記録した組み合わせの結果を再開時に出力できる。
+type resultSpool struct {
+	path string
+	file *os.File
+	err  error
+}
+
+// spoolPath はチェックポイントに対応する退避ファイルのパスを返す
+func spoolPath(checkpointPath string) string {
+	return checkpointPath + ".results"
+}
+
+// openResultSpool は退避ファイルを開く。
+// resumeが有効な場合は前回の実行で出力されなかった結果を返し、無効な場合は退避ファイルを空にして開始する。
+func openResultSpool(path string, resume bool) (*resultSpool, []*config.Result, error) {
+	var recovered []*config.Result
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if resume {
+		var err error
+		recovered, err = loadResultSpool(path)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	// The CLI intentionally writes the spool next to the user-supplied checkpoint path.
+	file, err := os.OpenFile(path, flags, 0600) // #nosec G304
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open result spool: %w", err)
+	}
+	return &resultSpool{path: path, file: file}, recovered, nil
+}
+
+// loadResultSpool は退避ファイルの結果を読み込む。書き込み途中で中断された最終行は読み飛ばす。
+func loadResultSpool(path string) ([]*config.Result, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result spool: %w", err)
+	}
+	lines, err := readLines(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result spool: %w", err)
+	}
+
+	results := make([]*config.Result, 0, len(lines))
+	for i, line := range lines {
+		var result config.Result
+		if err := json.Unmarshal(line, &result); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return nil, fmt.Errorf("invalid result spool entry at line %d: %w", i+1, err)
+		}
+		results = append(results, &result)
+	}
+	return results, nil
+}
+
+// write は結果を1行追記する。チェックポイントに記録する前に呼び出す。
+func (s *resultSpool) write(result *config.Result) {
+	if s.err != nil {
+		return
+	}
+	line, err := json.Marshal(result)
+	if err != nil {
+		s.err = fmt.Errorf("failed to format result spool entry: %w", err)
+		return
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		s.err = fmt.Errorf("failed to write result spool: %w", err)
+	}
+}
+
+// Close は退避ファイルを閉じる。
+// 結果の出力に成功した場合（commit）は退避した結果が不要になるため削除し、失敗した場合は次回の再開用に残す。
+func (s *resultSpool) Close(commit bool) error {
+	if err := s.file.Close(); err != nil && s.err == nil {
+		s.err = fmt.Errorf("failed to close result spool: %w", err)
+	}
+	if commit && s.err == nil {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			s.err = fmt.Errorf("failed to remove result spool: %w", err)
+		}
+	}
+	return s.err
+}
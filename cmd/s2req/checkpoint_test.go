@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+	httpClient "github.com/secureta/s2http-request/internal/http"
+	"github.com/secureta/s2http-request/internal/parser"
+)
+
+func TestParseCheckpoint(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  []checkpointEntry
+		wantError bool
+	}{
+		{
+			name:  "complete entries",
+			input: "{\"file\":\"a.yaml\",\"document\":0,\"combination\":1}\n{\"file\":\"a.yaml\",\"document\":1,\"combination\":0}\n",
+			expected: []checkpointEntry{
+				{File: "a.yaml", Document: 0, Combination: 1},
+				{File: "a.yaml", Document: 1, Combination: 0},
+			},
+		},
+		{
+			name:     "truncated last line is ignored",
+			input:    "{\"file\":\"a.yaml\",\"document\":0,\"combination\":1}\n{\"file\":\"a.ya",
+			expected: []checkpointEntry{{File: "a.yaml", Document: 0, Combination: 1}},
+		},
+		{
+			name:      "corrupted entry in the middle",
+			input:     "broken\n{\"file\":\"a.yaml\",\"document\":0,\"combination\":1}\n",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := parseCheckpoint(strings.NewReader(tt.input))
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(entries) != len(tt.expected) {
+				t.Fatalf("Expected %d entries, got %d: %v", len(tt.expected), len(entries), entries)
+			}
+			for i := range entries {
+				if entries[i] != tt.expected[i] {
+					t.Errorf("Entry %d: expected %v, got %v", i, tt.expected[i], entries[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBufferedSink_SpoolsResultsForResume(t *testing.T) {
+	dir := t.TempDir()
+	checkpointPath := filepath.Join(dir, "progress.ckpt")
+	outputPath := filepath.Join(dir, "results.json")
+	cliConfig := &config.CLIConfig{Output: outputPath, Format: config.OutputFormatJSON, Checkpoint: checkpointPath}
+
+	// 1回目の実行: 出力せずに終了した（強制終了）場合も退避ファイルに結果が残る
+	sink, err := newResultSink(cliConfig)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	sink.Emit(&config.Result{Request: config.ProcessedRequest{URL: "http://example.com/1"}})
+	recovered, err := loadResultSpool(spoolPath(checkpointPath))
+	if err != nil || len(recovered) != 1 {
+		t.Fatalf("Expected 1 spooled result, got %d (%v)", len(recovered), err)
+	}
+
+	// 2回目の実行（--resume）: 退避した結果と新しい結果をまとめて出力し、退避ファイルを削除する
+	cliConfig.Resume = true
+	sink, err = newResultSink(cliConfig)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	sink.Emit(&config.Result{Request: config.ProcessedRequest{URL: "http://example.com/2"}})
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	var results []*config.Result
+	if err := json.Unmarshal(data, &results); err != nil {
+		t.Fatalf("Invalid output: %v", err)
+	}
+	if len(results) != 2 || results[0].Request.URL != "http://example.com/1" || results[1].Request.URL != "http://example.com/2" {
+		t.Errorf("Expected the spooled and the new result, got %s", data)
+	}
+	if _, err := os.Stat(spoolPath(checkpointPath)); !os.IsNotExist(err) {
+		t.Errorf("Expected the result spool to be removed after output, got %v", err)
+	}
+}
+
+func TestProcessRequestConfigs_ResumeSkipsCompletedCombinations(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r.URL.Query().Get("a")+r.URL.Query().Get("b"))
+		mu.Unlock()
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client, err := httpClient.NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// キー順（a, b）の直積: 0=1x, 1=1y, 2=2x, 3=2y
+	p := parser.NewParser()
+	configs, err := p.ParseMultiple([]byte(`{"method":"GET","path":"/","query":{"a":{"$dict":"a"},"b":{"$dict":"b"}},"dict":{"b":["x","y"],"a":["1","2"]}}`), ".json", "sweep.json")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "progress.ckpt")
+	previous := "{\"file\":\"sweep.json\",\"document\":0,\"combination\":0}\n{\"file\":\"sweep.json\",\"document\":0,\"combination\":2}\n"
+	if err := os.WriteFile(path, []byte(previous), 0600); err != nil {
+		t.Fatalf("Failed to write checkpoint: %v", err)
+	}
+
+	cliConfig := &config.CLIConfig{Host: server.URL, Timeout: 5 * time.Second, MaxCombinations: 10, Concurrency: 2}
+	d, err := newDispatcher(client, cliConfig, "")
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+	d.checkpoint, err = openCheckpoint(path, true)
+	if err != nil {
+		t.Fatalf("Failed to open checkpoint: %v", err)
+	}
+
+	var results []*config.Result
+	if err := processRequestConfigs(p, d, configs, "sweep.json", nil, func(result *config.Result) {
+		results = append(results, result)
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := d.checkpoint.Close(); err != nil {
+		t.Fatalf("Failed to close checkpoint: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if len(received) != 2 || !containsAll(received, "1y", "2y") {
+		t.Errorf("Expected only 1y and 2y to be sent, got %v", received)
+	}
+
+	entries, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("Failed to load checkpoint: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("Expected 4 completed entries, got %d: %v", len(entries), entries)
+	}
+}
+
+func TestAppendResults(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("json array is extended", func(t *testing.T) {
+		path := filepath.Join(dir, "results.json")
+		cliConfig := &config.CLIConfig{Output: path, Format: config.OutputFormatJSON}
+		if err := outputResults([]*config.Result{newTestResult("http://example.com/a", 200)}, cliConfig); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		cliConfig.Resume = true
+		if err := outputResults([]*config.Result{newTestResult("http://example.com/b", 404)}, cliConfig); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		var results []config.Result
+		if err := json.Unmarshal(data, &results); err != nil {
+			t.Fatalf("Output is not a JSON array: %v", err)
+		}
+		if len(results) != 2 || results[1].Request.URL != "http://example.com/b" {
+			t.Errorf("Unexpected merged results: %+v", results)
+		}
+	})
+
+	t.Run("csv header is not repeated", func(t *testing.T) {
+		path := filepath.Join(dir, "results.csv")
+		cliConfig := &config.CLIConfig{Output: path, Format: config.OutputFormatCSV, Resume: true}
+		// 既存ファイルがない場合はヘッダー付きで作成される
+		if err := outputResults([]*config.Result{newTestResult("http://example.com/a", 200)}, cliConfig); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := outputResults([]*config.Result{newTestResult("http://example.com/b", 404)}, cliConfig); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		lines := strings.Split(string(data), "\n")
+		if len(lines) != 3 || !strings.HasPrefix(lines[0], "Method,") || !strings.Contains(lines[2], "/b,404") {
+			t.Errorf("Unexpected CSV output: %q", string(data))
+		}
+	})
+}
+
+func containsAll(values []string, want ...string) bool {
+	set := make(map[string]bool)
+	for _, v := range values {
+		set[v] = true
+	}
+	for _, w := range want {
+		if !set[w] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestHelperProcess はTestCheckpoint_ResumeAfterKillからs2reqとして起動されるプロセス
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("S2REQ_HELPER_PROCESS") != "1" {
+		t.Skip("helper process")
+	}
+	args := strings.Split(os.Getenv("S2REQ_HELPER_ARGS"), "\n")
+	os.Args = append([]string{"s2req"}, args...)
+	main()
+	os.Exit(0)
+}
+
+func TestCheckpoint_ResumeAfterKill(t *testing.T) {
+	// 3件目（a=3）は1回目の実行中は応答せず、その間にプロセスを強制終了する
+	var mu sync.Mutex
+	var received []string
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.URL.Query().Get("a")
+		mu.Lock()
+		received = append(received, value)
+		mu.Unlock()
+		if value == "3" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	requestPath := filepath.Join(dir, "sweep.json")
+	if err := os.WriteFile(requestPath, []byte(`{"method":"GET","path":"/","query":{"a":{"$dict":"a"}},"dict":{"a":["1","2","3","4"]}}`), 0600); err != nil {
+		t.Fatalf("Failed to write request file: %v", err)
+	}
+	checkpointPath := filepath.Join(dir, "sweep.ckpt")
+	outputPath := filepath.Join(dir, "results.json")
+
+	command := func(extra ...string) *exec.Cmd {
+		args := append([]string{"--host", server.URL, "--checkpoint", checkpointPath, "--output", outputPath}, extra...)
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+		cmd.Env = append(os.Environ(), "S2REQ_HELPER_PROCESS=1", "S2REQ_HELPER_ARGS="+strings.Join(append(args, requestPath), "\n"))
+		return cmd
+	}
+
+	first := command()
+	if err := first.Start(); err != nil {
+		t.Fatalf("Failed to start s2req: %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if entries, _ := loadCheckpoint(checkpointPath); len(entries) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			_ = first.Process.Kill()
+			t.Fatalf("Timed out waiting for checkpoint entries")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// SIGKILLでは終了時の出力処理が実行されない
+	if err := first.Process.Kill(); err != nil {
+		t.Fatalf("Failed to kill s2req: %v", err)
+	}
+	_ = first.Wait()
+	close(release)
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected no output after kill, got %v", err)
+	}
+
+	mu.Lock()
+	received = nil
+	mu.Unlock()
+	if output, err := command("--resume").CombinedOutput(); err != nil {
+		t.Fatalf("Resumed run failed: %v\n%s", err, output)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || !containsAll(received, "3", "4") {
+		t.Errorf("Expected only a=3 and a=4 to be sent on resume, got %v", received)
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	var results []*config.Result
+	if err := json.Unmarshal(data, &results); err != nil {
+		t.Fatalf("Invalid output: %v", err)
+	}
+	var values []string
+	for _, result := range results {
+		values = append(values, strings.TrimPrefix(result.Request.URL, server.URL+"/?a="))
+	}
+	if len(values) != 4 || !containsAll(values, "1", "2", "3", "4") {
+		t.Errorf("Expected results for all 4 combinations, got %v", values)
+	}
+}
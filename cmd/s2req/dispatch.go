@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+	"github.com/secureta/s2http-request/internal/http"
+	"github.com/secureta/s2http-request/internal/parser"
+)
+
+// dispatcher は処理済みリクエストをワーカープールで送信する
+type dispatcher struct {
+	ctx        context.Context // 取り消されると未送信のリクエストを送らずに終了する
+	client     *http.Client
+	cliConfig  *config.CLIConfig
+	userAgent  string
+	verboseOut io.Writer         // Verboseモードの表示の出力先
+	limiter    *rateLimiter      // --rateのレート制御
+	docLimiter *rateLimiter      // meta.rateのレート制御（--rateに加えて適用する）
+	renderer   *renderSink       // ドライラン時のみ設定される
+	checkpoint *checkpoint       // --checkpoint指定時のみ設定される
+	assertions *assertionSummary // expectの評価結果の集計
+	verdicts   *verdictSummary   // WAFテストの判定の集計
+	hooks      *hookRunner       // meta.hooksが指定されたドキュメントのみ設定される
+	// verdictRules は--verdict-rulesで読み込んだルール（未指定の場合はnil）
+	verdictRules *config.VerdictConfig
+	// metaLimiters はmeta.rateの設定ごとのレート制御。
+	// 同じ設定のドキュメントでバケットを共有し、ドキュメントごとにバーストが回復しないようにする。
+	metaLimiters map[config.RateConfig]*rateLimiter
+	// warnedRawRequestID は--request-idを適用しないrawのドキュメントについて警告済みか
+	warnedRawRequestID bool
+}
+
+// dispatchOutcome は1件のリクエスト送信結果を表す
+type dispatchOutcome struct {
+	seq      int
+	index    int
+	request  *config.ProcessedRequest
+	response *config.ResponseData
+	err      error
+}
+
+// errRunAborted は残りのファイルを処理せずに実行を中止するエラー
+var errRunAborted = errors.New("run aborted")
+
+// requestError はリクエスト単位の送信エラーを表す
+type requestError struct {
+	Index   int
+	Request *config.ProcessedRequest
+	Err     error
+}
+
+func (e *requestError) Error() string {
+	return fmt.Sprintf("request #%d (%s %s) failed: %v", e.Index, e.Request.Method, maskQuery(e.Request.URL, e.Request.Secrets), e.Err)
+}
+
+func (e *requestError) Unwrap() error {
+	return e.Err
+}
+
+// newDispatcher は新しいdispatcherを作成
+func newDispatcher(client *http.Client, cliConfig *config.CLIConfig, userAgent string) (*dispatcher, error) {
+	d := &dispatcher{
+		ctx:          context.Background(),
+		client:       client,
+		cliConfig:    cliConfig,
+		userAgent:    userAgent,
+		verboseOut:   diagnosticOutput(cliConfig),
+		assertions:   &assertionSummary{},
+		verdicts:     &verdictSummary{},
+		metaLimiters: make(map[config.RateConfig]*rateLimiter),
+	}
+	if cliConfig.Rate != "" {
+		limiter, err := newRateLimiter(cliConfig.Rate, cliConfig.Burst, cliConfig.RatePerHost)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate: %w", err)
+		}
+		d.limiter = limiter
+	}
+	return d, nil
+}
+
+// forRequestConfig はリクエスト定義のmeta設定を反映したdispatcherを返す。
+// meta.rateは--rateを置き換えず、両方のレートを満たすように送信する。
+func (d *dispatcher) forRequestConfig(requestConfig *config.RequestConfig) (*dispatcher, error) {
+	if requestConfig.Meta == nil || (requestConfig.Meta.Rate == nil && requestConfig.Meta.Hooks == nil) {
+		return d, nil
+	}
+	if d.metaLimiters == nil {
+		d.metaLimiters = make(map[config.RateConfig]*rateLimiter)
+	}
+	docDispatcher := *d
+	if rateConfig := requestConfig.Meta.Rate; rateConfig != nil {
+		limiter, ok := d.metaLimiters[*rateConfig]
+		if !ok {
+			var err error
+			limiter, err = newRateLimiterFromConfig(rateConfig)
+			if err != nil {
+				return nil, fmt.Errorf("invalid meta.rate: %w", err)
+			}
+			d.metaLimiters[*rateConfig] = limiter
+		}
+		docDispatcher.docLimiter = limiter
+	}
+	docDispatcher.hooks = newHookRunner(requestConfig)
+	return &docDispatcher, nil
+}
+
+// concurrency は有効なワーカー数を返す
+func (d *dispatcher) concurrency() int {
+	if d.cliConfig.Concurrency < 1 {
+		return 1
+	}
+	return d.cliConfig.Concurrency
+}
+
+// requestSource は送信するリクエストを1件ずつ供給する。
+// parser.RequestIteratorと同じく、Nextがfalseを返すまでRequestとIndexを読み出す。
+type requestSource interface {
+	Next() bool
+	Request() *config.ProcessedRequest
+	Index() int
+}
+
+// sliceSource は処理済みリクエストのスライスをrequestSourceとして扱う
+type sliceSource struct {
+	requests []*config.ProcessedRequest
+	index    int
+}
+
+func newSliceSource(requests []*config.ProcessedRequest) *sliceSource {
+	return &sliceSource{requests: requests, index: -1}
+}
+
+func (s *sliceSource) Next() bool {
+	if s.index+1 >= len(s.requests) {
+		return false
+	}
+	s.index++
+	return true
+}
+
+func (s *sliceSource) Request() *config.ProcessedRequest {
+	return s.requests[s.index]
+}
+
+func (s *sliceSource) Index() int {
+	return s.index
+}
+
+// dispatchJob はワーカーに渡す1件のリクエスト
+type dispatchJob struct {
+	seq     int // 供給された順番（順序維持に使用）
+	index   int
+	request *config.ProcessedRequest
+}
+
+// dispatch はrequestsから1件ずつリクエストを取り出して並行送信し、結果をemitに渡す。
+// emitにはrequestsのIndexが渡され、Unorderedが無効な場合は供給された順で呼び出される。
+// 送信に失敗したリクエストは残りの送信を止めずにエラーとして集約される。
+// preフックが失敗したリクエストは、エラーに加えてhook_errorを持つ結果もemitに渡す。
+// ctxが取り消された場合、未送信のリクエストは送らずに終了する。
+func (d *dispatcher) dispatch(source string, requests requestSource, emit func(int, *config.Result)) []error {
+	if d.renderer != nil {
+		return d.render(source, requests)
+	}
+
+	workers := d.concurrency()
+	jobs := make(chan dispatchJob)
+	outcomes := make(chan dispatchOutcome)
+	// 順序を維持する場合に保持する結果が無制限に増えないよう、未出力のリクエスト数を制限する
+	window := make(chan struct{}, workers*16)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				response, err := d.send(job.request)
+				outcomes <- dispatchOutcome{
+					seq:      job.seq,
+					index:    job.index,
+					request:  job.request,
+					response: response,
+					err:      err,
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for seq := 0; requests.Next(); seq++ {
+			select {
+			case window <- struct{}{}:
+			case <-d.ctx.Done():
+				return
+			}
+
+			// User-Agentの設定はワーカーに渡す前に行う
+			request := requests.Request()
+			d.applyUserAgent(request)
+
+			select {
+			case jobs <- dispatchJob{seq: seq, index: requests.Index(), request: request}:
+			case <-d.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	var errs []error
+	handle := func(outcome dispatchOutcome) {
+		<-window
+		if outcome.err != nil {
+			errs = append(errs, &requestError{Index: outcome.index, Request: outcome.request, Err: outcome.err})
+			// preフックの失敗で送信しなかったリクエストも、hook_errorを持つ結果として出力する
+			var hookErr *preHookError
+			if errors.As(outcome.err, &hookErr) {
+				result := d.newResult(source, outcome.request, &config.ResponseData{})
+				result.HookError = hookErr.Error()
+				emit(outcome.index, result)
+			}
+			return
+		}
+		result := d.newResult(source, outcome.request, outcome.response)
+		d.printVerbose(result)
+		emit(outcome.index, result)
+	}
+
+	if d.cliConfig.Unordered {
+		for outcome := range outcomes {
+			handle(outcome)
+		}
+		return errs
+	}
+
+	// 順序を維持するため、到着が早すぎた結果は次の順番が揃うまで保持する
+	pending := make(map[int]dispatchOutcome)
+	next := 0
+	for outcome := range outcomes {
+		pending[outcome.seq] = outcome
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			handle(ready)
+			next++
+		}
+	}
+
+	return errs
+}
+
+// applyUserAgent はUser-Agentが未指定の場合に設定する。rawのリクエストは変更しない。
+func (d *dispatcher) applyUserAgent(request *config.ProcessedRequest) {
+	if request.Raw != "" {
+		return
+	}
+	if _, exists := request.Headers["User-Agent"]; exists {
+		return
+	}
+	if request.Headers == nil {
+		request.Headers = make(map[string]string)
+	}
+	if d.userAgent != "" {
+		// コマンドライン引数が指定されている場合はそれを使用
+		request.Headers["User-Agent"] = d.userAgent
+	} else {
+		// コマンドライン引数も指定されていない場合はデフォルト値を使用
+		request.Headers["User-Agent"] = getDefaultUserAgent()
+	}
+}
+
+// send は1件のリクエストを送信する
+func (d *dispatcher) send(request *config.ProcessedRequest) (*config.ResponseData, error) {
+	// レート制御による待機時間はタイムアウトに含めない。--rate、meta.rateの順に待機する。
+	// rawのリクエストは、absolute-formのリクエストターゲットでも接続先のホストで制御する
+	target := request.URL
+	if request.RawOrigin != "" {
+		target = request.RawOrigin
+	}
+	for _, limiter := range []*rateLimiter{d.limiter, d.docLimiter} {
+		if limiter == nil {
+			continue
+		}
+		if err := limiter.Wait(d.ctx, target); err != nil {
+			return nil, fmt.Errorf("rate limiter: %w", err)
+		}
+	}
+	// preフックはレート制御の待機後に実行し、署名などが送信直前の値になるようにする
+	if err := d.hooks.runPre(d.ctx, request); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, d.cliConfig.Timeout)
+	defer cancel()
+
+	if d.cliConfig.Retry > 0 {
+		return d.client.SendRequestWithRetry(ctx, request, d.cliConfig.Retry)
+	}
+	return d.client.SendRequest(ctx, request)
+}
+
+// prepareOAuth2 はmeta.authがoauth2のドキュメントについて、ワーカーが送信を始める前にトークンを取得する。
+// 取得できない場合は全てのリクエストが失敗するため、実行を中止するエラーを返す。ドライランでは取得しない。
+func (d *dispatcher) prepareOAuth2(requests *parser.RequestIterator) error {
+	if d.renderer != nil {
+		return nil
+	}
+	auth, tlsOptions, err := requests.OAuth2()
+	if err != nil {
+		return fmt.Errorf("failed to process meta.auth: %w", err)
+	}
+	if auth == nil {
+		return nil
+	}
+	if err := d.client.PrepareOAuth2(d.ctx, auth, tlsOptions); err != nil {
+		return fmt.Errorf("%w: %w", errRunAborted, err)
+	}
+	return nil
+}
+
+// newResult は送信結果からResultを作成する
+func (d *dispatcher) newResult(source string, request *config.ProcessedRequest, response *config.ResponseData) *config.Result {
+	result := &config.Result{
+		Request:  maskSecrets(*request), // 認証情報は出力しない
+		Response: *response,
+		Metadata: map[string]interface{}{
+			"file":       source,
+			"timestamp":  time.Now().Format(time.RFC3339),
+			"request_id": request.RequestID,
+		},
+	}
+	// どの組み合わせの結果かを分析できるよう、dictの値を記録する
+	if len(request.DictValues) > 0 {
+		result.Metadata["dict"] = request.DictValues
+	}
+	return result
+}
+
+// printVerbose はVerboseモードの場合に送信結果を表示する
+func (d *dispatcher) printVerbose(result *config.Result) {
+	if !d.cliConfig.Verbose {
+		return
+	}
+	fmt.Fprintf(d.verboseOut, "Request: %s %s\n", result.Request.Method, result.Request.URL)
+	if result.Request.RequestID != "" {
+		fmt.Fprintf(d.verboseOut, "Request ID: %s\n", result.Request.RequestID)
+	}
+	fmt.Fprintf(d.verboseOut, "Response: %d\n", result.Response.StatusCode)
+}
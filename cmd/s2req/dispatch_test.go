@@ -0,0 +1,291 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+	httpClient "github.com/secureta/s2http-request/internal/http"
+	"github.com/secureta/s2http-request/internal/parser"
+)
+
+func newTestRequests(baseURL string, n int) []*config.ProcessedRequest {
+	requests := make([]*config.ProcessedRequest, n)
+	for i := 0; i < n; i++ {
+		requests[i] = &config.ProcessedRequest{
+			Method:  "GET",
+			URL:     fmt.Sprintf("%s/?id=%d", baseURL, i),
+			Headers: map[string]string{},
+		}
+	}
+	return requests
+}
+
+func TestDispatcher_PreservesOrder(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			observed := atomic.LoadInt32(&maxInFlight)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxInFlight, observed, current) {
+				break
+			}
+		}
+
+		// 後半のリクエストほど早く返して順序の入れ替わりを起こす
+		id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+		time.Sleep(time.Duration(10-id) * 5 * time.Millisecond)
+		_, _ = w.Write([]byte(r.URL.Query().Get("id")))
+	}))
+	defer server.Close()
+
+	client, err := httpClient.NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	d, err := newDispatcher(client, &config.CLIConfig{Timeout: 5 * time.Second, Concurrency: 4}, "")
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	var results []*config.Result
+	errs := d.dispatch("test", newSliceSource(newTestRequests(server.URL, 10)), func(_ int, result *config.Result) {
+		results = append(results, result)
+	})
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if len(results) != 10 {
+		t.Fatalf("Expected 10 results, got %d", len(results))
+	}
+	for i, result := range results {
+		if result.Response.Body != strconv.Itoa(i) {
+			t.Errorf("Result %d: expected body %q, got %q", i, strconv.Itoa(i), result.Response.Body)
+		}
+		if result.Metadata["file"] != "test" {
+			t.Errorf("Result %d: expected file metadata %q, got %v", i, "test", result.Metadata["file"])
+		}
+	}
+	if got := atomic.LoadInt32(&maxInFlight); got < 2 || got > 4 {
+		t.Errorf("Expected between 2 and 4 concurrent requests, got %d", got)
+	}
+}
+
+func TestDispatcher_Unordered(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+		time.Sleep(time.Duration(3-id) * 30 * time.Millisecond)
+		_, _ = w.Write([]byte(r.URL.Query().Get("id")))
+	}))
+	defer server.Close()
+
+	client, err := httpClient.NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	d, err := newDispatcher(client, &config.CLIConfig{Timeout: 5 * time.Second, Concurrency: 4, Unordered: true}, "")
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	var bodies []string
+	errs := d.dispatch("test", newSliceSource(newTestRequests(server.URL, 4)), func(_ int, result *config.Result) {
+		bodies = append(bodies, result.Response.Body)
+	})
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if len(bodies) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(bodies))
+	}
+	if bodies[0] != "3" {
+		t.Errorf("Expected fastest response first, got order %v", bodies)
+	}
+}
+
+func TestDispatcher_CollectsErrorsWithoutDroppingResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client, err := httpClient.NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	requests := newTestRequests(server.URL, 3)
+	requests[1].URL = "invalid-url"
+
+	d, err := newDispatcher(client, &config.CLIConfig{Timeout: 5 * time.Second, Concurrency: 2}, "custom-agent")
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	var results []*config.Result
+	errs := d.dispatch("test", newSliceSource(requests), func(_ int, result *config.Result) {
+		results = append(results, result)
+	})
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %d: %v", len(errs), errs)
+	}
+	reqErr, ok := errs[0].(*requestError)
+	if !ok {
+		t.Fatalf("Expected *requestError, got %T", errs[0])
+	}
+	if reqErr.Index != 1 {
+		t.Errorf("Expected failing index 1, got %d", reqErr.Index)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.Request.Headers["User-Agent"] != "custom-agent" {
+			t.Errorf("Expected User-Agent %q, got %q", "custom-agent", result.Request.Headers["User-Agent"])
+		}
+	}
+}
+
+func TestProcessRequestConfigs_PreparesOAuth2Token(t *testing.T) {
+	var tokens, calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if _, pass, _ := r.BasicAuth(); pass != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+				return
+			}
+			tokens.Add(1)
+			_, _ = w.Write([]byte(`{"access_token":"t1","token_type":"bearer"}`))
+			return
+		}
+		calls.Add(1)
+		if r.Header.Get("Authorization") != "Bearer t1" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr bool
+		tokens  int32
+		calls   int32
+	}{
+		{name: "token is fetched once before dispatch", secret: "secret", tokens: 1, calls: 3},
+		{name: "token error aborts the run without sending", secret: "wrong", wantErr: true, tokens: 0, calls: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens.Store(0)
+			calls.Store(0)
+			client, err := httpClient.NewClient(5*time.Second, "")
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			content := `method: GET
+path: {$dict: path}
+dict:
+  path: [/a, /b, /c]
+meta:
+  auth:
+    type: oauth2
+    token-url: ` + server.URL + `/token
+    client-id: id
+    client-secret: ` + tt.secret + `
+`
+			p := parser.NewParser()
+			configs, err := p.ParseMultiple([]byte(content), ".yaml", "oauth2.yaml")
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+			d, err := newDispatcher(client, &config.CLIConfig{Host: server.URL, Timeout: 5 * time.Second, Concurrency: 3}, "")
+			if err != nil {
+				t.Fatalf("Failed to create dispatcher: %v", err)
+			}
+
+			var results []*config.Result
+			err = processRequestConfigs(p, d, configs, "oauth2.yaml", nil, func(result *config.Result) {
+				results = append(results, result)
+			})
+			if tt.wantErr {
+				if !errors.Is(err, errRunAborted) || !strings.Contains(err.Error(), "invalid_client") {
+					t.Errorf("Expected the run to be aborted with the token error, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tokens.Load() != tt.tokens || calls.Load() != tt.calls || len(results) != int(tt.calls) {
+				t.Errorf("Expected %d token(s) and %d call(s), got %d and %d with %d result(s)", tt.tokens, tt.calls, tokens.Load(), calls.Load(), len(results))
+			}
+			for _, result := range results {
+				if result.Response.StatusCode != http.StatusOK {
+					t.Errorf("Expected status 200, got %d", result.Response.StatusCode)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessRequestConfigs_SkipsRequestIDForRawDocuments(t *testing.T) {
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.URL.Path+"="+r.Header.Get("X-Request-ID"))
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client, err := httpClient.NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	content := "method: GET\npath: /normal\n---\nraw: \"GET /raw1 HTTP/1.1\\nHost: x\\n\\n\"\n---\nraw: \"GET /raw2 HTTP/1.1\\nHost: x\\n\\n\"\n"
+	p := parser.NewParser()
+	configs, err := p.ParseMultiple([]byte(content), ".yaml", "mixed.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	cliConfig := &config.CLIConfig{
+		Host:      server.URL,
+		Timeout:   5 * time.Second,
+		RequestID: &config.RequestIDConfig{Location: config.RequestIDLocationHeader, Key: "X-Request-ID"},
+	}
+	d, err := newDispatcher(client, cliConfig, "")
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	var logs strings.Builder
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	var results []*config.Result
+	if err := processRequestConfigs(p, d, configs, "mixed.yaml", nil, func(result *config.Result) {
+		results = append(results, result)
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(results) != 3 || len(ids) != 3 {
+		t.Fatalf("Expected all 3 documents to be sent, got %d result(s): %v", len(results), ids)
+	}
+	if !strings.HasPrefix(ids[0], "/normal=") || ids[0] == "/normal=" || ids[1] != "/raw1=" || ids[2] != "/raw2=" {
+		t.Errorf("Expected the request ID only on the normal document, got %v", ids)
+	}
+	if count := strings.Count(logs.String(), "--request-id is not applied to raw requests"); count != 1 {
+		t.Errorf("Expected one warning, got %d: %s", count, logs.String())
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// maxSummaryFailures はサマリーに表示する失敗の最大件数
+const maxSummaryFailures = 20
+
+// assertionSummary はexpectの評価結果を集計する
+type assertionSummary struct {
+	passed   int      // 全項目を満たしたリクエスト数
+	failed   int      // いずれかの項目を満たさなかったリクエスト数
+	errors   int      // expectを持つドキュメントで送信に失敗したリクエスト数
+	failures []string // 失敗の内容（先頭のmaxSummaryFailures件）
+	omitted  int      // 表示しきれなかった失敗の件数
+}
+
+// record はexpectを評価した結果を1件集計する
+func (s *assertionSummary) record(source string, result *config.Result) {
+	if result.Passed == nil {
+		return
+	}
+	if *result.Passed {
+		s.passed++
+		return
+	}
+	s.failed++
+	for _, assertion := range result.Assertions {
+		if assertion.Passed {
+			continue
+		}
+		s.addFailure(fmt.Sprintf("%s: %s %s: %s expected %s, got %s",
+			source, result.Request.Method, result.Request.URL, assertion.Name, assertion.Expected, assertion.Actual))
+	}
+}
+
+// recordError はexpectを評価できなかった送信エラーを1件集計する
+func (s *assertionSummary) recordError(source string, err error) {
+	s.errors++
+	s.addFailure(fmt.Sprintf("%s: %v", source, err))
+}
+
+func (s *assertionSummary) addFailure(message string) {
+	if len(s.failures) >= maxSummaryFailures {
+		s.omitted++
+		return
+	}
+	s.failures = append(s.failures, message)
+}
+
+// total はexpectの対象となったリクエスト数を返す
+func (s *assertionSummary) total() int {
+	return s.passed + s.failed + s.errors
+}
+
+// ok は失敗したリクエストがないかを返す
+func (s *assertionSummary) ok() bool {
+	return s.failed == 0 && s.errors == 0
+}
+
+// print はサマリーを書き出す。expectの対象がない場合は何も書き出さない。
+func (s *assertionSummary) print(w io.Writer) {
+	if s.total() == 0 {
+		return
+	}
+	for _, failure := range s.failures {
+		fmt.Fprintf(w, "FAIL %s\n", failure)
+	}
+	if s.omitted > 0 {
+		fmt.Fprintf(w, "... and %d more failure(s)\n", s.omitted)
+	}
+	fmt.Fprintf(w, "Assertions: %d passed, %d failed, %d errors (%d requests)\n", s.passed, s.failed, s.errors, s.total())
+}
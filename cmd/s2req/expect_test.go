@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+	httpClient "github.com/secureta/s2http-request/internal/http"
+	"github.com/secureta/s2http-request/internal/parser"
+)
+
+func TestProcessRequestConfigs_EvaluatesExpect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.RawQuery, "script") {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("Request blocked"))
+			return
+		}
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	client, err := httpClient.NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	content := `method: GET
+path: /search
+query:
+  q: {$dict: q}
+dict:
+  q: ["hello", "<script>"]
+expect:
+  status: 403
+  body-contains: blocked
+---
+method: GET
+path: /
+`
+	p := parser.NewParser()
+	configs, err := p.ParseMultiple([]byte(content), ".yaml", "waf.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	d, err := newDispatcher(client, &config.CLIConfig{Host: server.URL, Timeout: 5 * time.Second, Concurrency: 2}, "")
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	var results []*config.Result
+	if err := processRequestConfigs(p, d, configs, "waf.yaml", nil, func(result *config.Result) {
+		results = append(results, result)
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	if results[0].Passed == nil || *results[0].Passed || len(results[0].Assertions) != 2 {
+		t.Errorf("Expected first request to fail both assertions, got %+v", results[0].Assertions)
+	}
+	if results[1].Passed == nil || !*results[1].Passed {
+		t.Errorf("Expected second request to pass, got %+v", results[1].Assertions)
+	}
+	if results[2].Passed != nil || results[2].Assertions != nil {
+		t.Errorf("Expected no assertions without expect, got %+v", results[2])
+	}
+
+	if d.assertions.ok() || d.assertions.passed != 1 || d.assertions.failed != 1 {
+		t.Errorf("Unexpected summary: %+v", d.assertions)
+	}
+	var out bytes.Buffer
+	d.assertions.print(&out)
+	for _, want := range []string{
+		"FAIL waf.yaml: GET " + server.URL + "/search?q=hello: status expected 403, got 200",
+		`body-contains expected "blocked", got not found`,
+		"Assertions: 1 passed, 1 failed, 0 errors (2 requests)",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestAssertionSummary(t *testing.T) {
+	t.Run("nothing to report", func(t *testing.T) {
+		s := &assertionSummary{}
+		s.record("a.yaml", &config.Result{})
+		var out bytes.Buffer
+		s.print(&out)
+		if out.Len() != 0 || !s.ok() {
+			t.Errorf("Expected empty summary, got %q", out.String())
+		}
+	})
+
+	t.Run("send errors count as failures and output is capped", func(t *testing.T) {
+		s := &assertionSummary{}
+		for i := 0; i < maxSummaryFailures+5; i++ {
+			s.recordError("a.yaml", fmt.Errorf("connection refused %d", i))
+		}
+		var out bytes.Buffer
+		s.print(&out)
+		if s.ok() {
+			t.Errorf("Expected send errors to fail the run")
+		}
+		if got := strings.Count(out.String(), "FAIL "); got != maxSummaryFailures {
+			t.Errorf("Expected %d failure lines, got %d", maxSummaryFailures, got)
+		}
+		for _, want := range []string{"... and 5 more failure(s)", "0 passed, 0 failed, 25 errors (25 requests)"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Expected summary to contain %q, got:\n%s", want, out.String())
+			}
+		}
+	})
+}
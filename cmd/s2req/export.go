@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/secureta/s2http-request/internal/config"
+	"github.com/secureta/s2http-request/internal/http"
+	"github.com/secureta/s2http-request/internal/parser"
+)
+
+// exportFormat はエクスポート先のコマンド形式
+type exportFormat string
+
+const (
+	exportFormatCurl   exportFormat = "curl"
+	exportFormatHTTPie exportFormat = "httpie"
+)
+
+// defaultBodyContentType はSendRequestがContent-Type未指定時に付与する値
+const defaultBodyContentType = "application/x-www-form-urlencoded"
+
+func handleExportCommand() {
+	exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
+
+	var (
+		as              = exportCmd.String("as", "curl", "Command format (curl, httpie; httpie cannot send URL fragments, so requests with a fragment need curl)")
+		host            = exportCmd.String("host", "http://localhost", "Target host URL")
+		output          = exportCmd.String("output", "", "Output file path")
+		userAgent       = exportCmd.String("user-agent", "", "Override User-Agent header")
+		requestID       = exportCmd.String("request-id", "", "Enable Request ID (path=head|tail, query=<key>, header=<key>)")
+		maxCombinations = exportCmd.Int("max-combinations", 0, "Optional safety cap on dict combinations per request definition (0 = unlimited)")
+		showSecrets     = exportCmd.Bool("show-secrets", false, "Write meta.auth credentials into the commands instead of masking them")
+	)
+	vars := make(varFlags)
+	var varFiles varFileFlags
+	exportCmd.Var(vars, "var", "Override variable (key=value, repeatable)")
+	exportCmd.Var(&varFiles, "var-file", "Load variables from a YAML, JSON or dotenv file (repeatable, later files win)")
+	var plugins, pluginDirs pluginFlags
+	exportCmd.Var(&plugins, "plugin", "Load a plugin executable that provides a $function (repeatable)")
+	exportCmd.Var(&pluginDirs, "plugin-dir", "Load every executable in this directory as a plugin (repeatable)")
+
+	// Parse arguments starting from position 2 (after "export")
+	if err := exportCmd.Parse(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to parse export arguments: %v\n", err)
+		os.Exit(1)
+	}
+
+	format := exportFormat(*as)
+	if format != exportFormatCurl && format != exportFormatHTTPie {
+		log.Fatalf("Invalid --as value %q, expected 'curl' or 'httpie'", *as)
+	}
+
+	variables, err := mergeVariables(varFiles, vars)
+	if err != nil {
+		log.Fatalf("Failed to load variables: %v", err)
+	}
+
+	cliConfig := &config.CLIConfig{
+		Host:            *host,
+		MaxCombinations: *maxCombinations,
+		ShowSecrets:     *showSecrets,
+	}
+	if *requestID != "" {
+		cliConfig.RequestID, err = parseRequestIDOption(*requestID)
+		if err != nil {
+			log.Fatalf("Invalid request-id option: %v", err)
+		}
+	}
+
+	w, closer, err := openOutput(*output, false)
+	if err != nil {
+		log.Fatalf("Failed to open output: %v", err)
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+
+	d := &dispatcher{cliConfig: cliConfig, userAgent: *userAgent}
+	p := parser.NewParser()
+	if _, err := loadPlugins(p, plugins, pluginDirs); err != nil {
+		log.Fatalf("Failed to load plugins: %v", err)
+	}
+
+	files := exportCmd.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	failed := false
+	for _, filePath := range files {
+		requestConfigs, source, err := parseRequestSource(p, filePath)
+		if err != nil {
+			log.Printf("Error processing file %s: %v", filePath, err)
+			failed = true
+			continue
+		}
+		if err := exportRequestConfigs(p, d, requestConfigs, source, variables, format, w); err != nil {
+			log.Printf("Error exporting %s: %v", source, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// parseRequestSource はファイルまたは標準入力("-")からリクエスト定義を読み込む
+func parseRequestSource(p *parser.Parser, filePath string) ([]*config.RequestConfig, string, error) {
+	if filePath == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, "stdin", fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		configs, err := p.ParseMultiple(data, detectFormat(data), "stdin")
+		return configs, "stdin", err
+	}
+
+	// The CLI intentionally accepts user-supplied request definition paths.
+	data, err := os.ReadFile(filePath) // #nosec G304
+	if err != nil {
+		return nil, filePath, fmt.Errorf("failed to read file: %w", err)
+	}
+	configs, err := p.ParseMultiple(data, filepath.Ext(filePath), filePath)
+	return configs, filePath, err
+}
+
+// exportRequestConfigs はリクエスト定義を展開し、1リクエスト1行のコマンドとして書き出す
+func exportRequestConfigs(p *parser.Parser, d *dispatcher, requestConfigs []*config.RequestConfig, source string, variables map[string]interface{}, format exportFormat, w io.Writer) error {
+	for _, requestConfig := range requestConfigs {
+		ctx := context.Background()
+		if len(variables) > 0 {
+			ctx = context.WithValue(ctx, "variables", variables)
+		}
+
+		requests, err := p.IterateRequestsWithConfig(ctx, requestConfig, d.cliConfig.Host, d.cliConfig)
+		if err != nil {
+			return fmt.Errorf("failed to process requests: %w", err)
+		}
+
+		for requests.Next() {
+			processedRequest := requests.Request()
+			d.applyUserAgent(processedRequest)
+			if !d.cliConfig.ShowSecrets {
+				// 認証情報は--show-secretsを指定した場合のみコマンドに含める
+				masked := maskSecrets(*processedRequest)
+				processedRequest = &masked
+			}
+
+			if processedRequest.Raw != "" {
+				return fmt.Errorf("raw requests cannot be exported as commands, use --dry-run to print the bytes")
+			}
+			// curlはTransfer-Encoding: chunkedを指定するとボディを自身でチャンクに分割するため、構築済みのボディを再現できない
+			if _, ok := lookupHeader(processedRequest.Headers, "Transfer-Encoding"); ok {
+				return fmt.Errorf("requests with Transfer-Encoding cannot be exported as commands, use --dry-run to print the bytes")
+			}
+			var command string
+			switch format {
+			case exportFormatHTTPie:
+				command, err = exportAsHTTPie(processedRequest)
+			default:
+				command, err = exportAsCurl(processedRequest)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to export %s %s: %w", processedRequest.Method, processedRequest.URL, err)
+			}
+			if _, err := fmt.Fprintln(w, command); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+		}
+		if err := requests.Err(); err != nil {
+			return fmt.Errorf("failed to process requests: %w", err)
+		}
+	}
+	return nil
+}
+
+// exportHeaders は送信時と同じヘッダーをキー順で返す。
+// OAuth2のトークンは取得しないため、Authorizationヘッダーにはプレースホルダーを出力する。
+func exportHeaders(processedRequest *config.ProcessedRequest) [][2]string {
+	headers := make(map[string]string, len(processedRequest.Headers)+2)
+	for key, value := range processedRequest.Headers {
+		if processedRequest.OAuth2 != nil && strings.EqualFold(key, "Authorization") {
+			continue
+		}
+		headers[key] = value
+	}
+	if processedRequest.OAuth2 != nil {
+		headers["Authorization"] = "Bearer " + http.OAuth2TokenPlaceholder
+	}
+	// raw request target以外ではSendRequestと同様にContent-Typeを補う
+	if processedRequest.RawRequestTarget == "" && processedRequest.Body != "" {
+		if _, ok := lookupHeader(headers, "Content-Type"); !ok {
+			headers["Content-Type"] = defaultBodyContentType
+		}
+	}
+
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([][2]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, [2]string{key, headers[key]})
+	}
+	return pairs
+}
+
+func lookupHeader(headers map[string]string, name string) (string, bool) {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// splitRequestTarget はURLをオリジンとリクエストターゲットに分割する。
+// raw request targetやフラグメントを含む場合にcurlの--request-targetで使用する。
+func splitRequestTarget(processedRequest *config.ProcessedRequest) (origin string, target string, needsTarget bool, err error) {
+	if processedRequest.RawRequestTarget != "" {
+		origin, err = requestOrigin(processedRequest.URL)
+		return origin, processedRequest.RawRequestTarget, true, err
+	}
+
+	parsedURL, err := url.Parse(processedRequest.URL)
+	if err != nil {
+		return "", "", false, fmt.Errorf("invalid URL: %w", err)
+	}
+	if parsedURL.Fragment == "" && !strings.Contains(processedRequest.URL, "#") {
+		return processedRequest.URL, "", false, nil
+	}
+
+	// fragmentTransportと同じリクエストターゲットを構築
+	target = parsedURL.Path
+	if parsedURL.RawQuery != "" {
+		target += "?" + parsedURL.RawQuery
+	}
+	target += "#" + parsedURL.Fragment
+	return parsedURL.Scheme + "://" + parsedURL.Host, target, true, nil
+}
+
+// requestOrigin はURLのスキームとホスト部分を返す
+func requestOrigin(rawURL string) (string, error) {
+	schemeEnd := strings.Index(rawURL, "://")
+	if schemeEnd < 0 {
+		return "", fmt.Errorf("failed to parse URL origin: %s", rawURL)
+	}
+	rest := rawURL[schemeEnd+3:]
+	if hostEnd := strings.IndexAny(rest, "/?#"); hostEnd >= 0 {
+		rest = rest[:hostEnd]
+	}
+	if rest == "" {
+		return "", fmt.Errorf("failed to parse URL origin: empty host")
+	}
+	return rawURL[:schemeEnd+3] + rest, nil
+}
+
+// exportAsCurl は処理済みリクエストをcurlコマンドに変換する
+func exportAsCurl(processedRequest *config.ProcessedRequest) (string, error) {
+	origin, target, needsTarget, err := splitRequestTarget(processedRequest)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"curl"}
+	if processedRequest.Method != "GET" || processedRequest.Body != "" {
+		args = append(args, "-X", shellQuote(processedRequest.Method))
+	}
+	if processedRequest.DigestAuth != nil {
+		// Digest認証はcurlにチャレンジへの応答を任せる
+		args = append(args, "--digest", "-u", shellQuote(processedRequest.DigestAuth.Username+":"+processedRequest.DigestAuth.Password))
+	}
+	if needsTarget {
+		// ドットセグメントの正規化を抑止し、リクエストターゲットをそのまま送信する
+		args = append(args, "--path-as-is", "--request-target", shellQuote(target), shellQuote(origin))
+	} else {
+		args = append(args, shellQuote(origin))
+	}
+	for _, header := range exportHeaders(processedRequest) {
+		if header[1] == "" {
+			// curlは "Name;" で空の値のヘッダーを送信する
+			args = append(args, "-H", shellQuote(header[0]+";"))
+			continue
+		}
+		args = append(args, "-H", shellQuote(header[0]+": "+header[1]))
+	}
+
+	if processedRequest.Body == "" {
+		return strings.Join(args, " "), nil
+	}
+	if isShellSafeText(processedRequest.Body) {
+		args = append(args, "--data-binary", shellQuote(processedRequest.Body))
+		return strings.Join(args, " "), nil
+	}
+
+	// バイナリや制御文字を含むボディはprintfで生成して標準入力から渡す
+	args = append(args, "--data-binary", "@-")
+	return printfCommand(processedRequest.Body) + " | " + strings.Join(args, " "), nil
+}
+
+// exportAsHTTPie は処理済みリクエストをHTTPieコマンドに変換する
+func exportAsHTTPie(processedRequest *config.ProcessedRequest) (string, error) {
+	if processedRequest.RawRequestTarget == "" && strings.Contains(processedRequest.URL, "#") {
+		// HTTPieはURLのフラグメントを送信前に取り除き、リクエストターゲットを直接指定する手段もない
+		return "", fmt.Errorf("httpie cannot send URL fragments, use --as curl instead")
+	}
+
+	args := []string{"http"}
+	body := processedRequest.Body
+	pipeBody := body != "" && !isShellSafeText(body)
+	if !pipeBody {
+		args = append(args, "--ignore-stdin")
+	}
+	if processedRequest.RawRequestTarget != "" {
+		args = append(args, "--path-as-is")
+	}
+	if processedRequest.DigestAuth != nil {
+		args = append(args, "-A", "digest", "-a", shellQuote(processedRequest.DigestAuth.Username+":"+processedRequest.DigestAuth.Password))
+	}
+	if body != "" && isShellSafeText(body) {
+		args = append(args, "--raw", shellQuote(body))
+	}
+
+	requestURL := processedRequest.URL
+	if processedRequest.RawRequestTarget != "" {
+		origin, err := requestOrigin(processedRequest.URL)
+		if err != nil {
+			return "", err
+		}
+		requestURL = origin + processedRequest.RawRequestTarget
+	}
+	args = append(args, shellQuote(processedRequest.Method), shellQuote(requestURL))
+
+	for _, header := range exportHeaders(processedRequest) {
+		if header[1] == "" {
+			// HTTPieは "Name;" で空の値のヘッダーを送信する
+			args = append(args, shellQuote(header[0]+";"))
+			continue
+		}
+		args = append(args, shellQuote(header[0]+":"+header[1]))
+	}
+
+	if pipeBody {
+		return printfCommand(body) + " | " + strings.Join(args, " "), nil
+	}
+	return strings.Join(args, " "), nil
+}
+
+// shellQuote はPOSIXシェル向けに文字列をクォートする
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("@%+=:,./_-", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// isShellSafeText はシングルクォートでそのまま表現できる文字列かを判定する
+func isShellSafeText(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r == '\n' || r == '\t' {
+			continue
+		}
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// printfCommand は任意のバイト列を出力するprintfコマンドを生成する
+func printfCommand(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '%':
+			b.WriteString("%%")
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c == '\'':
+			b.WriteString(`'\''`)
+		case c == '-' && i == 0:
+			// 先頭の "-" はprintfのオプションと解釈されないよう8進数で表す
+			b.WriteString(`\055`)
+		case c >= 0x20 && c < 0x7f:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, `\%03o`, c)
+		}
+	}
+	return "printf '" + b.String() + "'"
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/base64"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/secureta/s2http-request/internal/config"
+	"github.com/secureta/s2http-request/internal/parser"
+)
+
+// runShell はコマンドをPOSIXシェルで実行し、標準出力を返す
+func runShell(t *testing.T, command string) string {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	output, err := exec.Command("sh", "-c", command).Output()
+	if err != nil {
+		t.Fatalf("Failed to run %q: %v", command, err)
+	}
+	return string(output)
+}
+
+func TestShellQuote_RoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		"simple",
+		"with space",
+		"it's",
+		`"double" and $VAR and ` + "`cmd`",
+		"multi\nline",
+		"<script>alert(1)</script>",
+		"' OR '1'='1",
+	}
+
+	for _, input := range inputs {
+		got := runShell(t, "printf '%s' "+shellQuote(input))
+		if got != input {
+			t.Errorf("shellQuote(%q) round trip produced %q", input, got)
+		}
+	}
+}
+
+func TestPrintfCommand_RoundTrip(t *testing.T) {
+	inputs := []string{
+		"line1\r\nline2",
+		"nul\x00byte",
+		"-starts-with-dash",
+		"100% \\ backslash 'quote'",
+		"\xff\xfe invalid utf8",
+	}
+
+	for _, input := range inputs {
+		got := runShell(t, printfCommand(input))
+		if got != input {
+			t.Errorf("printfCommand(%q) round trip produced %q", input, got)
+		}
+	}
+}
+
+func TestExportAsCurl(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  *config.ProcessedRequest
+		expected string
+	}{
+		{
+			name: "simple GET",
+			request: &config.ProcessedRequest{
+				Method:  "GET",
+				URL:     "http://example.com/search?q=1",
+				Headers: map[string]string{"X-Test": "a b"},
+			},
+			expected: `curl 'http://example.com/search?q=1' -H 'X-Test: a b'`,
+		},
+		{
+			name: "POST with default content type",
+			request: &config.ProcessedRequest{
+				Method:  "POST",
+				URL:     "http://example.com/login",
+				Headers: map[string]string{},
+				Body:    "user=admin&pass=it's",
+			},
+			expected: `curl -X POST http://example.com/login -H 'Content-Type: application/x-www-form-urlencoded' --data-binary 'user=admin&pass=it'\''s'`,
+		},
+		{
+			name: "raw request target",
+			request: &config.ProcessedRequest{
+				Method:           "GET",
+				URL:              "http://example.com/a/../%%32%65",
+				RawRequestTarget: "/a/../%%32%65",
+				Headers:          map[string]string{"Host": "tenant.example.test"},
+			},
+			expected: `curl --path-as-is --request-target /a/../%%32%65 http://example.com -H 'Host: tenant.example.test'`,
+		},
+		{
+			name: "fragment",
+			request: &config.ProcessedRequest{
+				Method:  "GET",
+				URL:     "http://example.com/page?x=1#section",
+				Headers: map[string]string{"X-Empty": ""},
+			},
+			expected: `curl --path-as-is --request-target '/page?x=1#section' http://example.com -H 'X-Empty;'`,
+		},
+		{
+			name: "binary body",
+			request: &config.ProcessedRequest{
+				Method:  "PUT",
+				URL:     "http://example.com/upload",
+				Headers: map[string]string{"Content-Type": "application/octet-stream"},
+				Body:    "a\r\n\x00",
+			},
+			expected: `printf 'a\015\012\000' | curl -X PUT http://example.com/upload -H 'Content-Type: application/octet-stream' --data-binary @-`,
+		},
+		{
+			name: "digest auth",
+			request: &config.ProcessedRequest{
+				Method:     "GET",
+				URL:        "http://example.com/admin",
+				DigestAuth: &config.DigestAuth{Username: "admin", Password: "it's"},
+			},
+			expected: `curl --digest -u 'admin:it'\''s' http://example.com/admin`,
+		},
+		{
+			name: "oauth2 token placeholder",
+			request: &config.ProcessedRequest{
+				Method:  "GET",
+				URL:     "http://example.com/api",
+				Headers: map[string]string{"Authorization": "Bearer stale"},
+				OAuth2:  &config.OAuth2Auth{TokenURL: "https://auth.example.com/token"},
+			},
+			expected: `curl http://example.com/api -H 'Authorization: Bearer <oauth2-token>'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := exportAsCurl(tt.request)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExportAsHTTPie(t *testing.T) {
+	tests := []struct {
+		name      string
+		request   *config.ProcessedRequest
+		expected  string
+		wantError bool
+	}{
+		{
+			name: "POST with text body",
+			request: &config.ProcessedRequest{
+				Method:  "POST",
+				URL:     "http://example.com/api",
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    "{\"a\":\n1}",
+			},
+			expected: "http --ignore-stdin --raw '{\"a\":\n1}' POST http://example.com/api Content-Type:application/json",
+		},
+		{
+			name: "raw request target",
+			request: &config.ProcessedRequest{
+				Method:           "GET",
+				URL:              "http://example.com/a/../b",
+				RawRequestTarget: "/a/../b",
+				Headers:          map[string]string{"X-Empty": ""},
+			},
+			expected: `http --ignore-stdin --path-as-is GET http://example.com/a/../b 'X-Empty;'`,
+		},
+		{
+			name: "binary body",
+			request: &config.ProcessedRequest{
+				Method:  "POST",
+				URL:     "http://example.com/upload",
+				Headers: map[string]string{"Content-Type": "application/octet-stream"},
+				Body:    "\x00",
+			},
+			expected: `printf '\000' | http POST http://example.com/upload Content-Type:application/octet-stream`,
+		},
+		{
+			name: "digest auth",
+			request: &config.ProcessedRequest{
+				Method:     "GET",
+				URL:        "http://example.com/admin",
+				DigestAuth: &config.DigestAuth{Username: "admin", Password: "secret"},
+			},
+			expected: `http --ignore-stdin -A digest -a admin:secret GET http://example.com/admin`,
+		},
+		{
+			name: "oauth2 token placeholder",
+			request: &config.ProcessedRequest{
+				Method: "GET",
+				URL:    "http://example.com/api",
+				OAuth2: &config.OAuth2Auth{TokenURL: "https://auth.example.com/token"},
+			},
+			expected: `http --ignore-stdin GET http://example.com/api 'Authorization:Bearer <oauth2-token>'`,
+		},
+		{
+			name: "fragment is not supported",
+			request: &config.ProcessedRequest{
+				Method: "GET",
+				URL:    "http://example.com/#frag",
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := exportAsHTTPie(tt.request)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExportRequestConfigs(t *testing.T) {
+	p := parser.NewParser()
+	configs, err := p.ParseMultiple([]byte(`{"method":"GET","path":"/u","query":{"id":{"$dict":"id"}},"dict":{"id":["1","2"]}}`), ".json", "test.json")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	d := &dispatcher{cliConfig: &config.CLIConfig{Host: "http://example.com", MaxCombinations: 10}, userAgent: "ua"}
+	var out strings.Builder
+	if err := exportRequestConfigs(p, d, configs, "test.json", nil, exportFormatCurl, &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 commands, got %d: %q", len(lines), out.String())
+	}
+	if lines[0] != `curl 'http://example.com/u?id=1' -H 'User-Agent: ua'` {
+		t.Errorf("Unexpected first command: %s", lines[0])
+	}
+}
+
+func TestExportRequestConfigs_MasksSecrets(t *testing.T) {
+	p := parser.NewParser()
+	configs, err := p.ParseMultiple([]byte(`method: GET
+path: /basic
+meta:
+  auth:
+    type: basic
+    username: admin
+    password: s3cret-basic
+---
+method: GET
+path: /key
+meta:
+  auth:
+    type: api-key
+    name: api_key
+    value: s3cret-key
+    in: query
+---
+method: GET
+path: /digest
+meta:
+  auth:
+    type: digest
+    username: admin
+    password: s3cret-digest
+`), ".yaml", "test.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	basic := base64.StdEncoding.EncodeToString([]byte("admin:s3cret-basic"))
+	secrets := []string{basic, "s3cret-key", "s3cret-digest"}
+
+	for _, format := range []exportFormat{exportFormatCurl, exportFormatHTTPie} {
+		t.Run(string(format), func(t *testing.T) {
+			d := &dispatcher{cliConfig: &config.CLIConfig{Host: "http://example.com"}}
+			var out strings.Builder
+			if err := exportRequestConfigs(p, d, configs, "test.yaml", nil, format, &out); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, secret := range secrets {
+				if strings.Contains(out.String(), secret) {
+					t.Errorf("Expected %q to be masked, got:\n%s", secret, out.String())
+				}
+			}
+			if !strings.Contains(out.String(), "Basic ****") || !strings.Contains(out.String(), "api_key=****") || !strings.Contains(out.String(), "admin:****") {
+				t.Errorf("Expected masked credentials, got:\n%s", out.String())
+			}
+
+			// --show-secretsを指定した場合は実行可能なコマンドを出力する
+			d.cliConfig.ShowSecrets = true
+			out.Reset()
+			if err := exportRequestConfigs(p, d, configs, "test.yaml", nil, format, &out); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, secret := range secrets {
+				if !strings.Contains(out.String(), secret) {
+					t.Errorf("Expected %q with --show-secrets, got:\n%s", secret, out.String())
+				}
+			}
+		})
+	}
+}
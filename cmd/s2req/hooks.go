@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// defaultHookTimeout はtimeout未指定時のフックの実行時間の上限
+const defaultHookTimeout = 10 * time.Second
+
+// hookRunner はmeta.hooksのコマンドを実行する
+type hookRunner struct {
+	hooks *config.HooksConfig
+	dir   string // コマンドの作業ディレクトリ（リクエスト定義ファイルのディレクトリ）
+}
+
+// newHookRunner はリクエスト定義のmeta.hooksからhookRunnerを作成する。フックがない場合はnilを返す。
+func newHookRunner(requestConfig *config.RequestConfig) *hookRunner {
+	if requestConfig.Meta == nil || requestConfig.Meta.Hooks == nil {
+		return nil
+	}
+	runner := &hookRunner{hooks: requestConfig.Meta.Hooks}
+	if requestConfig.FilePath != "" {
+		runner.dir = filepath.Dir(requestConfig.FilePath)
+	}
+	return runner
+}
+
+// preHookError はpreフックが失敗したため、リクエストを送信しなかったことを表す
+type preHookError struct {
+	err error
+}
+
+func (e *preHookError) Error() string {
+	return e.err.Error()
+}
+
+func (e *preHookError) Unwrap() error {
+	return e.err
+}
+
+// runPre はpreフックに処理済みリクエストをJSONで渡し、標準出力に返されたリクエストで置き換える。
+// 標準出力が空の場合はリクエストを変更しない。返されたJSONにないフィールドは元の値を引き継ぐ。
+func (h *hookRunner) runPre(ctx context.Context, request *config.ProcessedRequest) error {
+	if h == nil || h.hooks.Pre == nil {
+		return nil
+	}
+	input, err := json.Marshal(request)
+	if err != nil {
+		return &preHookError{fmt.Errorf("pre hook: failed to encode request: %w", err)}
+	}
+	output, err := h.run(ctx, h.hooks.Pre, input)
+	if err != nil {
+		return &preHookError{fmt.Errorf("pre hook %s: %w", h.hooks.Pre.Command[0], err)}
+	}
+	if len(bytes.TrimSpace(output)) == 0 {
+		return nil
+	}
+
+	modified := *request
+	modified.Headers = nil // 返されたヘッダーで置き換える（削除されたヘッダーを残さない）
+	if err := json.Unmarshal(output, &modified); err != nil {
+		return &preHookError{fmt.Errorf("pre hook %s: invalid request JSON on stdout: %w", h.hooks.Pre.Command[0], err)}
+	}
+	if modified.Headers == nil {
+		modified.Headers = request.Headers
+	}
+	*request = modified
+	return nil
+}
+
+// runPost はpostフックに結果をJSONで渡す。失敗した場合はエラーを結果のhook_errorに記録する。
+func (h *hookRunner) runPost(ctx context.Context, result *config.Result) error {
+	if h == nil || h.hooks.Post == nil {
+		return nil
+	}
+	input, err := json.Marshal(result)
+	if err != nil {
+		err = fmt.Errorf("post hook: failed to encode result: %w", err)
+	} else if _, err = h.run(ctx, h.hooks.Post, input); err != nil {
+		err = fmt.Errorf("post hook %s: %w", h.hooks.Post.Command[0], err)
+	}
+	if err != nil {
+		result.HookError = err.Error()
+	}
+	return err
+}
+
+// run はコマンドを実行し、標準出力を返す。失敗した場合は標準エラー出力をエラーに含める。
+func (h *hookRunner) run(ctx context.Context, hook *config.HookConfig, input []byte) ([]byte, error) {
+	timeout := defaultHookTimeout
+	if hook.Timeout != "" {
+		parsed, err := time.ParseDuration(hook.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		timeout = parsed
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// フックはリクエスト定義で指定されたコマンドをそのまま実行する
+	cmd := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...) // #nosec G204
+	cmd.Dir = h.dir
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// 子プロセスが出力を開いたままでも、終了後は待ち続けない
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%w: %s", err, message)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+	httpClient "github.com/secureta/s2http-request/internal/http"
+	"github.com/secureta/s2http-request/internal/parser"
+)
+
+// writeHookScript はdirに実行可能なシェルスクリプトを作成する
+func writeHookScript(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0700); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+}
+
+func TestHookRunner_Pre(t *testing.T) {
+	dir := t.TempDir()
+	writeHookScript(t, dir, "sign.sh", `cat > seen.json; printf '{"Headers":{"X-Signed":"yes"},"Body":"changed"}'`)
+	writeHookScript(t, dir, "noop.sh", `cat > /dev/null`)
+	writeHookScript(t, dir, "fail.sh", `echo "solver unavailable" >&2; exit 3`)
+	writeHookScript(t, dir, "slow.sh", `exec sleep 5`)
+	writeHookScript(t, dir, "invalid.sh", `echo "not json"`)
+
+	tests := []struct {
+		name    string
+		hook    config.HookConfig
+		want    config.ProcessedRequest
+		wantErr string
+	}{
+		{
+			name: "modified request",
+			hook: config.HookConfig{Command: config.StringList{"./sign.sh"}},
+			want: config.ProcessedRequest{Method: "POST", URL: "http://example.com/", Headers: map[string]string{"X-Signed": "yes"}, Body: "changed"},
+		},
+		{
+			name: "empty output keeps the request",
+			hook: config.HookConfig{Command: config.StringList{"sh", "noop.sh"}},
+			want: config.ProcessedRequest{Method: "POST", URL: "http://example.com/", Headers: map[string]string{"X-Original": "1"}, Body: "body"},
+		},
+		{
+			name:    "failure includes stderr",
+			hook:    config.HookConfig{Command: config.StringList{"./fail.sh"}},
+			wantErr: "pre hook ./fail.sh: exit status 3: solver unavailable",
+		},
+		{
+			name:    "timeout",
+			hook:    config.HookConfig{Command: config.StringList{"./slow.sh"}, Timeout: "100ms"},
+			wantErr: "pre hook ./slow.sh: timed out after 100ms",
+		},
+		{
+			name:    "invalid JSON",
+			hook:    config.HookConfig{Command: config.StringList{"./invalid.sh"}},
+			wantErr: "pre hook ./invalid.sh: invalid request JSON on stdout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := newHookRunner(&config.RequestConfig{
+				FilePath: filepath.Join(dir, "request.yaml"),
+				Meta:     &config.MetaConfig{Hooks: &config.HooksConfig{Pre: &tt.hook}},
+			})
+			request := &config.ProcessedRequest{
+				Method:     "POST",
+				URL:        "http://example.com/",
+				Headers:    map[string]string{"X-Original": "1"},
+				Body:       "body",
+				DictValues: map[string]interface{}{"payload": "x"},
+			}
+
+			err := runner.runPre(context.Background(), request)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if request.Method != tt.want.Method || request.URL != tt.want.URL || request.Body != tt.want.Body ||
+				len(request.Headers) != len(tt.want.Headers) || request.Headers["X-Signed"] != tt.want.Headers["X-Signed"] {
+				t.Errorf("Unexpected request: %+v", request)
+			}
+			if request.DictValues["payload"] != "x" {
+				t.Errorf("Expected fields not in the JSON to be kept, got %+v", request.DictValues)
+			}
+		})
+	}
+
+	// フックは標準入力でリクエストを受け取り、リクエスト定義のディレクトリで実行される
+	seen, err := os.ReadFile(filepath.Join(dir, "seen.json"))
+	if err != nil || !strings.Contains(string(seen), `"Method":"POST"`) || !strings.Contains(string(seen), `"X-Original":"1"`) {
+		t.Errorf("Unexpected hook input: %s (%v)", seen, err)
+	}
+}
+
+func TestProcessRequestConfigs_RunsHooks(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.URL.Query().Get("q")+" "+r.Header.Get("X-Captcha"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeHookScript(t, dir, "pre.sh", `sed 's/"Headers":{/"Headers":{"X-Captcha":"solved",/'`)
+	writeHookScript(t, dir, "post.sh", `cat >> results.jsonl; echo >> results.jsonl; grep -q 'q=b"' results.jsonl && { echo "disk full" >&2; exit 1; }; exit 0`)
+
+	content := `method: GET
+path: /
+query:
+  q: {$dict: q}
+dict:
+  q: [a, b]
+meta:
+  hooks:
+    pre:
+      command: ./pre.sh
+    post:
+      command: [sh, post.sh]
+      timeout: 5s
+`
+	p := parser.NewParser()
+	configs, err := p.ParseMultiple([]byte(content), ".yaml", filepath.Join(dir, "hooks.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	client, err := httpClient.NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	d, err := newDispatcher(client, &config.CLIConfig{Host: server.URL, Timeout: 5 * time.Second, Concurrency: 1}, "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	var results []*config.Result
+	if err := processRequestConfigs(p, d, configs, "hooks.yaml", nil, func(result *config.Result) {
+		results = append(results, result)
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if strings.Join(received, ",") != "a solved,b solved" {
+		t.Errorf("Expected the pre hook to modify each request, got %q", received)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].Request.Headers["X-Captcha"] != "solved" || results[0].HookError != "" {
+		t.Errorf("Expected the result to show the modified request, got %+v", results[0])
+	}
+	if results[1].HookError != "post hook sh: exit status 1: disk full" {
+		t.Errorf("Expected the post hook error in the result, got %q", results[1].HookError)
+	}
+
+	logged, err := os.ReadFile(filepath.Join(dir, "results.jsonl"))
+	if err != nil || strings.Count(string(logged), `"status_code":200`) != 2 {
+		t.Errorf("Expected the post hook to receive both results, got %s (%v)", logged, err)
+	}
+}
+
+func TestProcessRequestConfigs_EmitsResultForFailedPreHook(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.URL.Query().Get("q"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeHookScript(t, dir, "pre.sh", `grep -q 'q=b' && { echo "solver down" >&2; exit 1; }; exit 0`)
+	writeHookScript(t, dir, "post.sh", `cat >> results.jsonl; echo >> results.jsonl`)
+
+	content := `method: GET
+path: /
+query:
+  q: {$dict: q}
+dict:
+  q: [a, b]
+expect:
+  status: 200
+meta:
+  hooks:
+    pre:
+      command: ./pre.sh
+    post:
+      command: ./post.sh
+`
+	p := parser.NewParser()
+	configs, err := p.ParseMultiple([]byte(content), ".yaml", filepath.Join(dir, "hooks.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	client, err := httpClient.NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	d, err := newDispatcher(client, &config.CLIConfig{Host: server.URL, Timeout: 5 * time.Second, Concurrency: 1}, "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	var buf bytes.Buffer
+	sink := newNDJSONSink(&buf, nil)
+	if err := processRequestConfigs(p, d, configs, "hooks.yaml", nil, sink.Emit); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if strings.Join(received, ",") != "a" {
+		t.Errorf("Expected only the request with a successful pre hook to be sent, got %q", received)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 NDJSON lines, got %d: %s", len(lines), buf.String())
+	}
+	var failed config.Result
+	if err := json.Unmarshal([]byte(lines[1]), &failed); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if failed.HookError != "pre hook ./pre.sh: exit status 1: solver down" || failed.Response.StatusCode != 0 || !strings.Contains(failed.Request.URL, "q=b") {
+		t.Errorf("Expected a result with the pre hook error, got %+v", failed)
+	}
+	if failed.Passed != nil || d.assertions.passed != 1 || d.assertions.errors != 1 {
+		t.Errorf("Expected the failed pre hook to count as an error, got %+v", d.assertions)
+	}
+
+	// postフックは送信したリクエストの結果だけを受け取る
+	logged, err := os.ReadFile(filepath.Join(dir, "results.jsonl"))
+	if err != nil || strings.Count(string(logged), `"status_code":200`) != 1 || strings.Contains(string(logged), "q=b") {
+		t.Errorf("Expected the post hook to receive only the sent request, got %s (%v)", logged, err)
+	}
+}
@@ -3,19 +3,23 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/secureta/s2http-request/internal/config"
 	"github.com/secureta/s2http-request/internal/http"
 	"github.com/secureta/s2http-request/internal/parser"
+	"github.com/secureta/s2http-request/internal/response"
 	"gopkg.in/yaml.v3"
 )
 
@@ -112,11 +116,11 @@ func getDefaultUserAgent() string {
 }
 
 // processStdin reads from stdin and processes the input
-func processStdin(p *parser.Parser, client *http.Client, cliConfig *config.CLIConfig, userAgent string, variables map[string]interface{}) ([]*config.Result, error) {
+func processStdin(p *parser.Parser, d *dispatcher, variables map[string]interface{}, emit func(*config.Result)) error {
 	// Read all data from stdin
 	data, err := io.ReadAll(os.Stdin)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from stdin: %w", err)
+		return fmt.Errorf("failed to read from stdin: %w", err)
 	}
 
 	// Detect the format of the input
@@ -125,79 +129,10 @@ func processStdin(p *parser.Parser, client *http.Client, cliConfig *config.CLICo
 	// Parse the input
 	requestConfigs, err := p.ParseMultiple(data, format, "stdin")
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse stdin input: %w", err)
+		return fmt.Errorf("failed to parse stdin input: %w", err)
 	}
 
-	var allResults []*config.Result
-
-	// Process each request config
-	for _, requestConfig := range requestConfigs {
-		// Create context with variables
-		ctx := context.Background()
-		if len(variables) > 0 {
-			ctx = context.WithValue(ctx, "variables", variables)
-		}
-
-		// Process requests with Request ID
-		processedRequests, err := p.ProcessRequestsWithConfig(ctx, requestConfig, cliConfig.Host, cliConfig)
-		if err != nil {
-			return nil, fmt.Errorf("failed to process requests: %w", err)
-		}
-
-		// Send each processed request
-		for _, processedRequest := range processedRequests {
-			// Set User-Agent if not specified
-			if _, exists := processedRequest.Headers["User-Agent"]; !exists {
-				if userAgent != "" {
-					processedRequest.Headers["User-Agent"] = userAgent
-				} else {
-					processedRequest.Headers["User-Agent"] = getDefaultUserAgent()
-				}
-			}
-
-			// Send the request
-			ctx, cancel := context.WithTimeout(context.Background(), cliConfig.Timeout)
-
-			var response *config.ResponseData
-			if cliConfig.Retry > 0 {
-				response, err = client.SendRequestWithRetry(ctx, processedRequest, cliConfig.Retry)
-			} else {
-				response, err = client.SendRequest(ctx, processedRequest)
-			}
-
-			// Always cancel the context when done with this request
-			cancel()
-
-			if err != nil {
-				log.Printf("Failed to send request: %v", err)
-				continue
-			}
-
-			// Create result
-			result := &config.Result{
-				Request:  *processedRequest,
-				Response: *response,
-				Metadata: map[string]interface{}{
-					"file":       "stdin",
-					"timestamp":  time.Now().Format(time.RFC3339),
-					"request_id": processedRequest.RequestID,
-				},
-			}
-
-			allResults = append(allResults, result)
-
-			// Verbose output
-			if cliConfig.Verbose {
-				fmt.Printf("Request: %s %s\n", processedRequest.Method, processedRequest.URL)
-				if processedRequest.RequestID != "" {
-					fmt.Printf("Request ID: %s\n", processedRequest.RequestID)
-				}
-				fmt.Printf("Response: %d\n", response.StatusCode)
-			}
-		}
-	}
-
-	return allResults, nil
+	return processRequestConfigs(p, d, requestConfigs, "stdin", variables, emit)
 }
 
 // detectFormat detects the format of the input data
@@ -242,6 +177,9 @@ func handleValidateCommand() {
 		verbose     = validateCmd.Bool("verbose", false, "Verbose output")
 		showVersion = validateCmd.Bool("version", false, "Show version")
 	)
+	var plugins, pluginDirs pluginFlags
+	validateCmd.Var(&plugins, "plugin", "Load a plugin executable that provides a $function (repeatable)")
+	validateCmd.Var(&pluginDirs, "plugin-dir", "Load every executable in this directory as a plugin (repeatable)")
 
 	// Parse arguments starting from position 2 (after "validate")
 	if err := validateCmd.Parse(os.Args[2:]); err != nil {
@@ -265,8 +203,16 @@ func handleValidateCommand() {
 		os.Exit(1)
 	}
 
-	// パーサーの作成
+	// パーサーの作成（プラグインの宣言もここで検証する）
 	p := parser.NewParser()
+	loaded, err := loadPlugins(p, plugins, pluginDirs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Failed to load plugins: %v\n", err)
+		os.Exit(1)
+	}
+	if *verbose {
+		printLoadedPlugins(os.Stdout, loaded)
+	}
 
 	var validationErrors []ValidationError
 	totalFiles := 0
@@ -379,7 +325,13 @@ func main() {
 		return
 	}
 
-	// Handle main command (no variable override support)
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		// Handle export subcommand
+		handleExportCommand()
+		return
+	}
+
+	// Handle main command
 	var (
 		host            = flag.String("host", "http://localhost", "Target host URL")
 		timeout         = flag.Duration("timeout", 30*time.Second, "Request timeout")
@@ -387,13 +339,40 @@ func main() {
 		proxy           = flag.String("proxy", "", "Proxy URL")
 		verbose         = flag.Bool("verbose", false, "Verbose output")
 		output          = flag.String("output", "", "Output file path")
-		format          = flag.String("format", "json", "Output format (json, csv, table)")
+		format          = flag.String("format", "json", "Output format (json, ndjson, csv, table)")
 		userAgent       = flag.String("user-agent", "", "Override User-Agent header")
 		requestID       = flag.String("request-id", "", "Enable Request ID (path=head|tail, query=<key>, header=<key>)")
-		maxCombinations = flag.Int("max-combinations", 1000, "Maximum number of dict combinations to generate")
+		maxCombinations = flag.Int("max-combinations", 0, "Optional safety cap on dict combinations per request definition (0 = unlimited)")
+		concurrency     = flag.Int("concurrency", 1, "Number of requests to send concurrently")
+		unordered       = flag.Bool("unordered", false, "Output results in completion order instead of request order")
+		rate            = flag.String("rate", "", "Maximum request rate (e.g. 20/s, 100/m, 1/500ms)")
+		burst           = flag.Int("burst", 1, "Maximum burst size for --rate")
+		ratePerHost     = flag.Bool("rate-per-host", false, "Apply --rate to each target host separately")
+		dryRun          = flag.Bool("dry-run", false, "Print each request as the exact HTTP/1.1 bytes without sending it")
+		showSecrets     = flag.Bool("show-secrets", false, "Print meta.auth credentials in --dry-run output instead of masking them")
+		checkpointPath  = flag.String("checkpoint", "", "Record completed requests to this file so an interrupted or killed run can be resumed (json, csv and table results are also saved to <file>.results until written)")
+		resume          = flag.Bool("resume", false, "Skip requests recorded in --checkpoint and append to the existing output")
+		verdict         = flag.Bool("verdict", false, "Classify responses as blocked, passed or error using the built-in WAF signatures")
+		verdictRules    = flag.String("verdict-rules", "", "Load verdict rules from a YAML file (implies --verdict)")
+		cookieJar       = flag.String("cookie-jar", "", "Share cookies across requests, loading and saving them in this Netscape-format file")
+		insecure        = flag.Bool("insecure", false, "Do not verify the server's TLS certificate")
+		caCert          = flag.String("cacert", "", "Verify the server's TLS certificate with the CA certificates in this PEM file")
+		clientCert      = flag.String("cert", "", "Client certificate PEM file for mutual TLS")
+		clientKey       = flag.String("key", "", "Private key PEM file for --cert (defaults to the --cert file)")
+		sni             = flag.String("sni", "", "Server name to send in the TLS handshake and verify the certificate against")
+		tlsMinVersion   = flag.String("tls-min-version", "", "Minimum TLS version (1.0, 1.1, 1.2, 1.3; default 1.2)")
+		tlsMaxVersion   = flag.String("tls-max-version", "", "Maximum TLS version (1.0, 1.1, 1.2, 1.3)")
 		showVersion     = flag.Bool("version", false, "Show version")
 	)
 
+	vars := make(varFlags)
+	var varFiles varFileFlags
+	flag.Var(vars, "var", "Override variable (key=value, repeatable)")
+	flag.Var(&varFiles, "var-file", "Load variables from a YAML, JSON or dotenv file (repeatable, later files win)")
+	var plugins, pluginDirs pluginFlags
+	flag.Var(&plugins, "plugin", "Load a plugin executable that provides a $function (repeatable)")
+	flag.Var(&pluginDirs, "plugin-dir", "Load every executable in this directory as a plugin (repeatable)")
+
 	flag.Parse()
 
 	if *showVersion {
@@ -401,9 +380,28 @@ func main() {
 		return
 	}
 
+	// 変数ファイルと--varをマージ（--var > 後のファイル > 前のファイル）
+	variables, err := mergeVariables(varFiles, vars)
+	if err != nil {
+		log.Fatalf("Failed to load variables: %v", err)
+	}
+
 	// Validate MaxCombinations
-	if *maxCombinations <= 0 {
-		log.Fatalf("max-combinations must be greater than 0, got %d", *maxCombinations)
+	if *maxCombinations < 0 {
+		log.Fatalf("max-combinations must not be negative, got %d", *maxCombinations)
+	}
+
+	// Validate Concurrency
+	if *concurrency <= 0 {
+		log.Fatalf("concurrency must be greater than 0, got %d", *concurrency)
+	}
+
+	// Validate Checkpoint
+	if *resume && *checkpointPath == "" {
+		log.Fatalf("--resume requires --checkpoint")
+	}
+	if *checkpointPath != "" && *dryRun {
+		log.Fatalf("--checkpoint cannot be used with --dry-run")
 	}
 
 	files := flag.Args()
@@ -420,7 +418,6 @@ func main() {
 	// Request IDの設定をパース
 	var requestIDConfig *config.RequestIDConfig
 	if *requestID != "" {
-		var err error
 		requestIDConfig, err = parseRequestIDOption(*requestID)
 		if err != nil {
 			log.Fatalf("Invalid request-id option: %v", err)
@@ -439,6 +436,27 @@ func main() {
 		Files:           files,
 		RequestID:       requestIDConfig,
 		MaxCombinations: *maxCombinations,
+		Concurrency:     *concurrency,
+		Unordered:       *unordered,
+		Rate:            *rate,
+		Burst:           *burst,
+		RatePerHost:     *ratePerHost,
+		DryRun:          *dryRun,
+		ShowSecrets:     *showSecrets,
+		Checkpoint:      *checkpointPath,
+		Resume:          *resume,
+		Verdict:         *verdict || *verdictRules != "",
+		VerdictRules:    *verdictRules,
+		CookieJar:       *cookieJar,
+		TLS: config.TLSConfig{
+			Insecure:   *insecure,
+			CACert:     *caCert,
+			Cert:       *clientCert,
+			Key:        *clientKey,
+			SNI:        *sni,
+			MinVersion: *tlsMinVersion,
+			MaxVersion: *tlsMaxVersion,
+		},
 	}
 
 	// If reading from stdin, update the Files field
@@ -451,42 +469,147 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create HTTP client: %v", err)
 	}
+	if err := client.SetTLSConfig(cliConfig.TLS); err != nil {
+		log.Fatalf("Invalid TLS options: %v", err)
+	}
+
+	// クッキージャーの読み込み（ファイルが存在しない場合は空のジャーから始める）
+	var jar *http.CookieJar
+	if cliConfig.CookieJar != "" {
+		jar, err = http.LoadCookieJar(cliConfig.CookieJar)
+		if err != nil {
+			log.Fatalf("Failed to load cookie jar: %v", err)
+		}
+		client.SetCookieJar(jar)
+	}
+
+	// 送信処理の作成
+	d, err := newDispatcher(client, cliConfig, *userAgent)
+	if err != nil {
+		log.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	// 判定ルールの読み込み
+	if cliConfig.VerdictRules != "" {
+		d.verdictRules, err = response.LoadVerdictConfig(cliConfig.VerdictRules)
+		if err != nil {
+			log.Fatalf("Failed to load verdict rules: %v", err)
+		}
+	}
+
+	// 中断シグナルを受けたら未送信のリクエストを送らずに終了し、取得済みの結果を出力する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// 2回目のシグナルでは即座に終了できるよう、通常のシグナル処理に戻す
+		stop()
+	}()
+	d.ctx = ctx
+
+	// 出力先の作成
+	var sink resultSink
+	if cliConfig.DryRun {
+		// ドライランでは送信せず、リクエストのワイヤー表現を書き出す
+		renderer, err := newRenderSink(cliConfig.Output)
+		if err != nil {
+			log.Fatalf("Failed to open output: %v", err)
+		}
+		d.renderer = renderer
+		sink = renderer
+	} else {
+		sink, err = newResultSink(cliConfig)
+		if err != nil {
+			log.Fatalf("Failed to open output: %v", err)
+		}
+	}
+
+	// チェックポイントの作成
+	if cliConfig.Checkpoint != "" {
+		// NDJSON以外で出力前の結果はresultSinkが退避ファイルに保存するため、完了したらすぐに記録する
+		d.checkpoint, err = openCheckpoint(cliConfig.Checkpoint, cliConfig.Resume)
+		if err != nil {
+			log.Fatalf("Failed to open checkpoint: %v", err)
+		}
+		if cliConfig.Resume {
+			log.Printf("Resuming from checkpoint: %d request(s) already completed", d.checkpoint.completed())
+		}
+	}
 
 	// パーサーの作成
 	p := parser.NewParser()
+	loaded, err := loadPlugins(p, plugins, pluginDirs)
+	if err != nil {
+		log.Fatalf("Failed to load plugins: %v", err)
+	}
+	if cliConfig.Verbose {
+		printLoadedPlugins(d.verboseOut, loaded)
+	}
 
-	var results []*config.Result
-
+	aborted := false
 	if readFromStdin {
 		// Process stdin input
-		stdinResults, err := processStdin(p, client, cliConfig, *userAgent, nil)
-		if err != nil {
+		if err := processStdin(p, d, variables, sink.Emit); err != nil && ctx.Err() == nil {
+			_ = sink.Close()
 			log.Fatalf("Error processing stdin: %v", err)
 		}
-		results = append(results, stdinResults...)
 	} else {
 		// 各ファイルを処理
 		for _, filePath := range files {
-			fileResults, err := processFile(p, client, cliConfig, filePath, *userAgent, nil)
-			if err != nil {
+			if err := processFile(p, d, filePath, variables, sink.Emit); err != nil {
+				if ctx.Err() != nil {
+					break
+				}
 				log.Printf("Error processing file %s: %v", filePath, err)
-				continue
+				if errors.Is(err, errRunAborted) {
+					aborted = true
+					break
+				}
 			}
-			results = append(results, fileResults...)
 		}
 	}
 
 	// 結果の出力
-	if err := outputResults(results, cliConfig); err != nil {
-		log.Fatalf("Failed to output results: %v", err)
+	outputErr := sink.Close()
+	if jar != nil {
+		// 中断した場合も、それまでに受け取ったクッキーを保存する
+		if err := jar.Save(cliConfig.CookieJar); err != nil {
+			log.Printf("Failed to save cookie jar: %v", err)
+		}
+	}
+	if d.checkpoint != nil {
+		if err := d.checkpoint.Close(); err != nil {
+			log.Printf("Failed to save checkpoint: %v", err)
+		}
+	}
+	if outputErr != nil {
+		log.Fatalf("Failed to output results: %v", outputErr)
+	}
+
+	// 判定とexpectの集計結果を表示
+	d.verdicts.print(os.Stderr)
+	d.assertions.print(os.Stderr)
+
+	if ctx.Err() != nil {
+		if cliConfig.Checkpoint != "" {
+			log.Printf("Interrupted; run again with --checkpoint %s --resume to continue", cliConfig.Checkpoint)
+		} else {
+			log.Printf("Interrupted")
+		}
+		os.Exit(130)
+	}
+
+	// CIで検出できるよう、アサーションが失敗した場合や実行を中止した場合は非ゼロで終了する
+	if !d.assertions.ok() || aborted {
+		os.Exit(1)
 	}
 }
 
-func processFile(p *parser.Parser, client *http.Client, cliConfig *config.CLIConfig, filePath string, userAgent string, variables map[string]interface{}) ([]*config.Result, error) {
+func processFile(p *parser.Parser, d *dispatcher, filePath string, variables map[string]interface{}, emit func(*config.Result)) error {
 	// ファイルの読み込み。The CLI intentionally accepts user-supplied request definition paths.
 	data, err := os.ReadFile(filePath) // #nosec G304
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return fmt.Errorf("failed to read file: %w", err)
 	}
 
 	// ファイル拡張子の取得
@@ -495,99 +618,136 @@ func processFile(p *parser.Parser, client *http.Client, cliConfig *config.CLICon
 	// リクエスト設定の解析（複数のドキュメントに対応）
 	requestConfigs, err := p.ParseMultiple(data, ext, filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse request config: %w", err)
+		return fmt.Errorf("failed to parse request config: %w", err)
 	}
 
-	var allResults []*config.Result
+	return processRequestConfigs(p, d, requestConfigs, filePath, variables, emit)
+}
+
+// processRequestConfigs は解析済みのリクエスト設定を展開し、ワーカープールで送信する。
+// 送信結果は受信するたびにemitへ渡される。
+func processRequestConfigs(p *parser.Parser, d *dispatcher, requestConfigs []*config.RequestConfig, source string, variables map[string]interface{}, emit func(*config.Result)) error {
+	// extractで取り出した変数（後続のドキュメントに引き継ぐ）
+	extracted := make(map[string]interface{})
 
 	// 各リクエスト設定を処理
-	for _, requestConfig := range requestConfigs {
+	for docIndex, requestConfig := range requestConfigs {
 		// Create context with variables
 		ctx := context.Background()
-		if len(variables) > 0 {
-			ctx = context.WithValue(ctx, "variables", variables)
+		if docVariables := chainVariables(variables, extracted); len(docVariables) > 0 {
+			ctx = context.WithValue(ctx, "variables", docVariables)
 		}
 
-		// リクエストの処理（辞書展開を含む）
-		processedRequests, err := p.ProcessRequestsWithConfig(ctx, requestConfig, cliConfig.Host, cliConfig)
-		if err != nil {
-			return nil, fmt.Errorf("failed to process requests: %w", err)
-		}
-
-		// 各処理済みリクエストを送信
-		for _, processedRequest := range processedRequests {
-			// User-Agentの設定処理
-			if _, exists := processedRequest.Headers["User-Agent"]; !exists {
-				// JSONやYAMLファイルでUser-Agentが指定されていない場合
-				if userAgent != "" {
-					// コマンドライン引数が指定されている場合はそれを使用
-					processedRequest.Headers["User-Agent"] = userAgent
-				} else {
-					// コマンドライン引数も指定されていない場合はデフォルト値を使用
-					processedRequest.Headers["User-Agent"] = getDefaultUserAgent()
-				}
-			}
+		// rawのリクエストは変更しないため--request-idを付与せずに送信する（警告は実行中に1回のみ）
+		if requestConfig.Raw != nil && d.cliConfig.RequestID != nil && !d.warnedRawRequestID {
+			log.Printf("--request-id is not applied to raw requests; write the ID in the raw request instead")
+			d.warnedRawRequestID = true
+		}
 
-			// リクエストの送信
-			ctx, cancel := context.WithTimeout(context.Background(), cliConfig.Timeout)
+		// リクエストの生成（辞書展開は送信に合わせて1件ずつ行う）
+		requests, err := p.IterateRequestsWithConfig(ctx, requestConfig, d.cliConfig.Host, d.cliConfig)
+		if err != nil {
+			return fmt.Errorf("failed to process requests: %w", err)
+		}
 
-			var response *config.ResponseData
-			if cliConfig.Retry > 0 {
-				response, err = client.SendRequestWithRetry(ctx, processedRequest, cliConfig.Retry)
-			} else {
-				response, err = client.SendRequest(ctx, processedRequest)
-			}
+		// meta.rateが指定されている場合はドキュメント単位でレートを上書き
+		docDispatcher, err := d.forRequestConfig(requestConfig)
+		if err != nil {
+			return err
+		}
 
-			// Always cancel the context when done with this request
-			cancel()
+		// meta.verdictや--verdictが指定されている場合はレスポンスを判定する
+		classifier, err := d.classifierFor(requestConfig)
+		if err != nil {
+			return fmt.Errorf("invalid verdict rules: %w", err)
+		}
 
-			if err != nil {
-				log.Printf("Failed to send request: %v", err)
-				continue
-			}
+		// meta.authがoauth2の場合は、送信を始める前にトークンを取得する
+		if err := d.prepareOAuth2(requests); err != nil {
+			return err
+		}
 
-			// 結果の作成
-			result := &config.Result{
-				Request:  *processedRequest,
-				Response: *response,
-				Metadata: map[string]interface{}{
-					"file":       filePath,
-					"timestamp":  time.Now().Format(time.RFC3339),
-					"request_id": processedRequest.RequestID,
-				},
-			}
+		// meta.baselineが指定されている場合は、組み合わせの前にベースラインを送信して比較に使用する
+		baseline, err := docDispatcher.sendBaseline(requests, requestConfig)
+		if err != nil {
+			return fmt.Errorf("failed to process requests: %w", err)
+		}
 
-			allResults = append(allResults, result)
+		// チェックポイントで完了済みの組み合わせは送信しない。
+		// extractを持つドキュメントは後続に渡す値（セッションなど）を取り直すため、再開時も送信する。
+		var pending requestSource = requests
+		if d.checkpoint != nil && len(requestConfig.Extract) == 0 {
+			pending = d.checkpoint.filter(requests, source, docIndex)
+		}
 
-			// Verbose出力
-			if cliConfig.Verbose {
-				fmt.Printf("Request: %s %s\n", processedRequest.Method, processedRequest.URL)
-				if processedRequest.RequestID != "" {
-					fmt.Printf("Request ID: %s\n", processedRequest.RequestID)
+		// 各処理済みリクエストを送信（失敗したリクエストがあっても残りは送信を続ける）
+		errs := docDispatcher.dispatch(source, pending, func(index int, result *config.Result) {
+			if result.HookError != "" {
+				// preフックが失敗して送信しなかったリクエストは、判定などを行わずに出力する。
+				// エラーとしての集計は下のerrsで行い、チェックポイントには記録しない（再開時に再送する）
+				emit(result)
+				return
+			}
+			if len(requestConfig.Extract) > 0 {
+				values, err := response.Extract(requestConfig.Extract, &result.Response)
+				if err != nil {
+					log.Printf("Failed to extract from %s %s: %v", result.Request.Method, result.Request.URL, err)
+				}
+				// dictで複数のリクエストに展開された場合は、後に出力された結果の値が優先される
+				for key, value := range values {
+					extracted[key] = value
 				}
-				fmt.Printf("Response: %d\n", response.StatusCode)
+				result.Extracted = values
 			}
+			if baseline != nil {
+				result.Baseline = response.Compare(baseline, &result.Response, requestConfig.Meta.Baseline)
+			}
+			if requestConfig.Expect != nil {
+				result.Assertions = response.Evaluate(requestConfig.Expect, &result.Response)
+				passed := response.Passed(result.Assertions)
+				result.Passed = &passed
+				d.assertions.record(source, result)
+			}
+			if classifier != nil {
+				result.Verdict, result.VerdictRule = classifier.Classify(&result.Response)
+				d.verdicts.record(source, result)
+			}
+			if err := docDispatcher.hooks.runPost(d.ctx, result); err != nil {
+				log.Printf("Failed to run hook for %s %s: %v", result.Request.Method, result.Request.URL, err)
+			}
+			emit(result)
+			if d.checkpoint != nil {
+				d.checkpoint.markDone(checkpointEntry{File: source, Document: docIndex, Combination: index})
+			}
+		})
+		for _, err := range errs {
+			log.Printf("Failed to send request: %v", err)
+			if requestConfig.Expect != nil {
+				// 応答を得られなかったリクエストはアサーションの失敗として扱う
+				d.assertions.recordError(source, err)
+			}
+			if classifier != nil {
+				d.verdicts.recordError(source, err)
+			}
+		}
+
+		if err := requests.Err(); err != nil {
+			return fmt.Errorf("failed to process requests: %w", err)
+		}
+		if err := d.ctx.Err(); err != nil {
+			return fmt.Errorf("interrupted: %w", err)
 		}
 	}
 
-	return allResults, nil
+	return nil
 }
 
 func outputResults(results []*config.Result, cliConfig *config.CLIConfig) error {
-	var output []byte
-	var err error
-
-	switch cliConfig.Format {
-	case config.OutputFormatJSON:
-		output, err = json.MarshalIndent(results, "", "  ")
-	case config.OutputFormatCSV:
-		output, err = formatAsCSV(results)
-	case config.OutputFormatTable:
-		output, err = formatAsTable(results)
-	default:
-		output, err = json.MarshalIndent(results, "", "  ")
+	if cliConfig.Output != "" && cliConfig.Resume {
+		return appendResults(cliConfig.Output, results, cliConfig.Format)
 	}
 
+	output, err := formatResults(results, cliConfig.Format)
 	if err != nil {
 		return fmt.Errorf("failed to format output: %w", err)
 	}
@@ -600,6 +760,26 @@ func outputResults(results []*config.Result, cliConfig *config.CLIConfig) error
 	return nil
 }
 
+// formatResults は結果を指定のフォーマットに変換する
+func formatResults(results []*config.Result, format config.OutputFormat) ([]byte, error) {
+	var output []byte
+	var err error
+
+	switch format {
+	case config.OutputFormatJSON:
+		output, err = json.MarshalIndent(results, "", "  ")
+	case config.OutputFormatNDJSON:
+		output, err = formatAsNDJSON(results)
+	case config.OutputFormatCSV:
+		output, err = formatAsCSV(results)
+	case config.OutputFormatTable:
+		output, err = formatAsTable(results)
+	default:
+		output, err = json.MarshalIndent(results, "", "  ")
+	}
+	return output, err
+}
+
 func formatAsCSV(results []*config.Result) ([]byte, error) {
 	var lines []string
 	lines = append(lines, "Method,URL,StatusCode,ResponseTime,BodyLength,RequestID")
@@ -1,7 +1,15 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+	httpClient "github.com/secureta/s2http-request/internal/http"
+	"github.com/secureta/s2http-request/internal/parser"
 )
 
 func TestVarFlags_Set(t *testing.T) {
@@ -242,3 +250,83 @@ func compareValues(expected, actual interface{}) bool {
 	// Handle primitive types
 	return expected == actual
 }
+
+func TestProcessRequestConfigs_ChainsExtractedVariables(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "SESSIONID", Value: "s3cr3t"})
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data":{"csrf":"c5rf","user":{"id":42}}}`))
+		default:
+			received = append(received, r.URL.Path+" "+r.Header.Get("Cookie")+" "+r.URL.Query().Get("csrf"))
+			_, _ = w.Write([]byte("OK"))
+		}
+	}))
+	defer server.Close()
+
+	client, err := httpClient.NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	content := `method: POST
+path: /login
+extract:
+  session: {cookie: SESSIONID}
+  csrf: {json: data.csrf}
+  user_id: {json: data.user.id}
+  missing: {header: X-Missing, default: fallback}
+---
+method: GET
+path: {$concat: [/users/, {$var: user_id}]}
+headers:
+  Cookie: {$concat: [SESSIONID=, {$var: session}]}
+query:
+  csrf: {$var: csrf}
+  page: {$dict: page}
+dict:
+  page: [1, 2]
+`
+	p := parser.NewParser()
+	configs, err := p.ParseMultiple([]byte(content), ".yaml", "chain.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	d, err := newDispatcher(client, &config.CLIConfig{Host: server.URL, Timeout: 5 * time.Second, Concurrency: 2}, "")
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	var results []*config.Result
+	// CLI変数は抽出した値で上書きされる（ドライランなどでの仮の値として使える）
+	variables := map[string]interface{}{"csrf": "placeholder"}
+	if err := processRequestConfigs(p, d, configs, "chain.yaml", variables, func(result *config.Result) {
+		results = append(results, result)
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	if results[0].Extracted["session"] != "s3cr3t" || results[0].Extracted["missing"] != "fallback" {
+		t.Errorf("Unexpected extracted values: %v", results[0].Extracted)
+	}
+	if results[1].Extracted != nil {
+		t.Errorf("Expected no extracted values for documents without extract, got %v", results[1].Extracted)
+	}
+	if len(received) != 2 {
+		t.Errorf("Expected 2 chained requests, got %v", received)
+	}
+	for _, got := range received {
+		if got != "/users/42 SESSIONID=s3cr3t c5rf" {
+			t.Errorf("Expected chained values in request, got %q", got)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// maskedValue は出力時に認証情報を置き換える文字列
+const maskedValue = "****"
+
+// maskSecrets は認証情報（request.Secrets）をマスクしたリクエストのコピーを返す。
+// 無関係な値を壊さないよう、ヘッダーは値全体か認証スキーム以降が一致する場合、
+// クエリパラメータは値全体が一致する場合にのみマスクする。Digestの資格情報はパスワードをマスクする。
+func maskSecrets(request config.ProcessedRequest) config.ProcessedRequest {
+	if request.DigestAuth != nil {
+		request.DigestAuth = &config.DigestAuth{Username: request.DigestAuth.Username, Password: maskedValue}
+	}
+	if len(request.Secrets) == 0 {
+		return request
+	}
+
+	headers := make(map[string]string, len(request.Headers))
+	for key, value := range request.Headers {
+		for _, secret := range request.Secrets {
+			if value == secret {
+				value = maskedValue
+			} else if scheme, credentials, ok := strings.Cut(value, " "); ok && credentials == secret {
+				value = scheme + " " + maskedValue
+			}
+		}
+		headers[key] = value
+	}
+	request.Headers = headers
+
+	request.URL = maskQuery(request.URL, request.Secrets)
+	request.RawRequestTarget = maskQuery(request.RawRequestTarget, request.Secrets)
+	return request
+}
+
+// maskQuery はURLまたはリクエストターゲットのクエリパラメータのうち、値がsecretsと一致するものをマスクする
+func maskQuery(target string, secrets []string) string {
+	base, query, ok := strings.Cut(target, "?")
+	if !ok {
+		return target
+	}
+	query, fragment, hasFragment := strings.Cut(query, "#")
+
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		for _, secret := range secrets {
+			if value == url.QueryEscape(secret) {
+				params[i] = key + "=" + maskedValue
+				break
+			}
+		}
+	}
+
+	masked := base + "?" + strings.Join(params, "&")
+	if hasFragment {
+		masked += "#" + fragment
+	}
+	return masked
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+func TestMaskSecrets(t *testing.T) {
+	request := config.ProcessedRequest{
+		Method:           "GET",
+		URL:              "http://example.com/t?api_key=k%26v&q=k%26v&other=tk#frag",
+		RawRequestTarget: "/t?api_key=k%26v",
+		Headers: map[string]string{
+			"Authorization": "Bearer tk",
+			"X-Api-Key":     "k&v",
+			"User-Agent":    "s2req/dev (tk)",
+		},
+		Secrets: []string{"tk", "k&v"},
+	}
+
+	masked := maskSecrets(request)
+	if masked.URL != "http://example.com/t?api_key=****&q=****&other=****#frag" {
+		t.Errorf("Unexpected URL: %s", masked.URL)
+	}
+	if masked.RawRequestTarget != "/t?api_key=****" {
+		t.Errorf("Unexpected request target: %s", masked.RawRequestTarget)
+	}
+	if masked.Headers["Authorization"] != "Bearer ****" || masked.Headers["X-Api-Key"] != "****" {
+		t.Errorf("Expected credentials to be masked, got %v", masked.Headers)
+	}
+	// 値の一部に含まれるだけの場合はマスクしない
+	if masked.Headers["User-Agent"] != "s2req/dev (tk)" {
+		t.Errorf("Expected unrelated header to be kept, got %s", masked.Headers["User-Agent"])
+	}
+	// 送信に使う元のリクエストは変更しない
+	if request.Headers["Authorization"] != "Bearer tk" {
+		t.Errorf("Expected the original request to be unchanged")
+	}
+
+	digest := config.ProcessedRequest{DigestAuth: &config.DigestAuth{Username: "admin", Password: "pw"}}
+	if got := maskSecrets(digest); got.DigestAuth.Username != "admin" || got.DigestAuth.Password != "****" {
+		t.Errorf("Expected digest password to be masked, got %+v", got.DigestAuth)
+	}
+	if digest.DigestAuth.Password != "pw" {
+		t.Errorf("Expected the original digest credentials to be unchanged")
+	}
+
+	plain := config.ProcessedRequest{URL: "http://example.com/?q=1"}
+	if got := maskSecrets(plain); got.URL != plain.URL {
+		t.Errorf("Expected request without secrets to be unchanged, got %s", got.URL)
+	}
+}
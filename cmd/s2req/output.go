@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// resultSink は送信結果の出力先
+type resultSink interface {
+	// Emit は結果を1件受け取る。書き込みエラーは記録され、Closeで返される。
+	Emit(result *config.Result)
+	// Close は出力を完了する
+	Close() error
+}
+
+// newResultSink は出力フォーマットに応じたresultSinkを作成する
+func newResultSink(cliConfig *config.CLIConfig) (resultSink, error) {
+	if cliConfig.Format != config.OutputFormatNDJSON {
+		sink := &bufferedSink{cliConfig: cliConfig}
+		if cliConfig.Checkpoint != "" {
+			// 強制終了に備え、出力前の結果を退避する。再開時は前回出力できなかった結果を引き継ぐ
+			spool, recovered, err := openResultSpool(spoolPath(cliConfig.Checkpoint), cliConfig.Resume)
+			if err != nil {
+				return nil, err
+			}
+			sink.spool = spool
+			sink.results = recovered
+		}
+		return sink, nil
+	}
+
+	w, closer, err := openOutput(cliConfig.Output, cliConfig.Resume)
+	if err != nil {
+		return nil, err
+	}
+	return newNDJSONSink(w, closer), nil
+}
+
+// diagnosticOutput はVerboseモードの表示の出力先を返す。
+// NDJSONやドライランの出力を標準出力に逐次書き出す場合は、出力に混ざらないよう標準エラー出力を使う。
+func diagnosticOutput(cliConfig *config.CLIConfig) io.Writer {
+	streaming := cliConfig.Format == config.OutputFormatNDJSON || cliConfig.DryRun
+	if streaming && cliConfig.Output == "" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// openOutput は逐次書き込み用の出力先を開く。pathが空の場合は標準出力を返す。
+// appendModeが有効な場合は既存の内容を残して末尾に追記する。
+func openOutput(path string, appendMode bool) (io.Writer, io.Closer, error) {
+	if path == "" {
+		return os.Stdout, nil, nil
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	// The CLI intentionally writes results to a user-supplied output path.
+	file, err := os.OpenFile(path, flags, 0600) // #nosec G304
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open output file: %w", err)
+	}
+	return file, file, nil
+}
+
+// bufferedSink は全ての結果を保持し、終了時にまとめて出力する
+type bufferedSink struct {
+	cliConfig *config.CLIConfig
+	results   []*config.Result
+	spool     *resultSpool // --checkpoint指定時のみ設定される
+}
+
+func (s *bufferedSink) Emit(result *config.Result) {
+	s.results = append(s.results, result)
+	if s.spool != nil {
+		s.spool.write(result)
+	}
+}
+
+func (s *bufferedSink) Close() error {
+	err := outputResults(s.results, s.cliConfig)
+	if s.spool != nil {
+		// 出力に失敗した場合は退避した結果を残し、--resumeで出力できるようにする
+		if spoolErr := s.spool.Close(err == nil); spoolErr != nil && err == nil {
+			err = spoolErr
+		}
+	}
+	return err
+}
+
+// ndjsonSink は結果を受け取るたびに1行のJSONとして書き出す
+type ndjsonSink struct {
+	writer *bufio.Writer
+	closer io.Closer
+	err    error
+}
+
+func newNDJSONSink(w io.Writer, closer io.Closer) *ndjsonSink {
+	return &ndjsonSink{
+		writer: bufio.NewWriter(w),
+		closer: closer,
+	}
+}
+
+func (s *ndjsonSink) Emit(result *config.Result) {
+	if s.err != nil {
+		return
+	}
+	line, err := json.Marshal(result)
+	if err != nil {
+		s.err = fmt.Errorf("failed to format output: %w", err)
+		return
+	}
+	if _, err := s.writer.Write(append(line, '\n')); err != nil {
+		s.err = fmt.Errorf("failed to write output: %w", err)
+		return
+	}
+	// 途中で異常終了しても出力済みの結果が失われないよう、1行ごとにフラッシュする
+	if err := s.writer.Flush(); err != nil {
+		s.err = fmt.Errorf("failed to flush output: %w", err)
+	}
+}
+
+func (s *ndjsonSink) Close() error {
+	if s.closer != nil {
+		if err := s.closer.Close(); err != nil && s.err == nil {
+			s.err = fmt.Errorf("failed to close output: %w", err)
+		}
+	}
+	return s.err
+}
+
+// formatAsNDJSON は結果を1行1件のJSONに変換する
+func formatAsNDJSON(results []*config.Result) ([]byte, error) {
+	var output []byte
+	for _, result := range results {
+		line, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, line...)
+		output = append(output, '\n')
+	}
+	return output, nil
+}
+
+// appendResults は既存の出力ファイルに結果を追記する（--resume用）。
+// JSONは既存の配列に結合し、CSVとテーブルはヘッダーを繰り返さずに行を追加する。
+func appendResults(path string, results []*config.Result, format config.OutputFormat) error {
+	existing, err := os.ReadFile(path) // #nosec G304
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read existing output: %w", err)
+	}
+	if len(bytes.TrimSpace(existing)) == 0 {
+		output, err := formatResults(results, format)
+		if err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+		return os.WriteFile(path, output, 0600)
+	}
+
+	var output []byte
+	switch format {
+	case config.OutputFormatNDJSON:
+		output, err = formatAsNDJSON(results)
+		if err == nil && len(output) > 0 && existing[len(existing)-1] != '\n' {
+			output = append([]byte("\n"), output...)
+		}
+	case config.OutputFormatCSV:
+		output, err = formatRowsWithoutHeader(formatAsCSV, results, 1)
+	case config.OutputFormatTable:
+		output, err = formatRowsWithoutHeader(formatAsTable, results, 2)
+	default:
+		var merged []json.RawMessage
+		if err := json.Unmarshal(existing, &merged); err != nil {
+			return fmt.Errorf("existing output is not a JSON array: %w", err)
+		}
+		for _, result := range results {
+			line, err := json.Marshal(result)
+			if err != nil {
+				return fmt.Errorf("failed to format output: %w", err)
+			}
+			merged = append(merged, line)
+		}
+		output, err = json.MarshalIndent(merged, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+		return os.WriteFile(path, output, 0600)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+	if len(output) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600) // #nosec G304
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+	if _, err := file.Write(output); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return file.Close()
+}
+
+// formatRowsWithoutHeader は行形式のフォーマットから先頭のヘッダー行を除き、追記用に改行を前置する
+func formatRowsWithoutHeader(format func([]*config.Result) ([]byte, error), results []*config.Result, headerLines int) ([]byte, error) {
+	if len(results) == 0 {
+		return nil, nil
+	}
+	output, err := format(results)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.SplitN(string(output), "\n", headerLines+1)
+	return []byte("\n" + lines[headerLines]), nil
+}
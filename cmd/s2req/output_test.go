@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+func newTestResult(url string, status int) *config.Result {
+	return &config.Result{
+		Request:  config.ProcessedRequest{Method: "GET", URL: url, Headers: map[string]string{}},
+		Response: config.ResponseData{StatusCode: status},
+		Metadata: map[string]interface{}{"file": "test"},
+	}
+}
+
+func TestNDJSONSink_WritesEachResultImmediately(t *testing.T) {
+	var buf bytes.Buffer
+	sink := newNDJSONSink(&buf, nil)
+
+	sink.Emit(newTestResult("http://example.com/a", 200))
+	// 1件目は次の結果を待たずに書き出されている
+	if got := strings.Count(buf.String(), "\n"); got != 1 {
+		t.Fatalf("Expected 1 line after first result, got %d: %q", got, buf.String())
+	}
+
+	sink.Emit(newTestResult("http://example.com/b", 404))
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	expectedStatus := []int{200, 404}
+	for i, line := range lines {
+		var result config.Result
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("Line %d is not valid JSON: %v", i, err)
+		}
+		if result.Response.StatusCode != expectedStatus[i] {
+			t.Errorf("Line %d: expected status %d, got %d", i, expectedStatus[i], result.Response.StatusCode)
+		}
+	}
+}
+
+func TestNewResultSink_NDJSONOutputFile(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "results.ndjson")
+	sink, err := newResultSink(&config.CLIConfig{Format: config.OutputFormatNDJSON, Output: outputPath})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sink.Emit(newTestResult("http://example.com/a", 200))
+
+	// Close前でもファイルに反映されている
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if strings.Count(string(data), "\n") != 1 {
+		t.Errorf("Expected 1 line before close, got %q", string(data))
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestNewResultSink_BufferedFormats(t *testing.T) {
+	sink, err := newResultSink(&config.CLIConfig{Format: config.OutputFormatCSV})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := sink.(*bufferedSink); !ok {
+		t.Errorf("Expected *bufferedSink for csv format, got %T", sink)
+	}
+}
+
+func TestFormatAsNDJSON(t *testing.T) {
+	output, err := formatAsNDJSON([]*config.Result{
+		newTestResult("http://example.com/a", 200),
+		newTestResult("http://example.com/b", 500),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := strings.Count(string(output), "\n"); got != 2 {
+		t.Errorf("Expected 2 lines, got %d", got)
+	}
+}
+
+func TestDiagnosticOutput(t *testing.T) {
+	tests := []struct {
+		name      string
+		cliConfig config.CLIConfig
+		expected  *os.File
+	}{
+		{name: "ndjson to stdout", cliConfig: config.CLIConfig{Format: config.OutputFormatNDJSON}, expected: os.Stderr},
+		{name: "dry run to stdout", cliConfig: config.CLIConfig{Format: config.OutputFormatJSON, DryRun: true}, expected: os.Stderr},
+		{name: "ndjson to file", cliConfig: config.CLIConfig{Format: config.OutputFormatNDJSON, Output: "results.ndjson"}, expected: os.Stdout},
+		{name: "table to stdout", cliConfig: config.CLIConfig{Format: config.OutputFormatTable}, expected: os.Stdout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diagnosticOutput(&tt.cliConfig); got != tt.expected {
+				t.Errorf("Expected %s, got %v", tt.expected.Name(), got)
+			}
+		})
+	}
+}
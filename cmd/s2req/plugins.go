@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/secureta/s2http-request/internal/parser"
+	"github.com/secureta/s2http-request/pkg/functions"
+)
+
+// pluginFlags は--pluginと--plugin-dirで指定されたパス（複数指定可）
+type pluginFlags []string
+
+func (f *pluginFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *pluginFlags) Set(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("plugin path cannot be empty")
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+// loadPlugins は--plugin-dirのディレクトリ内の実行ファイルと--pluginのファイルを読み込み、
+// $関数としてパーサーに登録する。読み込んだプラグインを返す。
+func loadPlugins(p *parser.Parser, plugins, pluginDirs []string) ([]*functions.PluginFunction, error) {
+	var paths []string
+	for _, dir := range pluginDirs {
+		found, err := functions.FindPlugins(dir)
+		if err != nil {
+			return nil, fmt.Errorf("plugin directory %s: %w", dir, err)
+		}
+		paths = append(paths, found...)
+	}
+	paths = append(paths, plugins...)
+
+	loaded := make([]*functions.PluginFunction, 0, len(paths))
+	for _, path := range paths {
+		fn, err := functions.LoadPlugin(context.Background(), path)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.RegisterFunction(fn); err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", path, err)
+		}
+		loaded = append(loaded, fn)
+	}
+	return loaded, nil
+}
+
+// printLoadedPlugins は読み込んだプラグインを表示する
+func printLoadedPlugins(w io.Writer, loaded []*functions.PluginFunction) {
+	for _, plugin := range loaded {
+		fmt.Fprintf(w, "Loaded plugin %s from %s\n", plugin.Signature(), plugin.Path())
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/secureta/s2http-request/internal/parser"
+)
+
+func TestLoadPlugins(t *testing.T) {
+	dir := t.TempDir()
+	writeHookScript(t, dir, "upper", `echo '{"name":"upper","description":"Converts a string to upper case"}'`)
+	writeHookScript(t, dir, "lower", `echo '{"name":"lower"}'`)
+	other := t.TempDir()
+	writeHookScript(t, other, "upper", `echo '{"name":"upper"}'`)
+
+	p := parser.NewParser()
+	loaded, err := loadPlugins(p, nil, []string{dir})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(loaded) != 2 || loaded[0].Name() != "lower" || loaded[1].Signature() != "$upper" {
+		t.Errorf("Unexpected plugins: %+v", loaded)
+	}
+
+	// 既に読み込んだ関数と同じ名前のプラグインはエラーになる
+	_, err = loadPlugins(p, []string{filepath.Join(other, "upper")}, nil)
+	if err == nil || !strings.Contains(err.Error(), "function $upper is already defined by plugin "+filepath.Join(dir, "upper")) {
+		t.Errorf("Expected a conflict error, got %v", err)
+	}
+}
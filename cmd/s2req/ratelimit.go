@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// parseRate は "20/s"、"100/m"、"1/500ms" 形式のレート指定を1秒あたりのトークン数に変換する
+func parseRate(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("rate cannot be empty")
+	}
+
+	countPart, unitPart, hasUnit := strings.Cut(value, "/")
+	count, err := strconv.ParseFloat(strings.TrimSpace(countPart), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate count %q: %w", countPart, err)
+	}
+	if count <= 0 {
+		return 0, fmt.Errorf("rate count must be greater than 0, got %v", count)
+	}
+
+	per := time.Second
+	if hasUnit {
+		unitPart = strings.TrimSpace(unitPart)
+		switch unitPart {
+		case "s":
+			per = time.Second
+		case "m":
+			per = time.Minute
+		case "h":
+			per = time.Hour
+		default:
+			per, err = time.ParseDuration(unitPart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid rate unit %q, expected s, m, h or a duration", unitPart)
+			}
+			if per <= 0 {
+				return 0, fmt.Errorf("rate unit must be positive, got %s", unitPart)
+			}
+		}
+	}
+
+	return count / per.Seconds(), nil
+}
+
+// tokenBucket はトークンバケット方式のレートリミッター
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // 1秒あたりに補充されるトークン数
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// reserve はトークンを1つ予約し、送信まで待つべき時間を返す
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+
+	// トークンが不足している場合は負の残高として予約し、先着順に待機させる
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel は使われなかった予約のトークンを返却する（上限はバースト数）
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// Wait はトークンが利用可能になるまで待機する。
+// 待機中にctxが取り消された場合は、後続の送信を遅らせないよう予約を返却する。
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	delay := b.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rateLimiter は全体またはホスト単位で送信レートを制御する
+type rateLimiter struct {
+	rate    float64
+	burst   int
+	perHost bool
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// newRateLimiter は新しいrateLimiterを作成
+func newRateLimiter(rate string, burst int, perHost bool) (*rateLimiter, error) {
+	perSecond, err := parseRate(rate)
+	if err != nil {
+		return nil, err
+	}
+	if burst < 0 {
+		return nil, fmt.Errorf("burst must not be negative, got %d", burst)
+	}
+	return &rateLimiter{
+		rate:    perSecond,
+		burst:   burst,
+		perHost: perHost,
+		buckets: make(map[string]*tokenBucket),
+	}, nil
+}
+
+// newRateLimiterFromConfig はリクエスト定義のmeta.rateからrateLimiterを作成
+func newRateLimiterFromConfig(rateConfig *config.RateConfig) (*rateLimiter, error) {
+	return newRateLimiter(rateConfig.Limit, rateConfig.Burst, rateConfig.PerHost)
+}
+
+// Wait は指定URLへの送信が許可されるまで待機する
+func (l *rateLimiter) Wait(ctx context.Context, rawURL string) error {
+	key := ""
+	if l.perHost {
+		key = rateLimitKey(rawURL)
+	}
+
+	l.mu.Lock()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = newTokenBucket(l.rate, l.burst)
+		l.buckets[key] = bucket
+	}
+	l.mu.Unlock()
+
+	return bucket.Wait(ctx)
+}
+
+// rateLimitKey はURLからレート制御のキーとなるホストを取り出す
+func rateLimitKey(rawURL string) string {
+	if parsedURL, err := url.Parse(rawURL); err == nil && parsedURL.Host != "" {
+		return strings.ToLower(parsedURL.Host)
+	}
+
+	// raw request targetなどでURLとして解析できない場合はスキーム以降のホスト部分を使用
+	rest := rawURL
+	if schemeEnd := strings.Index(rest, "://"); schemeEnd >= 0 {
+		rest = rest[schemeEnd+3:]
+	}
+	if hostEnd := strings.IndexAny(rest, "/?#"); hostEnd >= 0 {
+		rest = rest[:hostEnd]
+	}
+	return strings.ToLower(rest)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		expected  float64
+		wantError bool
+	}{
+		{name: "per second", value: "20/s", expected: 20},
+		{name: "per minute", value: "120/m", expected: 2},
+		{name: "per hour", value: "3600/h", expected: 1},
+		{name: "per duration", value: "1/500ms", expected: 2},
+		{name: "count only", value: "5", expected: 5},
+		{name: "fractional", value: "0.5/s", expected: 0.5},
+		{name: "empty", value: "", wantError: true},
+		{name: "zero count", value: "0/s", wantError: true},
+		{name: "negative count", value: "-1/s", wantError: true},
+		{name: "invalid count", value: "abc/s", wantError: true},
+		{name: "invalid unit", value: "10/day", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRate(tt.value)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if math.Abs(got-tt.expected) > 1e-9 {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTokenBucket_Reserve(t *testing.T) {
+	now := time.Unix(0, 0)
+	bucket := newTokenBucket(10, 2)
+	bucket.now = func() time.Time { return now }
+
+	// バースト分は待機なし
+	for i := 0; i < 2; i++ {
+		if delay := bucket.reserve(); delay != 0 {
+			t.Fatalf("Reservation %d: expected no delay, got %v", i, delay)
+		}
+	}
+
+	// バーストを使い切った後は1トークンあたり100ms待機
+	if delay := bucket.reserve(); delay != 100*time.Millisecond {
+		t.Errorf("Expected 100ms delay, got %v", delay)
+	}
+	if delay := bucket.reserve(); delay != 200*time.Millisecond {
+		t.Errorf("Expected 200ms delay, got %v", delay)
+	}
+
+	// 時間経過でトークンが補充される（上限はバースト数）
+	now = now.Add(10 * time.Second)
+	if delay := bucket.reserve(); delay != 0 {
+		t.Errorf("Expected no delay after refill, got %v", delay)
+	}
+	if bucket.tokens > bucket.burst {
+		t.Errorf("Tokens %v exceed burst %v", bucket.tokens, bucket.burst)
+	}
+}
+
+func TestTokenBucket_WaitRefundsCancelledReservation(t *testing.T) {
+	now := time.Unix(0, 0)
+	bucket := newTokenBucket(1, 1)
+	bucket.now = func() time.Time { return now }
+
+	if delay := bucket.reserve(); delay != 0 {
+		t.Fatalf("Expected no delay for the burst, got %v", delay)
+	}
+
+	// 待機中に取り消された予約は返却され、後続の待ち時間は延びない
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := bucket.Wait(ctx); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if delay := bucket.reserve(); delay != time.Second {
+		t.Errorf("Expected 1s delay after the cancelled wait, got %v", delay)
+	}
+
+	// 返却してもバースト数を超えない
+	now = now.Add(time.Hour)
+	bucket.reserve()
+	bucket.cancel()
+	bucket.cancel()
+	if bucket.tokens > bucket.burst {
+		t.Errorf("Expected tokens to be capped at burst %v, got %v", bucket.burst, bucket.tokens)
+	}
+}
+
+func TestRateLimiter_PerHost(t *testing.T) {
+	limiter, err := newRateLimiter("1/h", 1, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// ホストごとにバケットが分かれるため、異なるホストは待機しない
+	if err := limiter.Wait(ctx, "http://a.example.test/one"); err != nil {
+		t.Fatalf("Unexpected error for first host: %v", err)
+	}
+	if err := limiter.Wait(ctx, "https://b.example.test/two"); err != nil {
+		t.Fatalf("Unexpected error for second host: %v", err)
+	}
+
+	// 同じホストへの2回目はレート制限で待機し、コンテキストの期限切れになる
+	if err := limiter.Wait(ctx, "http://A.example.test/three"); err == nil {
+		t.Errorf("Expected rate limit wait to exceed context deadline")
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "http://Example.com/path", expected: "example.com"},
+		{url: "https://example.com:8443/?q=1", expected: "example.com:8443"},
+		{url: "http://example.com/%%32%65", expected: "example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := rateLimitKey(tt.url); got != tt.expected {
+			t.Errorf("rateLimitKey(%q) = %q, expected %q", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestDispatcher_ForRequestConfig(t *testing.T) {
+	d, err := newDispatcher(nil, &config.CLIConfig{Rate: "100/s", Burst: 5}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	same, err := d.forRequestConfig(&config.RequestConfig{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if same != d {
+		t.Errorf("Expected the same dispatcher without meta.rate")
+	}
+
+	override, err := d.forRequestConfig(&config.RequestConfig{
+		Meta: &config.MetaConfig{Rate: &config.RateConfig{Limit: "2/s", Burst: 1}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if override.limiter != d.limiter || override.docLimiter == nil || override.docLimiter.rate != 2 {
+		t.Errorf("Expected --rate limiter kept and meta.rate limiter with rate 2, got %+v and %+v", override.limiter, override.docLimiter)
+	}
+
+	// 同じmeta.rateのドキュメントはバケットを共有する
+	again, err := d.forRequestConfig(&config.RequestConfig{
+		Meta: &config.MetaConfig{Rate: &config.RateConfig{Limit: "2/s", Burst: 1}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if again.docLimiter != override.docLimiter {
+		t.Errorf("Expected documents with the same meta.rate to share a limiter")
+	}
+
+	if _, err := d.forRequestConfig(&config.RequestConfig{
+		Meta: &config.MetaConfig{Rate: &config.RateConfig{Limit: "fast"}},
+	}); err == nil {
+		t.Errorf("Expected error for invalid meta.rate")
+	}
+}
+
+func TestDispatcher_MetaRateDoesNotLoosenCLIRate(t *testing.T) {
+	d, err := newDispatcher(nil, &config.CLIConfig{Rate: "1/h", Burst: 1, Timeout: time.Second}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	docDispatcher, err := d.forRequestConfig(&config.RequestConfig{
+		Meta: &config.MetaConfig{Rate: &config.RateConfig{Limit: "1000/s", Burst: 100}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	docDispatcher.ctx = ctx
+
+	// --rateのバーストを使い切った後は、meta.rateが緩くても--rateに従って待機する
+	if err := d.limiter.Wait(ctx, "http://example.com/"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = docDispatcher.send(&config.ProcessedRequest{Method: "GET", URL: "http://example.com/"})
+	if err == nil || !strings.Contains(err.Error(), "rate limiter") {
+		t.Errorf("Expected the --rate limiter to hold the request, got %v", err)
+	}
+}
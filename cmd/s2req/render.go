@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// renderSink はドライラン時の出力先。リクエストを送信せず、ワイヤー表現のみを書き出す
+type renderSink struct {
+	writer *bufio.Writer
+	closer io.Closer
+	count  int
+	err    error
+}
+
+// newRenderSink は新しいrenderSinkを作成。pathが空の場合は標準出力に書き出す
+func newRenderSink(path string) (*renderSink, error) {
+	w, closer, err := openOutput(path, false)
+	if err != nil {
+		return nil, err
+	}
+	return &renderSink{
+		writer: bufio.NewWriter(w),
+		closer: closer,
+	}, nil
+}
+
+// Emit はドライランでは結果が発生しないため何もしない
+func (s *renderSink) Emit(*config.Result) {}
+
+// writeRequest はリクエスト1件分のワイヤー表現を区切り行付きで書き出す
+func (s *renderSink) writeRequest(source string, index int, wire []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.count > 0 {
+		s.write([]byte("\n"))
+	}
+	s.count++
+	s.write([]byte(fmt.Sprintf("### %s #%d\n", source, index+1)))
+	s.write(wire)
+	if len(wire) > 0 && wire[len(wire)-1] != '\n' {
+		s.write([]byte("\n"))
+	}
+	if s.err == nil {
+		if err := s.writer.Flush(); err != nil {
+			s.err = fmt.Errorf("failed to flush output: %w", err)
+		}
+	}
+	return s.err
+}
+
+func (s *renderSink) write(p []byte) {
+	if s.err != nil {
+		return
+	}
+	if _, err := s.writer.Write(p); err != nil {
+		s.err = fmt.Errorf("failed to write output: %w", err)
+	}
+}
+
+func (s *renderSink) Close() error {
+	if err := s.writer.Flush(); err != nil && s.err == nil {
+		s.err = fmt.Errorf("failed to flush output: %w", err)
+	}
+	if s.closer != nil {
+		if err := s.closer.Close(); err != nil && s.err == nil {
+			s.err = fmt.Errorf("failed to close output: %w", err)
+		}
+	}
+	return s.err
+}
+
+// render はリクエストを送信せず、送信時と同じバイト列をrendererに書き出す
+func (d *dispatcher) render(source string, requests requestSource) []error {
+	var errs []error
+	for requests.Next() {
+		index, request := requests.Index(), requests.Request()
+		d.applyUserAgent(request)
+
+		rendered := request
+		if !d.cliConfig.ShowSecrets {
+			// 認証情報は--show-secretsを指定した場合のみ出力する
+			masked := maskSecrets(*request)
+			rendered = &masked
+		}
+		wire, err := d.client.RenderRequest(rendered)
+		if err != nil {
+			errs = append(errs, &requestError{Index: index, Request: request, Err: err})
+			continue
+		}
+		if err := d.renderer.writeRequest(source, index, wire); err != nil {
+			errs = append(errs, err)
+			break
+		}
+	}
+	return errs
+}
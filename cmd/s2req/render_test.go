@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+	httpClient "github.com/secureta/s2http-request/internal/http"
+)
+
+func TestDispatcher_DryRunRendersWithoutSending(t *testing.T) {
+	client, err := httpClient.NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "rendered.http")
+	renderer, err := newRenderSink(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to create render sink: %v", err)
+	}
+
+	d, err := newDispatcher(client, &config.CLIConfig{Timeout: 5 * time.Second, DryRun: true}, "s2req/test")
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+	d.renderer = renderer
+
+	// 127.0.0.1:1 には何も待ち受けていないため、送信すればエラーになる
+	requests := []*config.ProcessedRequest{
+		{Method: "GET", URL: "http://127.0.0.1:1/a", Headers: map[string]string{}},
+		{Method: "GET", URL: "http://127.0.0.1:1/b", RawRequestTarget: "/b?x=%%", Headers: map[string]string{}},
+		{Method: "GET", URL: "http://127.0.0.1:1/c", Raw: "GET /c HTTP/1.1\nHost: x\n\n", Headers: map[string]string{}},
+	}
+
+	emitted := 0
+	errs := d.dispatch("test.yaml", newSliceSource(requests), func(int, *config.Result) { emitted++ })
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if emitted != 0 {
+		t.Errorf("Expected no results in dry-run, got %d", emitted)
+	}
+	if err := renderer.Close(); err != nil {
+		t.Fatalf("Failed to close render sink: %v", err)
+	}
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	output := string(data)
+
+	for _, want := range []string{
+		"### test.yaml #1\nGET /a HTTP/1.1\r\n",
+		"### test.yaml #2\nGET /b?x=%% HTTP/1.1\r\n",
+		"User-Agent: s2req/test\r\n",
+		// rawのリクエストはそのまま出力する
+		"### test.yaml #3\nGET /c HTTP/1.1\nHost: x\n\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got %q", want, output)
+		}
+	}
+}
+
+func TestDispatcher_DryRunMasksSecrets(t *testing.T) {
+	client, err := httpClient.NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	for _, showSecrets := range []bool{false, true} {
+		outputPath := filepath.Join(t.TempDir(), "rendered.http")
+		renderer, err := newRenderSink(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to create render sink: %v", err)
+		}
+		d, err := newDispatcher(client, &config.CLIConfig{Timeout: 5 * time.Second, DryRun: true, ShowSecrets: showSecrets}, "s2req/test")
+		if err != nil {
+			t.Fatalf("Failed to create dispatcher: %v", err)
+		}
+		d.renderer = renderer
+
+		requests := []*config.ProcessedRequest{
+			{Method: "GET", URL: "http://127.0.0.1:1/a", Headers: map[string]string{"Authorization": "Bearer t0ken"}, Secrets: []string{"t0ken"}},
+			{Method: "GET", URL: "http://127.0.0.1:1/b?api_key=k3y", Headers: map[string]string{"X-Api-Key": "k3y"}, Secrets: []string{"k3y"}},
+		}
+		if errs := d.dispatch("test.yaml", newSliceSource(requests), func(int, *config.Result) {}); len(errs) != 0 {
+			t.Fatalf("Unexpected errors: %v", errs)
+		}
+		if err := renderer.Close(); err != nil {
+			t.Fatalf("Failed to close render sink: %v", err)
+		}
+		data, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		output := string(data)
+
+		for _, secret := range []string{"t0ken", "k3y"} {
+			if strings.Contains(output, secret) != showSecrets {
+				t.Errorf("show-secrets=%v: unexpected presence of %q in %q", showSecrets, secret, output)
+			}
+		}
+		if !showSecrets {
+			for _, want := range []string{"Authorization: Bearer ****\r\n", "GET /b?api_key=**** HTTP/1.1\r\n", "X-Api-Key: ****\r\n"} {
+				if !strings.Contains(output, want) {
+					t.Errorf("Expected output to contain %q, got %q", want, output)
+				}
+			}
+		}
+	}
+}
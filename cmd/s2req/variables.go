@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// varFileFlags is a custom flag type for handling multiple --var-file flags
+type varFileFlags []string
+
+func (v *varFileFlags) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *varFileFlags) Set(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("variable file path cannot be empty")
+	}
+	*v = append(*v, value)
+	return nil
+}
+
+// loadVarFile loads variables from a YAML, JSON or dotenv file.
+// The format is chosen by extension; anything other than .json/.yaml/.yml is read as dotenv.
+func loadVarFile(path string) (map[string]interface{}, error) {
+	// The CLI intentionally accepts user-supplied variable file paths.
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("failed to read variable file: %w", err)
+	}
+
+	variables := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.Unmarshal(data, &variables); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON variable file %s: %w", path, err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &variables); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML variable file %s: %w", path, err)
+		}
+	default:
+		variables, err = parseDotenv(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dotenv variable file %s: %w", path, err)
+		}
+	}
+
+	return variables, nil
+}
+
+// parseDotenv parses KEY=value lines. Values are kept as strings.
+func parseDotenv(content string) (map[string]interface{}, error) {
+	variables := make(map[string]interface{})
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid format, expected 'KEY=value'", lineNumber)
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("line %d: variable key cannot be empty", lineNumber)
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted value: %w", lineNumber, err)
+			}
+			value = unquoted
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		default:
+			// 引用符なしの値ではインラインコメントを除去
+			if idx := strings.Index(value, " #"); idx >= 0 {
+				value = strings.TrimSpace(value[:idx])
+			}
+		}
+
+		variables[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
+
+// mergeVariables merges variable files in order (later files win) and then applies --var overrides on top.
+func mergeVariables(varFiles []string, overrides map[string]interface{}) (map[string]interface{}, error) {
+	merged := make(map[string]interface{})
+	for _, path := range varFiles {
+		fileVars, err := loadVarFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range fileVars {
+			merged[key] = value
+		}
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged, nil
+}
+
+// chainVariables returns the variables for the next document: the CLI variables with values
+// extracted from earlier responses on top, so later documents see the latest server-issued value.
+func chainVariables(variables map[string]interface{}, extracted map[string]interface{}) map[string]interface{} {
+	if len(extracted) == 0 {
+		return variables
+	}
+	chained := make(map[string]interface{}, len(variables)+len(extracted))
+	for key, value := range variables {
+		chained[key] = value
+	}
+	for key, value := range extracted {
+		chained[key] = value
+	}
+	return chained
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeVarFile(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadVarFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name      string
+		fileName  string
+		content   string
+		expected  map[string]interface{}
+		wantError bool
+	}{
+		{
+			name:     "YAML file",
+			fileName: "vars.yaml",
+			content:  "user_id: 123\nname: alice\nids: [1, 2]\n",
+			expected: map[string]interface{}{"user_id": 123, "name": "alice", "ids": []interface{}{1, 2}},
+		},
+		{
+			name:     "JSON file",
+			fileName: "vars.json",
+			content:  `{"user_id": 123, "config": {"enabled": true}}`,
+			expected: map[string]interface{}{"user_id": float64(123), "config": map[string]interface{}{"enabled": true}},
+		},
+		{
+			name:     "dotenv file",
+			fileName: "staging.env",
+			content:  "# comment\nexport HOST=staging.example.com\nTOKEN=\"a b\\nc\"\nRAW='x # y'\nPLAIN=value # trailing\n\n",
+			expected: map[string]interface{}{"HOST": "staging.example.com", "TOKEN": "a b\nc", "RAW": "x # y", "PLAIN": "value"},
+		},
+		{
+			name:      "invalid dotenv line",
+			fileName:  "broken.env",
+			content:   "NOT_A_PAIR\n",
+			wantError: true,
+		},
+		{
+			name:      "invalid JSON",
+			fileName:  "broken.json",
+			content:   `{"key":`,
+			wantError: true,
+		},
+		{
+			name:      "YAML list is not a variable map",
+			fileName:  "list.yml",
+			content:   "- a\n- b\n",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeVarFile(t, dir, tt.fileName, tt.content)
+			got, err := loadVarFile(path)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !compareValues(tt.expected, got) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := loadVarFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Errorf("Expected error for missing file")
+	}
+}
+
+func TestMergeVariables(t *testing.T) {
+	dir := t.TempDir()
+	base := writeVarFile(t, dir, "base.yaml", "env: base\nuser_id: 1\nregion: us\n")
+	staging := writeVarFile(t, dir, "staging.env", "env=staging\nuser_id=2\n")
+
+	overrides := make(varFlags)
+	if err := overrides.Set("user_id=3"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got, err := mergeVariables([]string{base, staging}, overrides)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"env":     "staging", // later file wins
+		"region":  "us",      // kept from earlier file
+		"user_id": 3,         // --var wins over files
+	}
+	if !compareValues(expected, got) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestVarFileFlags_Set(t *testing.T) {
+	var files varFileFlags
+	if err := files.Set("a.yaml"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := files.Set("b.env"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := files.Set(" "); err == nil {
+		t.Errorf("Expected error for empty path")
+	}
+	if len(files) != 2 || files[0] != "a.yaml" || files[1] != "b.env" {
+		t.Errorf("Expected [a.yaml b.env], got %v", files)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/secureta/s2http-request/internal/config"
+	"github.com/secureta/s2http-request/internal/response"
+)
+
+// maxSummaryDictValues はファイルごとにサマリーに表示するdictの値の最大件数
+const maxSummaryDictValues = 50
+
+// maxSummaryValueLength はサマリーに表示するdictの値の最大文字数
+const maxSummaryValueLength = 60
+
+// classifierFor はドキュメントに適用する判定ルールのClassifierを返す。判定が無効な場合はnilを返す。
+// ルールはmeta.verdict、--verdict-rules、組み込みルールの順に適用される。
+func (d *dispatcher) classifierFor(requestConfig *config.RequestConfig) (*response.Classifier, error) {
+	var meta *config.VerdictConfig
+	if requestConfig.Meta != nil {
+		meta = requestConfig.Meta.Verdict
+	}
+	if meta == nil && !d.cliConfig.Verdict {
+		return nil, nil
+	}
+
+	var rules []config.VerdictRule
+	var builtin *bool
+	if meta != nil {
+		rules = append(rules, meta.Rules...)
+		builtin = meta.Builtin
+	}
+	if d.verdictRules != nil {
+		rules = append(rules, d.verdictRules.Rules...)
+		if builtin == nil {
+			builtin = d.verdictRules.Builtin
+		}
+	}
+	if builtin == nil || *builtin {
+		rules = append(rules, response.BuiltinVerdictRules()...)
+	}
+	return response.NewClassifier(rules)
+}
+
+// verdictCounts は判定ごとの件数
+type verdictCounts struct {
+	blocked int
+	passed  int
+	errors  int // 5xxと送信エラー
+}
+
+func (c *verdictCounts) add(verdict config.Verdict) {
+	switch verdict {
+	case config.VerdictBlocked:
+		c.blocked++
+	case config.VerdictPassed:
+		c.passed++
+	default:
+		c.errors++
+	}
+}
+
+func (c *verdictCounts) String() string {
+	return fmt.Sprintf("%d blocked, %d passed, %d errors", c.blocked, c.passed, c.errors)
+}
+
+// verdictValue はファイル内のdictの1つの値
+type verdictValue struct {
+	key   string
+	value string
+}
+
+// verdictFile はファイルごとの判定の集計
+type verdictFile struct {
+	counts verdictCounts
+	values []verdictValue // 最初に出現した順
+	byKey  map[verdictValue]*verdictCounts
+}
+
+// verdictSummary はファイルごとおよびdictの値ごとに判定を集計する
+type verdictSummary struct {
+	files  []string // 最初に出現した順
+	byFile map[string]*verdictFile
+}
+
+// record は判定済みの結果を1件集計する
+func (s *verdictSummary) record(source string, result *config.Result) {
+	s.add(source, &result.Request, result.Verdict)
+}
+
+// recordError は送信に失敗したリクエストをerrorとして集計する
+func (s *verdictSummary) recordError(source string, err error) {
+	var request *config.ProcessedRequest
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		request = reqErr.Request
+	}
+	s.add(source, request, config.VerdictError)
+}
+
+func (s *verdictSummary) add(source string, request *config.ProcessedRequest, verdict config.Verdict) {
+	if s.byFile == nil {
+		s.byFile = make(map[string]*verdictFile)
+	}
+	file, ok := s.byFile[source]
+	if !ok {
+		file = &verdictFile{byKey: make(map[verdictValue]*verdictCounts)}
+		s.byFile[source] = file
+		s.files = append(s.files, source)
+	}
+	file.counts.add(verdict)
+
+	if request == nil {
+		return
+	}
+	keys := make([]string, 0, len(request.DictValues))
+	for key := range request.DictValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := verdictValue{key: key, value: fmt.Sprintf("%v", request.DictValues[key])}
+		counts, ok := file.byKey[value]
+		if !ok {
+			counts = &verdictCounts{}
+			file.byKey[value] = counts
+			file.values = append(file.values, value)
+		}
+		counts.add(verdict)
+	}
+}
+
+// print はサマリーを書き出す。判定した結果がない場合は何も書き出さない。
+// dictの値はブロックされなかった件数の多い順に表示する。
+func (s *verdictSummary) print(w io.Writer) {
+	if len(s.files) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Verdicts:")
+	for _, source := range s.files {
+		file := s.byFile[source]
+		fmt.Fprintf(w, "  %s: %s\n", source, &file.counts)
+
+		values := make([]verdictValue, len(file.values))
+		copy(values, file.values)
+		sort.SliceStable(values, func(i, j int) bool {
+			return file.byKey[values[i]].passed > file.byKey[values[j]].passed
+		})
+		for i, value := range values {
+			if i == maxSummaryDictValues {
+				fmt.Fprintf(w, "    ... and %d more value(s)\n", len(values)-i)
+				break
+			}
+			fmt.Fprintf(w, "    %s=%s: %s\n", value.key, truncateValue(value.value), file.byKey[value])
+		}
+	}
+}
+
+// truncateValue は長い値をサマリー用に切り詰める
+func truncateValue(value string) string {
+	runes := []rune(value)
+	if len(runes) <= maxSummaryValueLength {
+		return value
+	}
+	return string(runes[:maxSummaryValueLength]) + "..."
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+	httpClient "github.com/secureta/s2http-request/internal/http"
+	"github.com/secureta/s2http-request/internal/parser"
+)
+
+func TestProcessRequestConfigs_ClassifiesVerdicts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		switch {
+		case strings.Contains(q, "<script>"):
+			w.Header().Set("Server", "cloudflare")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("Sorry, you have been blocked"))
+		case strings.Contains(q, "sleep"):
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte("OK"))
+		}
+	}))
+	defer server.Close()
+
+	client, err := httpClient.NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	content := `method: GET
+path: /
+query:
+  q: {$concat: [{$dict: payload}, {$dict: suffix}]}
+dict:
+  payload: ["<script>", "sleep(5)", "hello"]
+  suffix: ["", "--"]
+`
+	p := parser.NewParser()
+	configs, err := p.ParseMultiple([]byte(content), ".yaml", "waf.yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	d, err := newDispatcher(client, &config.CLIConfig{Host: server.URL, Timeout: 5 * time.Second, Concurrency: 2, Verdict: true}, "")
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	var results []*config.Result
+	if err := processRequestConfigs(p, d, configs, "waf.yaml", nil, func(result *config.Result) {
+		results = append(results, result)
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(results) != 6 {
+		t.Fatalf("Expected 6 results, got %d", len(results))
+	}
+	if results[0].Verdict != config.VerdictBlocked || results[0].VerdictRule != "cloudflare" {
+		t.Errorf("Expected cloudflare block, got %s (%s)", results[0].Verdict, results[0].VerdictRule)
+	}
+	if results[0].Metadata["dict"] == nil {
+		t.Errorf("Expected dict values in metadata")
+	}
+
+	var out bytes.Buffer
+	d.verdicts.print(&out)
+	for _, want := range []string{
+		"waf.yaml: 2 blocked, 2 passed, 2 errors",
+		"payload=hello: 0 blocked, 2 passed, 0 errors",
+		"payload=<script>: 2 blocked, 0 passed, 0 errors",
+		"suffix=--: 1 blocked, 1 passed, 1 errors",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", want, out.String())
+		}
+	}
+	// ブロックされなかった値を先に表示する
+	if strings.Index(out.String(), "payload=hello") > strings.Index(out.String(), "payload=<script>") {
+		t.Errorf("Expected passed values first, got:\n%s", out.String())
+	}
+}
+
+func TestClassifierFor(t *testing.T) {
+	falseValue := false
+	metaRules := &config.VerdictConfig{Rules: []config.VerdictRule{{Name: "meta", Status: 418}}}
+	fileRules := &config.VerdictConfig{Builtin: &falseValue, Rules: []config.VerdictRule{{Name: "file", Status: 418}}}
+	teapot := &config.ResponseData{StatusCode: 418}
+	forbidden := &config.ResponseData{StatusCode: 403}
+
+	tests := []struct {
+		name          string
+		cliVerdict    bool
+		verdictRules  *config.VerdictConfig
+		meta          *config.VerdictConfig
+		disabled      bool
+		teapotRule    string
+		forbiddenRule string
+	}{
+		{name: "disabled", disabled: true},
+		{name: "built-in only", cliVerdict: true, forbiddenRule: "generic-block-status"},
+		{name: "meta enables verdicts", meta: metaRules, teapotRule: "meta", forbiddenRule: "generic-block-status"},
+		{name: "meta rules before file rules", cliVerdict: true, verdictRules: fileRules, meta: metaRules, teapotRule: "meta"},
+		{name: "file disables built-ins", cliVerdict: true, verdictRules: fileRules, teapotRule: "file"},
+		{
+			name:         "meta builtin overrides file",
+			cliVerdict:   true,
+			verdictRules: fileRules,
+			meta:         &config.VerdictConfig{Builtin: new(bool)},
+			teapotRule:   "file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := newDispatcher(nil, &config.CLIConfig{Verdict: tt.cliVerdict}, "")
+			if err != nil {
+				t.Fatalf("Failed to create dispatcher: %v", err)
+			}
+			d.verdictRules = tt.verdictRules
+			requestConfig := &config.RequestConfig{}
+			if tt.meta != nil {
+				requestConfig.Meta = &config.MetaConfig{Verdict: tt.meta}
+			}
+
+			classifier, err := d.classifierFor(requestConfig)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.disabled {
+				if classifier != nil {
+					t.Errorf("Expected no classifier")
+				}
+				return
+			}
+			if _, rule := classifier.Classify(teapot); rule != tt.teapotRule {
+				t.Errorf("Expected 418 to match %q, got %q", tt.teapotRule, rule)
+			}
+			if _, rule := classifier.Classify(forbidden); rule != tt.forbiddenRule {
+				t.Errorf("Expected 403 to match %q, got %q", tt.forbiddenRule, rule)
+			}
+		})
+	}
+}
+
+func TestVerdictSummary_LimitsDictValues(t *testing.T) {
+	s := &verdictSummary{}
+	for i := 0; i < maxSummaryDictValues+3; i++ {
+		request := &config.ProcessedRequest{DictValues: map[string]interface{}{"payload": fmt.Sprintf("%d-%s", i, strings.Repeat("x", 100))}}
+		s.record("a.yaml", &config.Result{Request: *request, Verdict: config.VerdictBlocked})
+	}
+	s.recordError("a.yaml", fmt.Errorf("dial failed"))
+
+	var out bytes.Buffer
+	s.print(&out)
+	if !strings.Contains(out.String(), "a.yaml: 53 blocked, 0 passed, 1 errors") {
+		t.Errorf("Unexpected totals:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "... and 3 more value(s)") {
+		t.Errorf("Expected the value list to be capped:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "payload=0-"+strings.Repeat("x", maxSummaryValueLength-2)+"...: ") {
+		t.Errorf("Expected long values to be truncated:\n%s", out.String())
+	}
+}
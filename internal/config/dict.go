@@ -0,0 +1,90 @@
+package config
+
+import (
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalJSON はdictの値を配列とファイル参照に振り分けながらRequestConfigを解析する
+func (r *RequestConfig) UnmarshalJSON(data []byte) error {
+	type plain RequestConfig
+	aux := struct {
+		*plain
+		Dict map[string]interface{} `json:"dict,omitempty"`
+	}{plain: (*plain)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	var ok bool
+	if r.Dict, r.DictSources, ok = splitDict(aux.Dict); !ok {
+		// 配列でもファイル参照でもない値は、従来どおりの型エラーとして報告する
+		return json.Unmarshal(data, (*plain)(r))
+	}
+	return nil
+}
+
+// UnmarshalYAML はdictの値を配列とファイル参照に振り分けながらRequestConfigを解析する
+func (r *RequestConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain RequestConfig
+	if value.Kind != yaml.MappingNode {
+		return value.Decode((*plain)(r))
+	}
+
+	// dict以外のキーは通常どおり構造体に解析する
+	rest := *value
+	rest.Content = nil
+	var dictNode *yaml.Node
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		if value.Content[i].Value == "dict" {
+			dictNode = value.Content[i+1]
+			continue
+		}
+		rest.Content = append(rest.Content, value.Content[i], value.Content[i+1])
+	}
+	if err := rest.Decode((*plain)(r)); err != nil {
+		return err
+	}
+
+	if dictNode != nil {
+		var dict map[string]interface{}
+		if err := dictNode.Decode(&dict); err != nil {
+			return err
+		}
+		var ok bool
+		if r.Dict, r.DictSources, ok = splitDict(dict); !ok {
+			// 配列でもファイル参照でもない値は、従来どおりの型エラーとして報告する
+			return dictNode.Decode(&r.Dict)
+		}
+	}
+	return nil
+}
+
+// splitDict は配列（またはnull）の値とファイル参照（マップ）の値を分ける。
+// nullはDictに残し、検証で配列でないことを報告できるようにする。
+// それ以外の型が含まれる場合はfalseを返す。
+func splitDict(dict map[string]interface{}) (map[string][]interface{}, map[string]interface{}, bool) {
+	if dict == nil {
+		return nil, nil, true
+	}
+
+	values := make(map[string][]interface{}, len(dict))
+	var sources map[string]interface{}
+	for key, value := range dict {
+		switch v := value.(type) {
+		case []interface{}:
+			values[key] = v
+		case nil:
+			values[key] = nil
+		case map[string]interface{}:
+			if sources == nil {
+				sources = make(map[string]interface{})
+			}
+			sources[key] = v
+		default:
+			return nil, nil, false
+		}
+	}
+	return values, sources, true
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StringList は1つの文字列または文字列の配列として記述できるリスト
+type StringList []string
+
+// UnmarshalJSON は文字列と文字列の配列のどちらも受け付ける
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = StringList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// UnmarshalYAML は文字列と文字列の配列のどちらも受け付ける
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var single string
+		if err := value.Decode(&single); err != nil {
+			return err
+		}
+		*l = StringList{single}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
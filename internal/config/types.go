@@ -18,9 +18,169 @@ type RequestIDConfig struct {
 	Key      string            `json:"key,omitempty" yaml:"key,omitempty"` // query/headerの場合のキー名
 }
 
+// RateConfig はリクエスト送信レートの設定を表す構造体
+type RateConfig struct {
+	Limit   string `json:"limit" yaml:"limit"`                           // "20/s", "100/m" 形式
+	Burst   int    `json:"burst,omitempty" yaml:"burst,omitempty"`       // 連続して送信できる最大数
+	PerHost bool   `json:"per-host,omitempty" yaml:"per-host,omitempty"` // ホスト単位で制御する
+}
+
+// DictMode はdict配列の組み合わせ方を表す列挙型
+type DictMode string
+
+const (
+	DictModeProduct   DictMode = "product"   // 全配列の直積（デフォルト）
+	DictModeZip       DictMode = "zip"       // 全配列を同じインデックスで並行して進める
+	DictModePitchfork DictMode = "pitchfork" // zipの別名
+	DictModeSniper    DictMode = "sniper"    // 1つのキーだけを変化させ、他はデフォルト値に固定する
+)
+
+// Verdict はWAFテストでのレスポンスの判定を表す列挙型
+type Verdict string
+
+const (
+	VerdictBlocked Verdict = "blocked" // WAFなどによりブロックされた
+	VerdictPassed  Verdict = "passed"  // ブロックされずにアプリケーションまで届いた
+	VerdictError   Verdict = "error"   // ブロックのルールに該当しないサーバーエラー
+)
+
+// VerdictRule はレスポンスを判定するルールを表す構造体。指定した条件を全て満たす場合に該当する。
+type VerdictRule struct {
+	Name         string            `json:"name" yaml:"name"`
+	Verdict      Verdict           `json:"verdict,omitempty" yaml:"verdict,omitempty"`             // 該当した場合の判定（デフォルトはblocked）
+	Status       interface{}       `json:"status,omitempty" yaml:"status,omitempty"`               // expectのstatusと同じ形式
+	Headers      map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`             // ヘッダー値に対する正規表現（空文字は存在のみ）
+	BodyContains StringList        `json:"body-contains,omitempty" yaml:"body-contains,omitempty"` // ボディに含まれる文字列
+	BodyRegex    StringList        `json:"body-regex,omitempty" yaml:"body-regex,omitempty"`       // ボディにマッチする正規表現
+}
+
+// VerdictConfig は判定ルールの設定を表す構造体。meta.verdictと--verdict-rulesのファイルで共通の形式。
+type VerdictConfig struct {
+	Builtin   *bool         `json:"builtin,omitempty" yaml:"builtin,omitempty"`       // 組み込みルールを使用するか（デフォルトはtrue）
+	RulesFile string        `json:"rules-file,omitempty" yaml:"rules-file,omitempty"` // ルールを読み込むファイル（meta.verdictのみ）
+	Rules     []VerdictRule `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// BaselineConfig はベースラインとの差分分析の設定を表す構造体。
+// ベースラインはdictの値を無害な値に置き換えたリクエストで、各組み合わせの送信前に1回だけ送信される。
+type BaselineConfig struct {
+	Values         map[string]interface{} `json:"values,omitempty" yaml:"values,omitempty"`                     // ベースラインで使用するdictの値（未指定のキーはdict-defaults、それもなければ空文字）
+	MinSimilarity  *float64               `json:"min-similarity,omitempty" yaml:"min-similarity,omitempty"`     // ボディの類似度の下限（デフォルトは0.9）
+	MaxLengthDelta *int                   `json:"max-length-delta,omitempty" yaml:"max-length-delta,omitempty"` // ボディ長の差の上限（バイト数、未指定の場合は判定しない）
+	MaxTimeDelta   string                 `json:"max-time-delta,omitempty" yaml:"max-time-delta,omitempty"`     // 応答時間の増加の上限（"500ms", "2s"、未指定の場合は判定しない）
+	IgnoreHeaders  StringList             `json:"ignore-headers,omitempty" yaml:"ignore-headers,omitempty"`     // 増減を判定しないヘッダー名
+}
+
+// AuthType は認証方式を表す列挙型
+type AuthType string
+
+const (
+	AuthTypeBasic  AuthType = "basic"   // Authorization: Basic
+	AuthTypeBearer AuthType = "bearer"  // Authorization: Bearer
+	AuthTypeAPIKey AuthType = "api-key" // 任意のヘッダーまたはクエリパラメータ
+	AuthTypeDigest AuthType = "digest"  // 401のチャレンジに応答して再送する
+	AuthTypeOAuth2 AuthType = "oauth2"  // client credentialsで取得したトークンをBearerで送信する
+)
+
+// AuthConfig は認証の設定を表す構造体。値には$varなどの関数を使用できる。
+type AuthConfig struct {
+	Type     AuthType    `json:"type" yaml:"type"`
+	Username interface{} `json:"username,omitempty" yaml:"username,omitempty"` // basic, digest
+	Password interface{} `json:"password,omitempty" yaml:"password,omitempty"` // basic, digest
+	Token    interface{} `json:"token,omitempty" yaml:"token,omitempty"`       // bearer
+	Name     string      `json:"name,omitempty" yaml:"name,omitempty"`         // api-keyのヘッダー名またはパラメータ名
+	Value    interface{} `json:"value,omitempty" yaml:"value,omitempty"`       // api-keyの値
+	In       string      `json:"in,omitempty" yaml:"in,omitempty"`             // api-keyの送信先（header（デフォルト）またはquery）
+
+	TokenURL     interface{} `json:"token-url,omitempty" yaml:"token-url,omitempty"`         // oauth2のトークンエンドポイント
+	ClientID     interface{} `json:"client-id,omitempty" yaml:"client-id,omitempty"`         // oauth2
+	ClientSecret interface{} `json:"client-secret,omitempty" yaml:"client-secret,omitempty"` // oauth2
+	Scopes       StringList  `json:"scopes,omitempty" yaml:"scopes,omitempty"`               // oauth2で要求するスコープ
+	ClientAuth   string      `json:"client-auth,omitempty" yaml:"client-auth,omitempty"`     // oauth2のクライアント認証（basic（デフォルト）またはbody）
+}
+
+// SignType は署名方式を表す列挙型
+type SignType string
+
+const (
+	SignTypeAWSSigV4 SignType = "aws-sigv4" // AWS Signature Version 4
+	SignTypeHMAC     SignType = "hmac"      // テンプレートから作成した文字列のHMAC
+)
+
+// SignConfig はリクエストの署名の設定を表す構造体。鍵には$varなどの関数を使用できる。
+type SignConfig struct {
+	Type         SignType    `json:"type" yaml:"type"`
+	AccessKey    interface{} `json:"access-key,omitempty" yaml:"access-key,omitempty"`       // aws-sigv4
+	SecretKey    interface{} `json:"secret-key,omitempty" yaml:"secret-key,omitempty"`       // aws-sigv4
+	SessionToken interface{} `json:"session-token,omitempty" yaml:"session-token,omitempty"` // aws-sigv4（一時的な認証情報）
+	Region       string      `json:"region,omitempty" yaml:"region,omitempty"`               // aws-sigv4
+	Service      string      `json:"service,omitempty" yaml:"service,omitempty"`             // aws-sigv4
+	Key          interface{} `json:"key,omitempty" yaml:"key,omitempty"`                     // hmacの鍵
+	Algorithm    string      `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`         // hmacのハッシュ（sha1, sha256（デフォルト）, sha384, sha512）
+	Template     string      `json:"template,omitempty" yaml:"template,omitempty"`           // hmacで署名する文字列（{method}, {path}, {header:Name}などを展開）
+	Header       string      `json:"header,omitempty" yaml:"header,omitempty"`               // hmacの署名を設定するヘッダー名
+	Prefix       string      `json:"prefix,omitempty" yaml:"prefix,omitempty"`               // ヘッダーの値で署名の前に付ける文字列
+	Encoding     string      `json:"encoding,omitempty" yaml:"encoding,omitempty"`           // hmacの署名の表現（hex（デフォルト）またはbase64）
+}
+
+// HookConfig は外部コマンドで実行するフックの設定
+type HookConfig struct {
+	Command StringList `json:"command" yaml:"command"`                     // 実行するコマンドと引数（シェルを経由しない）
+	Timeout string     `json:"timeout,omitempty" yaml:"timeout,omitempty"` // 実行時間の上限（デフォルトは10s）
+}
+
+// HooksConfig は送信の前後に実行するフックの設定
+type HooksConfig struct {
+	Pre  *HookConfig `json:"pre,omitempty" yaml:"pre,omitempty"`   // 標準入力でProcessedRequestを受け取り、変更したリクエストを標準出力に返せる
+	Post *HookConfig `json:"post,omitempty" yaml:"post,omitempty"` // 標準入力でResultを受け取る
+}
+
+// TLSConfig はHTTPS接続のTLS設定。コマンドラインのオプションとmeta.tlsで共通。
+// 比較可能な値として、設定ごとのHTTPクライアントのキーにも使用する。
+type TLSConfig struct {
+	Insecure   bool   `json:"insecure,omitempty" yaml:"insecure,omitempty"`       // サーバー証明書を検証しない
+	CACert     string `json:"cacert,omitempty" yaml:"cacert,omitempty"`           // サーバー証明書の検証に使うCA証明書（PEM）。システムのCAの代わりに使用する
+	Cert       string `json:"cert,omitempty" yaml:"cert,omitempty"`               // クライアント証明書（PEM）
+	Key        string `json:"key,omitempty" yaml:"key,omitempty"`                 // クライアント証明書の秘密鍵（PEM、省略時はcertのファイルから読み込む）
+	SNI        string `json:"sni,omitempty" yaml:"sni,omitempty"`                 // 送信するSNI（サーバー証明書もこの名前で検証する）
+	MinVersion string `json:"min-version,omitempty" yaml:"min-version,omitempty"` // 最小のTLSバージョン（1.0、1.1、1.2、1.3、デフォルトは1.2）
+	MaxVersion string `json:"max-version,omitempty" yaml:"max-version,omitempty"` // 最大のTLSバージョン
+}
+
 // MetaConfig はリクエストのメタデータを表す構造体
 type MetaConfig struct {
-	RequestID *RequestIDConfig `json:"request-id,omitempty" yaml:"request-id,omitempty"`
+	RequestID    *RequestIDConfig       `json:"request-id,omitempty" yaml:"request-id,omitempty"`
+	Rate         *RateConfig            `json:"rate,omitempty" yaml:"rate,omitempty"`
+	DictMode     DictMode               `json:"dict-mode,omitempty" yaml:"dict-mode,omitempty"`
+	DictDefaults map[string]interface{} `json:"dict-defaults,omitempty" yaml:"dict-defaults,omitempty"` // sniperで固定する値（未指定のキーは配列の先頭）
+	Verdict      *VerdictConfig         `json:"verdict,omitempty" yaml:"verdict,omitempty"`
+	Baseline     *BaselineConfig        `json:"baseline,omitempty" yaml:"baseline,omitempty"`
+	Auth         *AuthConfig            `json:"auth,omitempty" yaml:"auth,omitempty"`
+	Sign         *SignConfig            `json:"sign,omitempty" yaml:"sign,omitempty"`
+	Hooks        *HooksConfig           `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+	TLS          *TLSConfig             `json:"tls,omitempty" yaml:"tls,omitempty"`
+}
+
+// ExtractRule はレスポンスから値を取り出して変数に設定するルールを表す構造体。
+// json/header/cookieで取り出し元を選び（未指定の場合はボディ全体）、regexで更に絞り込む。
+type ExtractRule struct {
+	JSON    string      `json:"json,omitempty" yaml:"json,omitempty"`       // ボディのJSONパス（"data.token", "items[0].id"）
+	Header  string      `json:"header,omitempty" yaml:"header,omitempty"`   // レスポンスヘッダー名
+	Cookie  string      `json:"cookie,omitempty" yaml:"cookie,omitempty"`   // Set-Cookieのクッキー名
+	Regex   string      `json:"regex,omitempty" yaml:"regex,omitempty"`     // 取り出し元に適用する正規表現
+	Group   *int        `json:"group,omitempty" yaml:"group,omitempty"`     // キャプチャグループ（デフォルトは1、グループがなければ0）
+	Default interface{} `json:"default,omitempty" yaml:"default,omitempty"` // 取り出せなかった場合の値
+}
+
+// ExpectConfig はレスポンスに対するアサーションを表す構造体
+type ExpectConfig struct {
+	Status          interface{}            `json:"status,omitempty" yaml:"status,omitempty"`                       // 200, "2xx", "200-299" またはそれらの配列
+	Headers         map[string]interface{} `json:"headers,omitempty" yaml:"headers,omitempty"`                     // 値の一致（trueは存在、falseは非存在）
+	BodyContains    StringList             `json:"body-contains,omitempty" yaml:"body-contains,omitempty"`         // ボディに含まれる文字列
+	BodyNotContains StringList             `json:"body-not-contains,omitempty" yaml:"body-not-contains,omitempty"` // ボディに含まれない文字列
+	BodyRegex       StringList             `json:"body-regex,omitempty" yaml:"body-regex,omitempty"`               // ボディにマッチする正規表現
+	JSON            map[string]interface{} `json:"json,omitempty" yaml:"json,omitempty"`                           // JSONパスと期待値
+	MaxTime         string                 `json:"max-time,omitempty" yaml:"max-time,omitempty"`                   // 最大応答時間（"500ms", "2s"）
 }
 
 // RequestConfig はリクエスト設定を表す構造体
@@ -34,7 +194,13 @@ type RequestConfig struct {
 	Variables map[string]interface{}   `json:"variables,omitempty" yaml:"variables,omitempty"`
 	Dict      map[string][]interface{} `json:"dict,omitempty" yaml:"dict,omitempty"`
 	Meta      *MetaConfig              `json:"meta,omitempty" yaml:"meta,omitempty"`
+	Extract   map[string]*ExtractRule  `json:"extract,omitempty" yaml:"extract,omitempty"` // レスポンスから取り出し、後続のドキュメントで$varとして参照する変数
+	Expect    *ExpectConfig            `json:"expect,omitempty" yaml:"expect,omitempty"`   // レスポンスに対するアサーション
+	Raw       interface{}              `json:"raw,omitempty" yaml:"raw,omitempty"`         // リクエスト全体のテキスト（method、path、headers、bodyなどの代わりに使い、そのまま送信する）
 	FilePath  string                   `json:"-" yaml:"-"`
+	// DictSources は配列ではなく外部ファイル参照（$lines, $csv, $jsonl）で指定されたdictの値。
+	// パーサーが読み込んでDictに展開する。
+	DictSources map[string]interface{} `json:"-" yaml:"-"`
 }
 
 // KeyValue は配列形式のパラメータを表す構造体
@@ -43,40 +209,125 @@ type KeyValue struct {
 	Value interface{} `json:"value" yaml:"value"`
 }
 
+// DigestAuth はDigest認証の資格情報を表す構造体
+type DigestAuth struct {
+	Username string
+	Password string
+}
+
+// OAuth2Auth はOAuth2のclient credentialsでトークンを取得するための設定。
+// 同じ設定のリクエストは1回の実行の中でトークンを共有する。
+type OAuth2Auth struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scope        string // スペース区切りのスコープ
+	ClientAuth   string // basicまたはbody
+}
+
 // ProcessedRequest は処理済みリクエストを表す構造体
 type ProcessedRequest struct {
 	Method           string
 	URL              string
 	RawRequestTarget string
+	Raw              string `json:",omitempty"` // rawで記述したリクエスト全体（評価と改行の正規化の後、そのまま送信する）
+	RawOrigin        string `json:"-"`          // rawのリクエストの接続先（--hostのスキームとホスト）
 	Headers          map[string]string
 	Body             string
-	RequestID        string // Request IDを追加
+	RequestID        string                 // Request IDを追加
+	DictValues       map[string]interface{} `json:"-"` // リクエストの生成に使用したdictの組み合わせ
+	DigestAuth       *DigestAuth            `json:"-"` // 401のチャレンジに応答するための資格情報
+	OAuth2           *OAuth2Auth            `json:"-"` // 送信時にトークンを取得するための設定
+	TLS              *TLSConfig             `json:"-"` // meta.tlsの設定（ファイルのパスはリクエスト定義のディレクトリから解決済み）
+	Secrets          []string               `json:"-"` // 結果の出力時にマスクする値（認証情報）
 }
 
 // ResponseTiming はレスポンス時間の詳細を表す構造体
+// 単位は秒。リダイレクトした場合、total以外は最後のリクエストの値。
 type ResponseTiming struct {
-	Total   float64 `json:"total"`
-	DNS     float64 `json:"dns"`
-	Connect float64 `json:"connect"`
-	SSL     float64 `json:"ssl"`
-	Send    float64 `json:"send"`
-	Wait    float64 `json:"wait"`
-	Receive float64 `json:"receive"`
+	Total            float64 `json:"total"`
+	DNS              float64 `json:"dns"`               // 名前解決
+	Connect          float64 `json:"connect"`           // TCP接続
+	SSL              float64 `json:"ssl"`               // TLSハンドシェイク
+	Send             float64 `json:"send"`              // 接続の取得からリクエストの書き込み完了まで
+	Wait             float64 `json:"wait"`              // リクエストの書き込み完了からレスポンスの最初のバイトまで
+	Receive          float64 `json:"receive"`           // レスポンスの最初のバイトから読み取り完了まで
+	TTFB             float64 `json:"ttfb"`              // 送信開始からレスポンスの最初のバイトまで
+	ConnectionReused bool    `json:"connection_reused"` // keep-aliveの接続を再利用した場合はtrue（DNS、connect、sslは0）
+}
+
+// TLSInfo はTLS接続で合意した内容とサーバー証明書の概要を表す構造体
+type TLSInfo struct {
+	Version         string           `json:"version"`                    // "TLS 1.3" など
+	CipherSuite     string           `json:"cipher_suite"`               // "TLS_AES_128_GCM_SHA256" など
+	Protocol        string           `json:"protocol,omitempty"`         // ALPNで合意したプロトコル（h2、http/1.1）
+	ServerName      string           `json:"server_name,omitempty"`      // 送信したSNI
+	PeerCertificate *CertificateInfo `json:"peer_certificate,omitempty"` // サーバー証明書（チェーンの先頭）
+}
+
+// CertificateInfo は証明書の概要を表す構造体
+type CertificateInfo struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	DNSNames     []string  `json:"dns_names,omitempty"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+	SerialNumber string    `json:"serial_number"`
+	SHA256       string    `json:"sha256"` // DERエンコードした証明書のSHA-256（16進数）
+}
+
+// Cookie はクッキージャーを通して送受信したクッキーを表す構造体
+type Cookie struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Domain string `json:"domain,omitempty"`
+	Path   string `json:"path,omitempty"`
 }
 
 // ResponseData はHTTPレスポンスデータを表す構造体
 type ResponseData struct {
-	StatusCode int                 `json:"status_code"`
-	Headers    map[string][]string `json:"headers"`
-	Body       string              `json:"body"`
-	Time       ResponseTiming      `json:"time"`
+	StatusCode      int                 `json:"status_code"`
+	Headers         map[string][]string `json:"headers"`
+	Body            string              `json:"body"`
+	Time            ResponseTiming      `json:"time"`
+	TLS             *TLSInfo            `json:"tls,omitempty"`              // HTTPSの場合のみ
+	CookiesSent     []Cookie            `json:"cookies_sent,omitempty"`     // クッキージャーから送信したクッキー
+	CookiesReceived []Cookie            `json:"cookies_received,omitempty"` // Set-Cookieで受け取ったクッキー（クッキージャー有効時のみ）
+}
+
+// AssertionResult はexpectの1項目の評価結果を表す構造体
+type AssertionResult struct {
+	Name     string `json:"name"` // "status", "header X-Frame-Options", "json data.id" など
+	Passed   bool   `json:"passed"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// BaselineDiff はベースラインのレスポンスとの差分を表す構造体
+type BaselineDiff struct {
+	Status         int      `json:"status"`                    // ベースラインのステータスコード
+	StatusChanged  bool     `json:"status_changed"`            // ステータスコードが異なるか
+	LengthDelta    int      `json:"length_delta"`              // ボディ長の差（バイト数）
+	Similarity     float64  `json:"similarity"`                // ボディの類似度（0〜1）
+	HeadersAdded   []string `json:"headers_added,omitempty"`   // ベースラインになかったヘッダー名
+	HeadersRemoved []string `json:"headers_removed,omitempty"` // ベースラインにあったが欠けたヘッダー名
+	TimeDelta      float64  `json:"time_delta"`                // 応答時間の差（秒）
+	Flagged        bool     `json:"flagged"`                   // いずれかの差分が閾値を超えたか
+	Reasons        []string `json:"reasons,omitempty"`         // 閾値を超えた差分の説明
 }
 
 // Result は最終的な結果を表す構造体
 type Result struct {
-	Request  ProcessedRequest       `json:"request"`
-	Response ResponseData           `json:"response"`
-	Metadata map[string]interface{} `json:"metadata"`
+	Request     ProcessedRequest       `json:"request"`
+	Response    ResponseData           `json:"response"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	Extracted   map[string]interface{} `json:"extracted,omitempty"`    // extractで取り出した変数
+	Passed      *bool                  `json:"passed,omitempty"`       // expectの全項目を満たしたか（expect未指定の場合はnil）
+	Assertions  []AssertionResult      `json:"assertions,omitempty"`   // expectの評価結果
+	Verdict     Verdict                `json:"verdict,omitempty"`      // WAFテストでの判定
+	VerdictRule string                 `json:"verdict_rule,omitempty"` // 判定に該当したルール名
+	Baseline    *BaselineDiff          `json:"baseline,omitempty"`     // meta.baselineのベースラインとの差分
+	HookError   string                 `json:"hook_error,omitempty"`   // meta.hooksのpreまたはpostの実行に失敗した場合のエラー
 }
 
 // OutputFormat は出力フォーマットを表す列挙型
@@ -86,6 +337,8 @@ const (
 	OutputFormatTable OutputFormat = "table"
 	OutputFormatCSV   OutputFormat = "csv"
 	OutputFormatJSON  OutputFormat = "json"
+	// OutputFormatNDJSON は結果を受信順に1行1件で逐次出力する
+	OutputFormatNDJSON OutputFormat = "ndjson"
 )
 
 // CLIConfig はCLIオプションを表す構造体
@@ -100,4 +353,17 @@ type CLIConfig struct {
 	Files           []string
 	RequestID       *RequestIDConfig // Request ID設定を追加
 	MaxCombinations int              // Dict組み合わせ数の上限
+	Concurrency     int              // 同時送信数
+	Unordered       bool             // 結果を完了順に出力する
+	Rate            string           // 送信レート（"20/s" 形式、空の場合は無制限）
+	Burst           int              // レート制御のバースト数
+	RatePerHost     bool             // ホスト単位でレート制御する
+	DryRun          bool             // 送信せずにワイヤー表現を出力する
+	ShowSecrets     bool             // ドライランやエクスポートで認証情報をマスクせずに出力する
+	Checkpoint      string           // 完了した組み合わせを記録するファイル
+	Resume          bool             // チェックポイントの完了分をスキップし、既存の結果に追記する
+	Verdict         bool             // 組み込みルールでレスポンスを判定する
+	VerdictRules    string           // 判定ルールを読み込むファイル（指定した場合はVerdictも有効になる）
+	CookieJar       string           // クッキーを読み込み、終了時に保存するNetscape形式のファイル
+	TLS             TLSConfig        // --insecure、--cacertなどのTLS設定
 }
@@ -1,7 +1,6 @@
 package http
 
 import (
-	"bufio"
 	"context"
 	"crypto/tls"
 	"fmt"
@@ -9,7 +8,9 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/secureta/s2http-request/internal/config"
@@ -18,51 +19,62 @@ import (
 // Client はHTTPクライアント
 type Client struct {
 	httpClient *http.Client
+	sender     *wireSender // フラグメントとraw request targetの送信に使用する
+	tlsConfig  *tls.Config // SetTLSConfigで設定された場合のみ
 	timeout    time.Duration
 	proxy      string
+	proxyURL   *url.URL
+	jar        *CookieJar       // SetCookieJarで設定された場合のみ
+	tokens     tokenCache       // meta.auth（oauth2）で取得したトークン
+	tlsOptions config.TLSConfig // コマンドラインのTLS設定
+	tlsClients tlsClientCache   // meta.tlsの設定ごとのクライアント
 }
 
-// fragmentTransport はフラグメントを含むリクエストを送信するためのカスタムトランスポート
+// tlsClient はTLS設定ごとのHTTPクライアントと手動送信用のwireSender
+type tlsClient struct {
+	httpClient *http.Client
+	sender     *wireSender
+}
+
+// tlsClientCache はmeta.tlsをコマンドラインの設定に重ねた設定ごとにクライアントを保持する
+type tlsClientCache struct {
+	mu      sync.Mutex
+	clients map[config.TLSConfig]*tlsClient
+}
+
+// fragmentTransport はフラグメントを含むリクエストとTransfer-Encodingを指定したリクエストを送信するためのカスタムトランスポート
 type fragmentTransport struct {
-	base http.RoundTripper
+	base   http.RoundTripper
+	sender *wireSender
 }
 
 func (t *fragmentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	// フラグメントが含まれているかチェック
-	if !strings.Contains(req.URL.String(), "#") {
-		// フラグメントがない場合は通常の処理
+	if !needsManualRequest(req) {
+		// それ以外は通常の処理
 		if t.base != nil {
 			return t.base.RoundTrip(req)
 		}
 		return http.DefaultTransport.RoundTrip(req)
 	}
 
-	// フラグメントがある場合は手動でリクエストを構築
-	return t.sendRequestWithFragment(req)
+	// 手動でリクエストを構築
+	fullRequest, err := buildFragmentRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return t.sender.roundTrip(req.Context(), req.URL, []byte(fullRequest), req)
 }
 
-func (t *fragmentTransport) sendRequestWithFragment(req *http.Request) (resp *http.Response, err error) {
-	// URLを解析
-	parsedURL := req.URL
-	host := parsedURL.Host
-	if parsedURL.Port() == "" {
-		if parsedURL.Scheme == "https" {
-			host += ":443"
-		} else {
-			host += ":80"
-		}
-	}
+// needsManualRequest は手動で構築する必要があるリクエストかを返す。
+// http.Transportはリクエストラインからフラグメントを、ヘッダーからTransfer-Encodingを取り除いて送信する。
+func needsManualRequest(req *http.Request) bool {
+	return strings.Contains(req.URL.String(), "#") || req.Header.Get("Transfer-Encoding") != ""
+}
 
-	// TCP接続を確立. The tool intentionally sends user-defined requests to user-specified hosts.
-	conn, err := net.DialTimeout("tcp", host, 30*time.Second) // #nosec G704
-	if err != nil {
-		return nil, fmt.Errorf("failed to dial: %w", err)
-	}
-	defer func() {
-		if closeErr := conn.Close(); closeErr != nil && err == nil {
-			err = fmt.Errorf("failed to close connection: %w", closeErr)
-		}
-	}()
+// buildFragmentRequest はフラグメントを含むリクエストのワイヤー表現を構築する。
+// Transfer-Encodingを指定した場合、ボディは構築済みのものとしてそのまま送信し、Content-Lengthは追加しない。
+func buildFragmentRequest(req *http.Request) (string, error) {
+	parsedURL := req.URL
 
 	// リクエストラインを構築（フラグメントを含む）
 	requestURI := parsedURL.Path
@@ -76,63 +88,51 @@ func (t *fragmentTransport) sendRequestWithFragment(req *http.Request) (resp *ht
 		requestURI = "/"
 	}
 
-	// HTTPリクエストを手動で構築
 	requestLine := fmt.Sprintf("%s %s HTTP/1.1\r\n", req.Method, requestURI)
 
-	// ヘッダーを構築
-	headers := ""
-	headers += fmt.Sprintf("Host: %s\r\n", parsedURL.Host)
+	// ヘッダーを構築（Hostヘッダーが指定されている場合はURLのホストの代わりに使う）
+	hostHeader := parsedURL.Host
+	if override := req.Header.Get("Host"); override != "" {
+		hostHeader = override
+	}
+	headers := fmt.Sprintf("Host: %s\r\n", hostHeader)
 
-	for key, values := range req.Header {
-		for _, value := range values {
+	for _, key := range sortedHeaderKeys(req.Header) {
+		if strings.EqualFold(key, "Host") {
+			continue
+		}
+		for _, value := range req.Header[key] {
 			headers += fmt.Sprintf("%s: %s\r\n", key, value)
 		}
 	}
 
-	// Connection: close を追加（シンプルにするため）
-	headers += "Connection: close\r\n"
-
 	// リクエストボディの処理
 	var body string
 	if req.Body != nil {
 		bodyBytes, err := io.ReadAll(req.Body)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read request body: %w", err)
+			return "", fmt.Errorf("failed to read request body: %w", err)
 		}
 		body = string(bodyBytes)
-		if body != "" {
+		if body != "" && req.Header.Get("Content-Length") == "" && req.Header.Get("Transfer-Encoding") == "" {
 			headers += fmt.Sprintf("Content-Length: %d\r\n", len(body))
 		}
 	}
 
-	// 完全なHTTPリクエストを構築
-	fullRequest := requestLine + headers + "\r\n" + body
-
-	// リクエストを送信
-	_, err = conn.Write([]byte(fullRequest))
-	if err != nil {
-		return nil, fmt.Errorf("failed to write request: %w", err)
-	}
-
-	// レスポンスを読み取り
-	reader := bufio.NewReader(conn)
-	resp, err = http.ReadResponse(reader, req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	// 接続は1件ごとに閉じる
+	if req.Header.Get("Connection") == "" {
+		headers += "Connection: close\r\n"
 	}
 
-	return resp, nil
+	// 完全なHTTPリクエストを構築
+	return requestLine + headers + "\r\n" + body, nil
 }
 
 // NewClient は新しいHTTPクライアントを作成
 func NewClient(timeout time.Duration, proxy string) (*Client, error) {
-	transport := &fragmentTransport{
-		base: http.DefaultTransport,
-	}
-
-	client := &http.Client{
-		Timeout:   timeout,
-		Transport: transport,
+	c := &Client{
+		timeout: timeout,
+		proxy:   proxy,
 	}
 
 	// プロキシ設定
@@ -141,30 +141,113 @@ func NewClient(timeout time.Duration, proxy string) (*Client, error) {
 		if err != nil {
 			return nil, fmt.Errorf("invalid proxy URL: %w", err)
 		}
+		c.proxyURL = proxyURL
+	}
 
-		baseTransport := &http.Transport{
-			Proxy: http.ProxyURL(proxyURL),
+	c.useTLSClient(c.newTLSClient(nil))
+	return c, nil
+}
+
+// newTLSClient はプロキシ、TLS設定、クッキージャーを反映したhttp.ClientとwireSenderを作成する
+func (c *Client) newTLSClient(tlsConfig *tls.Config) *tlsClient {
+	sender := &wireSender{
+		timeout:   c.timeout,
+		proxy:     c.proxyFor,
+		tlsConfig: tlsConfig,
+	}
+	transport := &fragmentTransport{
+		base:   http.DefaultTransport,
+		sender: sender,
+	}
+	if c.proxyURL != nil {
+		transport.base = &http.Transport{
+			Proxy:           http.ProxyURL(c.proxyURL),
+			TLSClientConfig: tlsConfig,
 		}
+	} else if tlsConfig != nil {
+		baseTransport := http.DefaultTransport.(*http.Transport).Clone()
+		baseTransport.TLSClientConfig = tlsConfig
 		transport.base = baseTransport
 	}
 
-	return &Client{
-		httpClient: client,
-		timeout:    timeout,
-		proxy:      proxy,
-	}, nil
+	var roundTripper http.RoundTripper = transport
+	if c.jar != nil {
+		roundTripper = &cookieTransport{base: transport, jar: c.jar}
+	}
+	return &tlsClient{
+		httpClient: &http.Client{
+			Timeout:   c.timeout,
+			Transport: roundTripper,
+		},
+		sender: sender,
+	}
 }
 
-// SendRequest はHTTPリクエストを送信
-func (c *Client) SendRequest(ctx context.Context, processedRequest *config.ProcessedRequest) (responseData *config.ResponseData, err error) {
-	if processedRequest.RawRequestTarget != "" {
-		return c.sendRawRequestTargetRequest(ctx, processedRequest)
+// proxyFor はwireSenderが使うプロキシを返す。--proxyがない場合はhttp.DefaultTransportと同じく環境変数に従う。
+func (c *Client) proxyFor(target *url.URL) (*url.URL, error) {
+	if c.proxyURL != nil {
+		return c.proxyURL, nil
 	}
+	return http.ProxyFromEnvironment(&http.Request{URL: target})
+}
 
-	// タイミング測定用
-	startTime := time.Now()
-	var dnsTime, connectTime, sslTime, sendTime, waitTime, receiveTime time.Duration
+// useTLSClient はデフォルトのクライアントを設定する
+func (c *Client) useTLSClient(client *tlsClient) {
+	c.httpClient = client.httpClient
+	c.sender = client.sender
+}
+
+// SetCookieJar はクッキージャーを設定する。以降の全てのリクエストでクッキーを送受信する。
+func (c *Client) SetCookieJar(jar *CookieJar) {
+	c.jar = jar
+	c.useTLSClient(c.newTLSClient(c.tlsConfig))
+}
+
+// SetTLSConfig はコマンドラインのTLS設定を設定する。CA証明書とクライアント証明書を読み込めない場合はエラーを返す。
+// meta.tlsを持つリクエストでは、この設定にmeta.tlsを重ねた設定を使う。
+func (c *Client) SetTLSConfig(options config.TLSConfig) error {
+	var tlsConfig *tls.Config
+	if options != (config.TLSConfig{}) {
+		var err error
+		if tlsConfig, err = newTLSConfig(options); err != nil {
+			return err
+		}
+	}
+	c.tlsOptions = options
+	c.tlsConfig = tlsConfig
+	c.useTLSClient(c.newTLSClient(tlsConfig))
+	return nil
+}
+
+// clientFor はリクエストのmeta.tlsに応じたhttp.ClientとwireSenderを返す
+func (c *Client) clientFor(options *config.TLSConfig) (*tlsClient, error) {
+	if options == nil {
+		return &tlsClient{httpClient: c.httpClient, sender: c.sender}, nil
+	}
+	merged := mergeTLSOptions(c.tlsOptions, *options)
+	if merged == c.tlsOptions {
+		return &tlsClient{httpClient: c.httpClient, sender: c.sender}, nil
+	}
+
+	c.tlsClients.mu.Lock()
+	defer c.tlsClients.mu.Unlock()
+	if client, ok := c.tlsClients.clients[merged]; ok {
+		return client, nil
+	}
+	tlsConfig, err := newTLSConfig(merged)
+	if err != nil {
+		return nil, fmt.Errorf("invalid meta.tls: %w", err)
+	}
+	if c.tlsClients.clients == nil {
+		c.tlsClients.clients = make(map[config.TLSConfig]*tlsClient)
+	}
+	client := c.newTLSClient(tlsConfig)
+	c.tlsClients.clients[merged] = client
+	return client, nil
+}
 
+// newRequest は処理済みリクエストからhttp.Requestを作成する
+func newRequest(ctx context.Context, processedRequest *config.ProcessedRequest) (*http.Request, error) {
 	// リクエストボディの準備
 	var bodyReader io.Reader
 	if processedRequest.Body != "" {
@@ -187,11 +270,47 @@ func (c *Client) SendRequest(ctx context.Context, processedRequest *config.Proce
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	}
 
-	// リクエスト送信時刻
-	sendTime = time.Since(startTime)
+	return req, nil
+}
+
+// SendRequest はHTTPリクエストを送信
+func (c *Client) SendRequest(ctx context.Context, processedRequest *config.ProcessedRequest) (*config.ResponseData, error) {
+	if processedRequest.DigestAuth != nil {
+		return c.sendWithDigestAuth(ctx, processedRequest)
+	}
+	if processedRequest.OAuth2 != nil {
+		return c.sendWithOAuth2(ctx, processedRequest)
+	}
+	return c.send(ctx, processedRequest)
+}
+
+// send は1件のリクエストを、rawやraw request targetの有無に応じた経路で送信する
+func (c *Client) send(ctx context.Context, processedRequest *config.ProcessedRequest) (responseData *config.ResponseData, err error) {
+	client, err := c.clientFor(processedRequest.TLS)
+	if err != nil {
+		return nil, err
+	}
+	if processedRequest.Raw != "" {
+		return c.sendRawRequest(ctx, processedRequest, client.sender)
+	}
+	if processedRequest.RawRequestTarget != "" {
+		return c.sendRawRequestTargetRequest(ctx, processedRequest, client.sender)
+	}
+
+	ctx, recorder := c.withCookieRecorder(ctx)
+
+	// タイミング測定用
+	timing := newTimingRecorder()
+	ctx = timing.withTrace(ctx)
+
+	// HTTPリクエストの作成
+	req, err := newRequest(ctx, processedRequest)
+	if err != nil {
+		return nil, err
+	}
 
 	// HTTPリクエストの送信
-	resp, err := c.httpClient.Do(req)
+	resp, err := client.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -201,80 +320,149 @@ func (c *Client) SendRequest(ctx context.Context, processedRequest *config.Proce
 		}
 	}()
 
-	// レスポンス受信時刻
-	waitTime = time.Since(startTime) - sendTime
-
 	// レスポンスボディの読み取り
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	// レスポンス処理完了時刻
-	receiveTime = time.Since(startTime) - waitTime - sendTime
-	totalTime := time.Since(startTime)
-
 	// レスポンスデータの構築
 	responseData = &config.ResponseData{
 		StatusCode: resp.StatusCode,
 		Headers:    resp.Header,
 		Body:       string(bodyBytes),
-		Time: config.ResponseTiming{
-			Total:   totalTime.Seconds(),
-			DNS:     dnsTime.Seconds(),
-			Connect: connectTime.Seconds(),
-			SSL:     sslTime.Seconds(),
-			Send:    sendTime.Seconds(),
-			Wait:    waitTime.Seconds(),
-			Receive: receiveTime.Seconds(),
-		},
+		Time:       timing.timing(time.Now()),
+		TLS:        tlsInfo(resp.TLS),
 	}
+	recorder.recordCookies(responseData)
+
+	return responseData, nil
+}
 
+// sendRawRequest はrawで記述したリクエストを変更せずにRawOriginの接続先へ送信する。
+// クッキージャーのクッキーは追加しないが、受け取ったクッキーはリクエストのURLに対して保存する。
+func (c *Client) sendRawRequest(ctx context.Context, processedRequest *config.ProcessedRequest, sender *wireSender) (*config.ResponseData, error) {
+	origin := processedRequest.RawOrigin
+	if origin == "" {
+		origin = processedRequest.URL
+	}
+	scheme, host, _, err := parseRawRequestOrigin(origin)
+	if err != nil {
+		return nil, err
+	}
+	responseData, received, err := sendWire(ctx, sender, scheme, host, []byte(processedRequest.Raw), processedRequest.Method)
+	if err != nil {
+		return nil, err
+	}
+	if c.jar != nil {
+		// origin-formの場合は接続先とリクエストターゲットのパス、absolute-formの場合はそのURLで照合する
+		cookieURL := rawRequestCookieURL(scheme, host, strings.TrimPrefix(processedRequest.URL, origin))
+		if !strings.HasPrefix(processedRequest.URL, origin) {
+			if target, parseErr := url.Parse(processedRequest.URL); parseErr == nil && target.Host != "" {
+				cookieURL = rawRequestCookieURL(target.Scheme, target.Host, target.EscapedPath())
+			}
+		}
+		c.jar.SetCookies(cookieURL, received)
+		responseData.CookiesReceived = receivedCookies(cookieURL, received)
+	}
 	return responseData, nil
 }
 
-func (c *Client) sendRawRequestTargetRequest(ctx context.Context, processedRequest *config.ProcessedRequest) (responseData *config.ResponseData, err error) {
-	startTime := time.Now()
-	scheme, host, hostname, err := parseRawRequestOrigin(processedRequest.URL)
+func (c *Client) sendRawRequestTargetRequest(ctx context.Context, processedRequest *config.ProcessedRequest, sender *wireSender) (*config.ResponseData, error) {
+	scheme, host, _, err := parseRawRequestOrigin(processedRequest.URL)
 	if err != nil {
 		return nil, err
 	}
 	if scheme != "http" && scheme != "https" {
 		return nil, fmt.Errorf("unsupported URL scheme for raw request target: %s", scheme)
 	}
-	dialHost := host
-	if !strings.Contains(host, ":") {
-		if scheme == "https" {
-			dialHost += ":443"
-		} else {
-			dialHost += ":80"
-		}
+
+	// クッキージャーのクッキーはCookieヘッダーに追加して送信する
+	var cookieURL *url.URL
+	var sentCookies []config.Cookie
+	if c.jar != nil {
+		cookieURL = rawRequestCookieURL(scheme, host, processedRequest.RawRequestTarget)
+		processedRequest, sentCookies = c.withJarCookies(processedRequest, cookieURL)
 	}
 
-	dialer := &net.Dialer{Timeout: c.timeout}
-	var conn net.Conn
-	if scheme == "https" {
-		conn, err = tls.DialWithDialer(dialer, "tcp", dialHost, &tls.Config{
-			ServerName: hostname,
-			MinVersion: tls.VersionTLS12,
-		})
-	} else {
-		conn, err = dialer.DialContext(ctx, "tcp", dialHost)
+	fullRequest := buildRawRequestTargetRequest(processedRequest, host)
+	responseData, received, err := sendWire(ctx, sender, scheme, host, []byte(fullRequest), processedRequest.Method)
+	if err != nil {
+		return nil, err
+	}
+	if c.jar != nil {
+		c.jar.SetCookies(cookieURL, received)
+		responseData.CookiesSent = sentCookies
+		responseData.CookiesReceived = receivedCookies(cookieURL, received)
+	}
+	return responseData, nil
+}
+
+// withJarCookies はクッキージャーのクッキーをCookieヘッダーに追加したリクエストと、追加したクッキーを返す。
+// 追加するクッキーがない場合は元のリクエストをそのまま返す。
+func (c *Client) withJarCookies(processedRequest *config.ProcessedRequest, cookieURL *url.URL) (*config.ProcessedRequest, []config.Cookie) {
+	existing, _ := getHeader(processedRequest.Headers, "Cookie")
+	cookieHeader, sentCookies := mergeCookieHeader(existing, c.jar.Cookies(cookieURL))
+	if len(sentCookies) == 0 {
+		return processedRequest, nil
+	}
+	withCookies := *processedRequest
+	withCookies.Headers = make(map[string]string, len(processedRequest.Headers)+1)
+	for key, value := range processedRequest.Headers {
+		if !strings.EqualFold(key, "Cookie") {
+			withCookies.Headers[key] = value
+		}
 	}
+	withCookies.Headers["Cookie"] = cookieHeader
+	return &withCookies, sentCookies
+}
+
+// sendWire は手動で構築したリクエストをschemeとhostの接続先に送信し、レスポンスと受け取ったクッキーを返す。
+// methodはレスポンスの解釈（HEADのボディなど）にのみ使う。
+func sendWire(ctx context.Context, sender *wireSender, scheme, host string, wire []byte, method string) (responseData *config.ResponseData, cookies []*http.Cookie, err error) {
+	timing := newTimingRecorder()
+	ctx = timing.withTrace(ctx)
+	resp, err := sender.roundTrip(ctx, &url.URL{Scheme: scheme, Host: host}, wire, &http.Request{Method: method})
 	if err != nil {
-		return nil, fmt.Errorf("failed to dial: %w", err)
+		return nil, nil, err
 	}
 	defer func() {
-		if closeErr := conn.Close(); closeErr != nil && err == nil {
-			err = fmt.Errorf("failed to close connection: %w", closeErr)
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close response body: %w", closeErr)
 		}
 	}()
-	if deadline, ok := ctx.Deadline(); ok {
-		_ = conn.SetDeadline(deadline)
-	} else if c.timeout > 0 {
-		_ = conn.SetDeadline(time.Now().Add(c.timeout))
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	responseData = &config.ResponseData{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header,
+		Body:       string(bodyBytes),
+		Time:       timing.timing(time.Now()),
+		TLS:        tlsInfo(resp.TLS),
+	}
+	return responseData, resp.Cookies(), nil
+}
+
+// rawRequestCookieURL はraw request targetのリクエストでクッキーの照合に使うURLを返す。
+// origin-form以外のリクエストターゲットではパスを"/"とみなす。
+func rawRequestCookieURL(scheme, host, requestTarget string) *url.URL {
+	path := "/"
+	if strings.HasPrefix(requestTarget, "/") {
+		path = requestTarget
+		if end := strings.IndexAny(path, "?#"); end >= 0 {
+			path = path[:end]
+		}
 	}
+	return &url.URL{Scheme: scheme, Host: host, Path: path}
+}
 
+// buildRawRequestTargetRequest はraw request targetを使うリクエストのワイヤー表現を構築する。
+// Transfer-Encodingを指定した場合はContent-Lengthを追加しない。
+func buildRawRequestTargetRequest(processedRequest *config.ProcessedRequest, host string) string {
 	body := processedRequest.Body
 	requestLine := fmt.Sprintf("%s %s HTTP/1.1\r\n", processedRequest.Method, processedRequest.RawRequestTarget)
 	hostHeader := host
@@ -282,59 +470,20 @@ func (c *Client) sendRawRequestTargetRequest(ctx context.Context, processedReque
 		hostHeader = override
 	}
 	headers := fmt.Sprintf("Host: %s\r\n", hostHeader)
-	for key, value := range processedRequest.Headers {
+	for _, key := range sortedKeys(processedRequest.Headers) {
 		if strings.EqualFold(key, "Host") {
 			continue
 		}
-		headers += fmt.Sprintf("%s: %s\r\n", key, value)
+		headers += fmt.Sprintf("%s: %s\r\n", key, processedRequest.Headers[key])
 	}
-	if body != "" && !hasHeader(processedRequest.Headers, "Content-Length") {
+	if body != "" && !hasHeader(processedRequest.Headers, "Content-Length") && !hasHeader(processedRequest.Headers, "Transfer-Encoding") {
 		headers += fmt.Sprintf("Content-Length: %d\r\n", len(body))
 	}
 	if !hasHeader(processedRequest.Headers, "Connection") {
 		headers += "Connection: close\r\n"
 	}
 
-	fullRequest := requestLine + headers + "\r\n" + body
-	sendStart := time.Now()
-	if _, err = conn.Write([]byte(fullRequest)); err != nil {
-		return nil, fmt.Errorf("failed to write request: %w", err)
-	}
-	sendTime := time.Since(sendStart)
-
-	reader := bufio.NewReader(conn)
-	dummyReq := &http.Request{Method: processedRequest.Method}
-	waitStart := time.Now()
-	resp, err := http.ReadResponse(reader, dummyReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-	defer func() {
-		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
-			err = fmt.Errorf("failed to close response body: %w", closeErr)
-		}
-	}()
-	waitTime := time.Since(waitStart)
-
-	receiveStart := time.Now()
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-	receiveTime := time.Since(receiveStart)
-	totalTime := time.Since(startTime)
-
-	return &config.ResponseData{
-		StatusCode: resp.StatusCode,
-		Headers:    resp.Header,
-		Body:       string(bodyBytes),
-		Time: config.ResponseTiming{
-			Total:   totalTime.Seconds(),
-			Send:    sendTime.Seconds(),
-			Wait:    waitTime.Seconds(),
-			Receive: receiveTime.Seconds(),
-		},
-	}, nil
+	return requestLine + headers + "\r\n" + body
 }
 
 func parseRawRequestOrigin(rawURL string) (scheme, host, hostname string, err error) {
@@ -383,6 +532,26 @@ func hasHeader(headers map[string]string, name string) bool {
 	return ok
 }
 
+// sortedKeys はヘッダー順を安定させるためにキーをソートして返す
+func sortedKeys(headers map[string]string) []string {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedHeaderKeys はhttp.Headerのキーをソートして返す
+func sortedHeaderKeys(header http.Header) []string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // SendRequestWithRetry はリトライ機能付きでHTTPリクエストを送信
 func (c *Client) SendRequestWithRetry(ctx context.Context, processedRequest *config.ProcessedRequest, maxRetries int) (*config.ResponseData, error) {
 	var lastErr error
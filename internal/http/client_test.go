@@ -3,9 +3,12 @@ package http
 import (
 	"bufio"
 	"context"
+	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -294,6 +297,162 @@ func TestSendRequestWithRawRequestTargetAllowsHostHeaderOverride(t *testing.T) {
 	}
 }
 
+func TestSendRequestWithRawSendsBytesVerbatim(t *testing.T) {
+	raw := "POST  /a/../b?x=1 HTTP/1.1\nHost: tenant.example.test\r\nX-Folded: a\r\n b\r\nContent-Length: 5\r\nContent-Length: 3\r\n\r\nhello"
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	captured := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// ヘッダーの解釈に頼らず、送信されるはずのバイト数だけ読み取る
+		buf := make([]byte, len(raw))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return
+		}
+		captured <- string(buf)
+		_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nSet-Cookie: session=abc; Path=/a\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok"))
+	}()
+
+	client, err := NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetCookieJar(NewCookieJar())
+	request := &config.ProcessedRequest{
+		Method:  "POST",
+		URL:     "http://" + listener.Addr().String() + "/a/../b?x=1",
+		Raw:     raw,
+		Headers: map[string]string{"X-Ignored": "1"},
+	}
+
+	rendered, err := client.RenderRequest(request)
+	if err != nil {
+		t.Fatalf("RenderRequest returned error: %v", err)
+	}
+	if string(rendered) != raw {
+		t.Errorf("Expected the dry run to print the raw request, got %q", rendered)
+	}
+
+	response, err := client.SendRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("SendRequest returned error: %v", err)
+	}
+	if response.StatusCode != http.StatusOK || response.Body != "ok" {
+		t.Errorf("Unexpected response: %d %q", response.StatusCode, response.Body)
+	}
+	if len(response.CookiesReceived) != 1 || response.CookiesReceived[0].Name != "session" {
+		t.Errorf("Expected the received cookie to be recorded, got %+v", response.CookiesReceived)
+	}
+
+	select {
+	case got := <-captured:
+		if got != raw {
+			t.Errorf("Expected the raw request to be sent verbatim\n got: %q\nwant: %q", got, raw)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for raw request")
+	}
+}
+
+func TestSendRequestWithRawAbsoluteFormConnectsToOrigin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		_, _ = w.Write([]byte(r.RequestURI))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	jar := NewCookieJar()
+	client.SetCookieJar(jar)
+
+	// 接続先は--host（RawOrigin）で、URLはリクエストターゲットのabsolute-formになる
+	response, err := client.SendRequest(context.Background(), &config.ProcessedRequest{
+		Method:    "GET",
+		URL:       "http://other.test/admin",
+		Raw:       "GET http://other.test/admin HTTP/1.1\r\nHost: other.test\r\nConnection: close\r\n\r\n",
+		RawOrigin: server.URL,
+	})
+	if err != nil {
+		t.Fatalf("SendRequest returned error: %v", err)
+	}
+	if response.StatusCode != http.StatusOK || response.Body != "http://other.test/admin" {
+		t.Errorf("Unexpected response: %d %q", response.StatusCode, response.Body)
+	}
+	if cookies := jar.Cookies(&url.URL{Scheme: "http", Host: "other.test", Path: "/admin"}); len(cookies) != 1 {
+		t.Errorf("Expected the cookie to be stored for the absolute-form URL, got %v", cookies)
+	}
+}
+
+func TestSendRequestWithTransferEncodingSendsBodyAsBuilt(t *testing.T) {
+	// サーバーはchunkedのボディを復元し、受け取った内容を返す
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "%v %d %q %v", r.TransferEncoding, r.ContentLength, body, r.Trailer)
+	}))
+	defer server.Close()
+
+	chunkedBody := "4;ext=1\r\nWiki\r\n5\r\npedia\r\n0\r\nX-Sum: 9\r\n\r\n"
+	tests := []struct {
+		name    string
+		request *config.ProcessedRequest
+	}{
+		{
+			name:    "http client",
+			request: &config.ProcessedRequest{Method: "POST", URL: server.URL + "/upload", Headers: map[string]string{"Transfer-Encoding": "chunked"}, Body: chunkedBody},
+		},
+		{
+			name:    "fragment",
+			request: &config.ProcessedRequest{Method: "POST", URL: server.URL + "/upload#x", Headers: map[string]string{"Transfer-Encoding": "chunked"}, Body: chunkedBody},
+		},
+		{
+			name: "raw request target",
+			request: &config.ProcessedRequest{Method: "POST", URL: server.URL + "/upload", RawRequestTarget: "/upload",
+				Headers: map[string]string{"transfer-encoding": "chunked"}, Body: chunkedBody},
+		},
+	}
+
+	client, err := NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ボディは構築済みのまま送信し、Content-Lengthは追加しない
+			rendered, err := client.RenderRequest(tt.request)
+			if err != nil {
+				t.Fatalf("RenderRequest returned error: %v", err)
+			}
+			if strings.Contains(strings.ToLower(string(rendered)), "content-length") || !strings.HasSuffix(string(rendered), "\r\n\r\n"+chunkedBody) {
+				t.Errorf("Expected the chunked body without Content-Length, got %q", rendered)
+			}
+
+			response, err := client.SendRequest(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("SendRequest returned error: %v", err)
+			}
+			if want := `[chunked] -1 "Wikipedia" map[X-Sum:[9]]`; response.Body != want {
+				t.Errorf("Expected %s, got %s", want, response.Body)
+			}
+		})
+	}
+}
+
 func TestSendRequestWithTimeout(t *testing.T) {
 	// 遅いレスポンスを返すテストサーバー
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,386 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// netscapeCookieHeader はNetscape形式のクッキーファイルの先頭行
+const netscapeCookieHeader = "# Netscape HTTP Cookie File"
+
+// httpOnlyPrefix はHttpOnlyのクッキーを表す行の接頭辞（curlと同じ形式）
+const httpOnlyPrefix = "#HttpOnly_"
+
+// CookieJar は実行中の全リクエストで共有するクッキージャー。
+// http.CookieJarを実装し、Netscape形式のファイルに読み書きできる。
+// テスト対象のホストに送ることを前提とするため、Public Suffix Listによる制限は行わない。
+type CookieJar struct {
+	mu      sync.Mutex
+	entries map[cookieKey]*cookieEntry
+	seq     int // 作成順（同じパス長のクッキーの送信順に使用）
+}
+
+// cookieKey はクッキーを一意に識別するキー
+type cookieKey struct {
+	domain string
+	path   string
+	name   string
+}
+
+// cookieEntry はジャーに保存されたクッキー
+type cookieEntry struct {
+	name     string
+	value    string
+	domain   string // 先頭のドットを除いたドメイン
+	path     string
+	hostOnly bool      // Domain属性がなく、設定したホストにのみ送信する
+	secure   bool      // HTTPSでのみ送信する
+	httpOnly bool      // ファイルへの保存時に#HttpOnly_を付ける
+	expires  time.Time // ゼロ値はセッションクッキー
+	seq      int
+}
+
+// NewCookieJar は空のクッキージャーを作成
+func NewCookieJar() *CookieJar {
+	return &CookieJar{entries: make(map[cookieKey]*cookieEntry)}
+}
+
+// LoadCookieJar はNetscape形式のファイルからクッキージャーを作成する。
+// ファイルが存在しない場合は空のジャーを返す。期限切れのクッキーは読み込まない。
+func LoadCookieJar(path string) (*CookieJar, error) {
+	jar := NewCookieJar()
+
+	// The CLI intentionally reads the user-specified cookie jar.
+	file, err := os.Open(path) // #nosec G304
+	if errors.Is(err, os.ErrNotExist) {
+		return jar, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cookie jar: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		if httpOnly {
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		} else if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("cookie jar %s:%d: expected 7 tab-separated fields, got %d", path, lineNumber, len(fields))
+		}
+		expiresUnix, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cookie jar %s:%d: invalid expiry %q", path, lineNumber, fields[4])
+		}
+
+		entry := &cookieEntry{
+			domain:   strings.TrimPrefix(strings.ToLower(fields[0]), "."),
+			hostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			path:     fields[2],
+			secure:   strings.EqualFold(fields[3], "TRUE"),
+			name:     fields[5],
+			value:    fields[6],
+			httpOnly: httpOnly,
+		}
+		if expiresUnix > 0 {
+			entry.expires = time.Unix(expiresUnix, 0)
+			if !entry.expires.After(now) {
+				continue
+			}
+		}
+		jar.store(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cookie jar: %w", err)
+	}
+	return jar, nil
+}
+
+// Save はクッキーをNetscape形式でファイルに書き出す。セッションクッキーは有効期限0として保存する。
+func (j *CookieJar) Save(path string) error {
+	j.mu.Lock()
+	entries := j.sortedEntries(time.Now())
+	j.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString(netscapeCookieHeader + "\n")
+	for _, entry := range entries {
+		domain := entry.domain
+		includeSubdomains := "FALSE"
+		if !entry.hostOnly {
+			domain = "." + domain
+			includeSubdomains = "TRUE"
+		}
+		if entry.httpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+		var expires int64
+		if !entry.expires.IsZero() {
+			expires = entry.expires.Unix()
+		}
+		fmt.Fprintf(&b, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, includeSubdomains, entry.path, strings.ToUpper(strconv.FormatBool(entry.secure)), expires, entry.name, entry.value)
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write cookie jar: %w", err)
+	}
+	return nil
+}
+
+// SetCookies はレスポンスで受け取ったクッキーを保存する（http.CookieJarの実装）
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	host := strings.ToLower(u.Hostname())
+	now := time.Now()
+	for _, cookie := range cookies {
+		entry := &cookieEntry{
+			name:     cookie.Name,
+			value:    cookie.Value,
+			domain:   host,
+			path:     cookie.Path,
+			hostOnly: true,
+			secure:   cookie.Secure,
+			httpOnly: cookie.HttpOnly,
+		}
+		if cookie.Domain != "" {
+			domain := strings.TrimPrefix(strings.ToLower(cookie.Domain), ".")
+			if !domainMatch(host, domain) {
+				continue // 他のドメインのクッキーは受け付けない
+			}
+			entry.domain = domain
+			entry.hostOnly = false
+		}
+		if !strings.HasPrefix(entry.path, "/") {
+			entry.path = defaultCookiePath(u.Path)
+		}
+
+		switch {
+		case cookie.MaxAge < 0:
+			entry.expires = now // Max-Age=0以下は削除
+		case cookie.MaxAge > 0:
+			entry.expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		case !cookie.Expires.IsZero():
+			entry.expires = cookie.Expires
+		}
+
+		if !entry.expires.IsZero() && !entry.expires.After(now) {
+			delete(j.entries, entry.key())
+			continue
+		}
+		j.store(entry)
+	}
+}
+
+// Cookies はURLに送信するクッキーを返す（http.CookieJarの実装）。
+// パスの長いクッキー、作成の早いクッキーの順に並ぶ。
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	host := strings.ToLower(u.Hostname())
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	var cookies []*http.Cookie
+	for _, entry := range j.sortedEntries(time.Now()) {
+		if entry.hostOnly && host != entry.domain || !entry.hostOnly && !domainMatch(host, entry.domain) {
+			continue
+		}
+		if !pathMatch(path, entry.path) {
+			continue
+		}
+		if entry.secure && u.Scheme != "https" {
+			continue
+		}
+		cookies = append(cookies, &http.Cookie{Name: entry.name, Value: entry.value, Domain: entry.domain, Path: entry.path})
+	}
+	return cookies
+}
+
+// store は作成順を引き継ぎつつクッキーを保存する。呼び出し側でロックを取得すること。
+func (j *CookieJar) store(entry *cookieEntry) {
+	key := entry.key()
+	if existing, ok := j.entries[key]; ok {
+		entry.seq = existing.seq
+	} else {
+		j.seq++
+		entry.seq = j.seq
+	}
+	j.entries[key] = entry
+}
+
+// sortedEntries は期限切れを除いたクッキーを送信順に返す。呼び出し側でロックを取得すること。
+func (j *CookieJar) sortedEntries(now time.Time) []*cookieEntry {
+	entries := make([]*cookieEntry, 0, len(j.entries))
+	for key, entry := range j.entries {
+		if !entry.expires.IsZero() && !entry.expires.After(now) {
+			delete(j.entries, key)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		if len(entries[a].path) != len(entries[b].path) {
+			return len(entries[a].path) > len(entries[b].path)
+		}
+		return entries[a].seq < entries[b].seq
+	})
+	return entries
+}
+
+func (e *cookieEntry) key() cookieKey {
+	return cookieKey{domain: e.domain, path: e.path, name: e.name}
+}
+
+// domainMatch はhostがdomainまたはそのサブドメインかを返す
+func domainMatch(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// pathMatch はリクエストパスがクッキーのパスに含まれるかを返す（RFC 6265 5.1.4）
+func pathMatch(requestPath, cookiePath string) bool {
+	if requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath はPath属性がない場合のパスを返す（RFC 6265 5.1.4）
+func defaultCookiePath(requestPath string) string {
+	if !strings.HasPrefix(requestPath, "/") {
+		return "/"
+	}
+	last := strings.LastIndex(requestPath, "/")
+	if last == 0 {
+		return "/"
+	}
+	return requestPath[:last]
+}
+
+// cookieRecorderKey はcookieRecorderをコンテキストに設定するキー
+type cookieRecorderKey struct{}
+
+// cookieRecorder は1件のリクエストで送受信したクッキーを記録する。
+// リダイレクトを含め、送信したクッキーは最初のリクエストの分、受信したクッキーは全てのレスポンスの分を記録する。
+type cookieRecorder struct {
+	sent     []config.Cookie
+	received []config.Cookie
+	started  bool
+}
+
+// cookieTransport はクッキージャーのクッキーを付けてリクエストを送信し、受け取ったクッキーを保存する。
+// http.Client.Jarと異なり、リクエスト定義で明示したCookieヘッダーのクッキーは上書きしない。
+type cookieTransport struct {
+	base http.RoundTripper
+	jar  *CookieJar
+}
+
+func (t *cookieTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder, _ := req.Context().Value(cookieRecorderKey{}).(*cookieRecorder)
+
+	cookieHeader, sent := mergeCookieHeader(req.Header.Get("Cookie"), t.jar.Cookies(req.URL))
+	if len(sent) > 0 {
+		// RoundTripperは受け取ったリクエストを変更してはならないため複製する
+		req = req.Clone(req.Context())
+		req.Header.Set("Cookie", cookieHeader)
+	}
+	if recorder != nil && !recorder.started {
+		recorder.started = true
+		recorder.sent = sent
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	received := resp.Cookies()
+	t.jar.SetCookies(req.URL, received)
+	if recorder != nil {
+		recorder.received = append(recorder.received, receivedCookies(req.URL, received)...)
+	}
+	return resp, nil
+}
+
+// mergeCookieHeader は既存のCookieヘッダーにジャーのクッキーを追加する。
+// 既存のヘッダーと同じ名前のクッキーは追加しない。追加したクッキーも返す。
+func mergeCookieHeader(existing string, cookies []*http.Cookie) (string, []config.Cookie) {
+	names := make(map[string]bool)
+	for _, part := range strings.Split(existing, ";") {
+		if name, _, _ := strings.Cut(strings.TrimSpace(part), "="); name != "" {
+			names[name] = true
+		}
+	}
+
+	header := existing
+	var sent []config.Cookie
+	for _, cookie := range cookies {
+		if names[cookie.Name] {
+			continue
+		}
+		names[cookie.Name] = true
+		if header != "" {
+			header += "; "
+		}
+		header += cookie.Name + "=" + cookie.Value
+		sent = append(sent, config.Cookie{Name: cookie.Name, Value: cookie.Value, Domain: cookie.Domain, Path: cookie.Path})
+	}
+	return header, sent
+}
+
+// receivedCookies はSet-Cookieのクッキーを記録用に変換する。Domain属性がない場合は送信先のホストを記録する。
+func receivedCookies(u *url.URL, cookies []*http.Cookie) []config.Cookie {
+	received := make([]config.Cookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		domain := strings.TrimPrefix(cookie.Domain, ".")
+		if domain == "" {
+			domain = u.Hostname()
+		}
+		received = append(received, config.Cookie{Name: cookie.Name, Value: cookie.Value, Domain: domain, Path: cookie.Path})
+	}
+	return received
+}
+
+// withCookieRecorder はクッキージャーが有効な場合に、送受信したクッキーを記録するコンテキストを返す
+func (c *Client) withCookieRecorder(ctx context.Context) (context.Context, *cookieRecorder) {
+	if c.jar == nil {
+		return ctx, nil
+	}
+	recorder := &cookieRecorder{}
+	return context.WithValue(ctx, cookieRecorderKey{}, recorder), recorder
+}
+
+// recordCookies は記録したクッキーをレスポンスデータに設定する
+func (r *cookieRecorder) recordCookies(responseData *config.ResponseData) {
+	if r == nil {
+		return
+	}
+	responseData.CookiesSent = r.sent
+	responseData.CookiesReceived = r.received
+}
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+func cookieNames(cookies []*http.Cookie) string {
+	names := make([]string, len(cookies))
+	for i, cookie := range cookies {
+		names[i] = cookie.Name + "=" + cookie.Value
+	}
+	return strings.Join(names, "; ")
+}
+
+func TestCookieJar_Matching(t *testing.T) {
+	jar := NewCookieJar()
+	origin, _ := url.Parse("https://www.example.com/app/login")
+	jar.SetCookies(origin, []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "2", Domain: ".example.com", Path: "/"},
+		{Name: "secure", Value: "3", Path: "/", Secure: true},
+		{Name: "admin", Value: "4", Path: "/admin"},
+		{Name: "other", Value: "5", Domain: "other.test"},
+		{Name: "expired", Value: "6", Expires: time.Now().Add(-time.Hour)},
+	})
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://www.example.com/app/page", want: "host=1; domain=2; secure=3"},
+		{url: "http://www.example.com/app", want: "host=1; domain=2"},
+		{url: "https://api.example.com/app/page", want: "domain=2"},
+		{url: "https://www.example.com/admin/users", want: "admin=4; domain=2; secure=3"},
+		{url: "https://www.example.com/administrator", want: "domain=2; secure=3"},
+		{url: "https://example.org/", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, _ := url.Parse(tt.url)
+			if got := cookieNames(jar.Cookies(u)); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	// Max-Age=0で削除し、同じキーは上書きする
+	jar.SetCookies(origin, []*http.Cookie{{Name: "host", MaxAge: -1}, {Name: "domain", Value: "updated", Domain: "example.com", Path: "/"}})
+	u, _ := url.Parse("http://www.example.com/app/page")
+	if got := cookieNames(jar.Cookies(u)); got != "domain=updated" {
+		t.Errorf("Expected deletion and update, got %q", got)
+	}
+}
+
+func TestCookieJar_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	future := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	jar := NewCookieJar()
+	origin, _ := url.Parse("https://www.example.com/")
+	jar.SetCookies(origin, []*http.Cookie{
+		{Name: "session", Value: "abc", HttpOnly: true},
+		{Name: "pref", Value: "dark", Domain: "example.com", Path: "/", Secure: true, Expires: future},
+	})
+	if err := jar.Save(path); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	want := netscapeCookieHeader + "\n" +
+		"#HttpOnly_www.example.com\tFALSE\t/\tFALSE\t0\tsession\tabc\n" +
+		".example.com\tTRUE\t/\tTRUE\t" + strconv.FormatInt(future.Unix(), 10) + "\tpref\tdark\n"
+	if string(data) != want {
+		t.Errorf("Unexpected file:\n%s\nwant:\n%s", data, want)
+	}
+
+	loaded, err := LoadCookieJar(path)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	u, _ := url.Parse("https://sub.example.com/")
+	if got := cookieNames(loaded.Cookies(u)); got != "pref=dark" {
+		t.Errorf("Expected domain cookie for subdomain, got %q", got)
+	}
+	if got := cookieNames(loaded.Cookies(origin)); got != "session=abc; pref=dark" {
+		t.Errorf("Expected both cookies for origin, got %q", got)
+	}
+}
+
+func TestLoadCookieJar(t *testing.T) {
+	dir := t.TempDir()
+
+	jar, err := LoadCookieJar(filepath.Join(dir, "missing.txt"))
+	if err != nil || len(jar.Cookies(&url.URL{Scheme: "http", Host: "example.com", Path: "/"})) != 0 {
+		t.Errorf("Expected an empty jar for a missing file, got %v", err)
+	}
+
+	expired := filepath.Join(dir, "expired.txt")
+	_ = os.WriteFile(expired, []byte("# comment\n\nexample.com\tFALSE\t/\tFALSE\t1\told\tx\nexample.com\tFALSE\t/\tFALSE\t0\tnew\ty\n"), 0600)
+	jar, err = LoadCookieJar(expired)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := cookieNames(jar.Cookies(&url.URL{Scheme: "http", Host: "example.com", Path: "/"})); got != "new=y" {
+		t.Errorf("Expected expired cookies to be skipped, got %q", got)
+	}
+
+	invalid := filepath.Join(dir, "invalid.txt")
+	_ = os.WriteFile(invalid, []byte(netscapeCookieHeader+"\nexample.com\tFALSE\t/\n"), 0600)
+	if _, err := LoadCookieJar(invalid); err == nil || !strings.Contains(err.Error(), "invalid.txt:2: expected 7 tab-separated fields") {
+		t.Errorf("Expected a line error, got %v", err)
+	}
+}
+
+func TestSendRequestWithCookieJar(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.URL.Path+" "+r.Header.Get("Cookie"))
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/"})
+			http.Redirect(w, r, "/home", http.StatusFound)
+		case "/home":
+			http.SetCookie(w, &http.Cookie{Name: "seen", Value: "1", Path: "/"})
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetCookieJar(NewCookieJar())
+
+	// リダイレクト先にもログインで受け取ったクッキーが送信される
+	response, err := client.SendRequest(context.Background(), &config.ProcessedRequest{Method: "GET", URL: server.URL + "/login"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(response.CookiesSent) != 0 {
+		t.Errorf("Expected no cookies sent on the first request, got %v", response.CookiesSent)
+	}
+	if len(response.CookiesReceived) != 2 || response.CookiesReceived[0].Name != "session" || response.CookiesReceived[0].Domain != "127.0.0.1" {
+		t.Errorf("Expected cookies from both hops, got %+v", response.CookiesReceived)
+	}
+
+	requests := []*config.ProcessedRequest{
+		// 通常のパス（明示したCookieヘッダーの同名のクッキーは上書きしない）
+		{Method: "GET", URL: server.URL + "/api", Headers: map[string]string{"Cookie": "seen=override"}},
+		// フラグメントを含むパス
+		{Method: "GET", URL: server.URL + "/fragment#top"},
+		// raw request target
+		{Method: "GET", URL: server.URL + "/raw?x", RawRequestTarget: "/raw?x"},
+	}
+	for _, request := range requests {
+		response, err := client.SendRequest(context.Background(), request)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", request.URL, err)
+		}
+		if len(response.CookiesSent) == 0 || response.CookiesSent[0] != (config.Cookie{Name: "session", Value: "s1", Domain: "127.0.0.1", Path: "/"}) {
+			t.Errorf("Unexpected cookies sent for %s: %+v", request.URL, response.CookiesSent)
+		}
+	}
+
+	want := []string{
+		"/login ",
+		"/home session=s1",
+		"/api seen=override; session=s1",
+		"/fragment#top session=s1; seen=1",
+		"/raw session=s1; seen=1",
+	}
+	if strings.Join(received, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected cookies received by server:\n%s\nwant:\n%s", strings.Join(received, "\n"), strings.Join(want, "\n"))
+	}
+}
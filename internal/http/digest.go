@@ -0,0 +1,257 @@
+package http
+
+import (
+	"context"
+	"crypto/md5" // #nosec G501 -- RFC 7616 requires MD5 for servers that only offer it
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// sendWithDigestAuth はリクエストを送信し、Digest認証のチャレンジ（401）を受けた場合は
+// Authorizationヘッダーを付けて1回だけ再送する
+func (c *Client) sendWithDigestAuth(ctx context.Context, processedRequest *config.ProcessedRequest) (*config.ResponseData, error) {
+	response, err := c.send(ctx, processedRequest)
+	if err != nil || response.StatusCode != http.StatusUnauthorized {
+		return response, err
+	}
+
+	challenge, ok := findDigestChallenge(http.Header(response.Headers).Values("Www-Authenticate"))
+	if !ok {
+		return response, nil
+	}
+	cnonce, err := newCnonce()
+	if err != nil {
+		return nil, fmt.Errorf("failed to answer digest challenge: %w", err)
+	}
+	authorization := challenge.authorize(processedRequest.DigestAuth, processedRequest.Method, digestURI(processedRequest), processedRequest.Body, cnonce)
+
+	retry := *processedRequest
+	retry.Headers = make(map[string]string, len(processedRequest.Headers)+1)
+	for key, value := range processedRequest.Headers {
+		if !strings.EqualFold(key, "Authorization") {
+			retry.Headers[key] = value
+		}
+	}
+	retry.Headers["Authorization"] = authorization
+	return c.send(ctx, &retry)
+}
+
+// digestURI はDigest認証のuriパラメータ（リクエストターゲット）を返す
+func digestURI(processedRequest *config.ProcessedRequest) string {
+	if processedRequest.RawRequestTarget != "" {
+		return processedRequest.RawRequestTarget
+	}
+	parsedURL, err := url.Parse(processedRequest.URL)
+	if err != nil {
+		return "/"
+	}
+	return parsedURL.RequestURI()
+}
+
+// digestChallenge はWWW-AuthenticateヘッダーのDigestチャレンジ
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string // MD5, MD5-sess, SHA-256, SHA-256-sess
+	qop       string // auth, auth-int（指定がなければ空）
+	userhash  bool
+}
+
+// findDigestChallenge はWWW-Authenticateヘッダーから対応しているDigestチャレンジを探す
+func findDigestChallenge(values []string) (*digestChallenge, bool) {
+	for _, value := range values {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(value), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+		params := parseAuthParams(rest)
+		challenge := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+			userhash:  strings.EqualFold(params["userhash"], "true"),
+		}
+		if challenge.algorithm == "" {
+			challenge.algorithm = "MD5"
+		}
+		if challenge.nonce == "" || challenge.newHash() == nil {
+			continue
+		}
+
+		// authを優先し、auth-intのみの場合はボディを含めて計算する
+		for _, qop := range strings.Split(params["qop"], ",") {
+			switch strings.TrimSpace(qop) {
+			case "auth":
+				challenge.qop = "auth"
+			case "auth-int":
+				if challenge.qop == "" {
+					challenge.qop = "auth-int"
+				}
+			}
+		}
+		return challenge, true
+	}
+	return nil, false
+}
+
+// parseAuthParams はカンマ区切りのauth-param（key=value、key="quoted"）を解析する
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s != "" {
+		s = strings.TrimLeft(s, " \t,")
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var value strings.Builder
+		if strings.HasPrefix(s, `"`) {
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			s = s[min(i+1, len(s)):]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value.WriteString(strings.TrimSpace(s[:end]))
+			s = s[end:]
+		}
+		params[key] = value.String()
+	}
+	return params
+}
+
+// newHash はalgorithmに対応するハッシュ関数を返す。対応していない場合はnilを返す。
+func (c *digestChallenge) newHash() func() hash.Hash {
+	switch strings.ToUpper(strings.TrimSuffix(strings.ToLower(c.algorithm), "-sess")) {
+	case "MD5":
+		return md5.New
+	case "SHA-256":
+		return sha256.New
+	default:
+		return nil
+	}
+}
+
+// newCnonce はクライアントが生成するランダムなnonceを返す
+func newCnonce() (string, error) {
+	cnonce := make([]byte, 16)
+	if _, err := rand.Read(cnonce); err != nil {
+		return "", fmt.Errorf("failed to generate cnonce: %w", err)
+	}
+	return hex.EncodeToString(cnonce), nil
+}
+
+// authorize はチャレンジに対するAuthorizationヘッダーの値を計算する（RFC 7616）
+func (c *digestChallenge) authorize(credentials *config.DigestAuth, method, uri, body, cnonce string) string {
+	newHash := c.newHash()
+	h := func(s string) string {
+		digest := newHash()
+		digest.Write([]byte(s))
+		return hex.EncodeToString(digest.Sum(nil))
+	}
+
+	const nc = "00000001"
+
+	ha1 := h(credentials.Username + ":" + c.realm + ":" + credentials.Password)
+	if strings.HasSuffix(strings.ToLower(c.algorithm), "-sess") {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+	if c.qop == "auth-int" {
+		ha2 = h(method + ":" + uri + ":" + h(body))
+	}
+
+	var response string
+	if c.qop != "" {
+		response = h(ha1 + ":" + c.nonce + ":" + nc + ":" + cnonce + ":" + c.qop + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + c.nonce + ":" + ha2)
+	}
+
+	// quoted-stringで表せないユーザー名はusername*（RFC 8187）で送る
+	username := "username=" + quoteString(credentials.Username)
+	if c.userhash {
+		username = "username=" + quoteString(h(credentials.Username+":"+c.realm))
+	} else if !isQuotableUsername(credentials.Username) {
+		username = "username*=" + encodeExtValue(credentials.Username)
+	}
+
+	params := []string{
+		username,
+		"realm=" + quoteString(c.realm),
+		"nonce=" + quoteString(c.nonce),
+		"uri=" + quoteString(uri),
+		"algorithm=" + c.algorithm,
+		"response=" + quoteString(response),
+	}
+	if c.opaque != "" {
+		params = append(params, "opaque="+quoteString(c.opaque))
+	}
+	if c.qop != "" {
+		params = append(params, "qop="+c.qop, "nc="+nc, "cnonce="+quoteString(cnonce))
+	}
+	if c.userhash {
+		params = append(params, "userhash=true")
+	}
+	return "Digest " + strings.Join(params, ", ")
+}
+
+// quoteString はRFC 7230のquoted-stringとして値を引用符で囲む。
+// バックスラッシュと二重引用符のみをエスケープし、それ以外のバイトはそのまま残す。
+func quoteString(value string) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		if value[i] == '"' || value[i] == '\\' {
+			builder.WriteByte('\\')
+		}
+		builder.WriteByte(value[i])
+	}
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+// isQuotableUsername はユーザー名をASCIIのquoted-stringで送れるかを返す
+func isQuotableUsername(username string) bool {
+	for i := 0; i < len(username); i++ {
+		if username[i] < 0x20 || username[i] >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeExtValue は値をRFC 8187のext-value（文字セットにUTF-8を指定したパーセントエンコード）に変換する
+func encodeExtValue(value string) string {
+	const attrChars = "!#$&+-.^_`|~"
+	var builder strings.Builder
+	builder.WriteString("UTF-8''")
+	for i := 0; i < len(value); i++ {
+		b := value[i]
+		if ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9') || strings.IndexByte(attrChars, b) >= 0 {
+			builder.WriteByte(b)
+			continue
+		}
+		fmt.Fprintf(&builder, "%%%02X", b)
+	}
+	return builder.String()
+}
@@ -0,0 +1,176 @@
+package http
+
+import (
+	"context"
+	"crypto/md5" // #nosec G501 -- the test server verifies MD5 digests
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+func TestDigestChallenge_RFC7616Examples(t *testing.T) {
+	// RFC 7616 3.9.1の例
+	header := `Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=%s, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`
+	credentials := &config.DigestAuth{Username: "Mufasa", Password: "Circle of Life"}
+	cnonce := "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+
+	tests := []struct {
+		algorithm string
+		response  string
+	}{
+		{algorithm: "MD5", response: "8ca523f5e9506fed4657c9700eebdbec"},
+		{algorithm: "SHA-256", response: "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			challenge, ok := findDigestChallenge([]string{`Basic realm="x"`, strings.Replace(header, "%s", tt.algorithm, 1)})
+			if !ok {
+				t.Fatalf("Expected a digest challenge")
+			}
+			if challenge.qop != "auth" || challenge.opaque != "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS" {
+				t.Errorf("Unexpected challenge: %+v", challenge)
+			}
+			authorization := challenge.authorize(credentials, "GET", "/dir/index.html", "", cnonce)
+			for _, want := range []string{`username="Mufasa"`, `uri="/dir/index.html"`, `response="` + tt.response + `"`, "qop=auth", "nc=00000001", `cnonce="` + cnonce + `"`} {
+				if !strings.Contains(authorization, want) {
+					t.Errorf("Expected %q in %s", want, authorization)
+				}
+			}
+		})
+	}
+}
+
+func TestDigestChallenge_QuotingAndNonASCIIUsername(t *testing.T) {
+	md5Hex := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	challenge, ok := findDigestChallenge([]string{`Digest realm="a \"b\" \\c", qop="auth", nonce="n/é"`})
+	if !ok {
+		t.Fatalf("Expected a digest challenge")
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		userhash bool
+		want     string
+	}{
+		{name: "ascii", username: "Mufasa", want: `username="Mufasa"`},
+		{name: "quote and backslash", username: `a"b\c`, want: `username="a\"b\\c"`},
+		{name: "non-ascii", username: "Jäsøn Doe", want: `username*=UTF-8''J%C3%A4s%C3%B8n%20Doe`},
+		{name: "non-ascii with userhash", username: "Jäsøn Doe", userhash: true, want: `username="` + md5Hex(`Jäsøn Doe:a "b" \c`) + `"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := *challenge
+			c.userhash = tt.userhash
+			authorization := c.authorize(&config.DigestAuth{Username: tt.username, Password: "pw"}, "GET", "/", "", "cn")
+
+			// 応答はエスケープしていないUTF-8のユーザー名とrealmから計算する
+			ha1 := md5Hex(tt.username + `:a "b" \c:pw`)
+			response := md5Hex(ha1 + ":n/é:00000001:cn:auth:" + md5Hex("GET:/"))
+			for _, want := range []string{tt.want, `realm="a \"b\" \\c"`, `nonce="n/é"`, `response="` + response + `"`} {
+				if !strings.Contains(authorization, want) {
+					t.Errorf("Expected %q in %s", want, authorization)
+				}
+			}
+		})
+	}
+}
+
+func TestFindDigestChallenge_Unsupported(t *testing.T) {
+	if _, ok := findDigestChallenge([]string{`Digest realm="x", nonce="n", algorithm=SHA-512-256`}); ok {
+		t.Errorf("Expected unsupported algorithm to be skipped")
+	}
+	if _, ok := findDigestChallenge([]string{`Bearer realm="x"`}); ok {
+		t.Errorf("Expected non-digest challenge to be skipped")
+	}
+}
+
+func TestParseAuthParams(t *testing.T) {
+	params := parseAuthParams(`realm="a, \"b\"", qop="auth,auth-int", stale=false,nonce=abc`)
+	want := map[string]string{"realm": `a, "b"`, "qop": "auth,auth-int", "stale": "false", "nonce": "abc"}
+	for key, value := range want {
+		if params[key] != value {
+			t.Errorf("Expected %s=%q, got %q", key, value, params[key])
+		}
+	}
+}
+
+func TestSendRequestWithDigestAuth(t *testing.T) {
+	md5Hex := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		params := parseAuthParams(strings.TrimPrefix(r.Header.Get("Authorization"), "Digest "))
+		ha1 := md5Hex("admin:test:secret")
+		ha2 := md5Hex(r.Method + ":" + r.RequestURI)
+		expected := md5Hex(ha1 + ":server-nonce:" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+		if params["uri"] != r.RequestURI || params["response"] != expected {
+			w.Header().Set("WWW-Authenticate", `Digest realm="test", qop="auth", nonce="server-nonce"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("welcome"))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		request  *config.ProcessedRequest
+		status   int
+		attempts int
+	}{
+		{
+			name:     "valid credentials",
+			request:  &config.ProcessedRequest{Method: "GET", URL: server.URL + "/admin?x=1", DigestAuth: &config.DigestAuth{Username: "admin", Password: "secret"}},
+			status:   http.StatusOK,
+			attempts: 2,
+		},
+		{
+			name:     "raw request target",
+			request:  &config.ProcessedRequest{Method: "GET", URL: server.URL + "/admin/%2e%2e", RawRequestTarget: "/admin/%2e%2e", DigestAuth: &config.DigestAuth{Username: "admin", Password: "secret"}},
+			status:   http.StatusOK,
+			attempts: 2,
+		},
+		{
+			name:     "wrong password is retried only once",
+			request:  &config.ProcessedRequest{Method: "GET", URL: server.URL + "/admin", DigestAuth: &config.DigestAuth{Username: "admin", Password: "wrong"}},
+			status:   http.StatusUnauthorized,
+			attempts: 2,
+		},
+		{
+			name:     "without credentials",
+			request:  &config.ProcessedRequest{Method: "GET", URL: server.URL + "/admin"},
+			status:   http.StatusUnauthorized,
+			attempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts = 0
+			response, err := client.SendRequest(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.status || attempts != tt.attempts {
+				t.Errorf("Expected status %d after %d attempts, got %d after %d", tt.status, tt.attempts, response.StatusCode, attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// oauth2ExpiryDelta は有効期限の直前に送信したリクエストが失効しないよう、早めに更新するための猶予
+const oauth2ExpiryDelta = 10 * time.Second
+
+// maxTokenResponseSize はトークンエンドポイントのレスポンスとして読み込む最大サイズ
+const maxTokenResponseSize = 1 << 20
+
+// OAuth2TokenPlaceholder はドライランやエクスポートで、送信時に取得するトークンの代わりに出力する値
+const OAuth2TokenPlaceholder = "<oauth2-token>"
+
+// oauth2Token はトークンエンドポイントから取得したアクセストークン
+type oauth2Token struct {
+	accessToken string
+	expiry      time.Time // expires_inがない場合はゼロ値（401を受けるまで使用する）
+}
+
+// tokenCache は設定ごとに取得したトークンを保持する
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[config.OAuth2Auth]*oauth2Token
+}
+
+// sendWithOAuth2 はキャッシュしたトークンをAuthorizationヘッダーに設定して送信する。
+// 401を受けた場合はトークンを取得し直して1回だけ再送する。
+func (c *Client) sendWithOAuth2(ctx context.Context, processedRequest *config.ProcessedRequest) (*config.ResponseData, error) {
+	token, err := c.oauth2Token(ctx, processedRequest.OAuth2, processedRequest.TLS, "")
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.send(ctx, withBearerToken(processedRequest, token))
+	if err != nil || response.StatusCode != http.StatusUnauthorized {
+		return response, err
+	}
+
+	token, err = c.oauth2Token(ctx, processedRequest.OAuth2, processedRequest.TLS, token)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(ctx, withBearerToken(processedRequest, token))
+}
+
+// PrepareOAuth2 はトークンを取得してキャッシュする。
+// リクエストの送信前に呼び出すと、資格情報やtoken-urlの誤りを1件のエラーとして検出できる。
+func (c *Client) PrepareOAuth2(ctx context.Context, auth *config.OAuth2Auth, tlsOptions *config.TLSConfig) error {
+	_, err := c.oauth2Token(ctx, auth, tlsOptions, "")
+	return err
+}
+
+// withBearerToken はAuthorizationヘッダーをトークンに置き換えたリクエストのコピーを返す
+func withBearerToken(processedRequest *config.ProcessedRequest, token string) *config.ProcessedRequest {
+	request := *processedRequest
+	request.Headers = make(map[string]string, len(processedRequest.Headers)+1)
+	for key, value := range processedRequest.Headers {
+		if !strings.EqualFold(key, "Authorization") {
+			request.Headers[key] = value
+		}
+	}
+	request.Headers["Authorization"] = "Bearer " + token
+	return &request
+}
+
+// oauth2Token はキャッシュしたトークンを返す。キャッシュがない場合、有効期限が切れた場合、
+// キャッシュがstale（401を受けたトークン）と同じ場合はトークンエンドポイントから取得し直す。
+// 並行するワーカーが同時に取得しないよう、取得中はロックを保持する。
+// トークンエンドポイントにはリクエストと同じTLS設定（meta.tls）で接続する。
+func (c *Client) oauth2Token(ctx context.Context, auth *config.OAuth2Auth, tlsOptions *config.TLSConfig, stale string) (string, error) {
+	c.tokens.mu.Lock()
+	defer c.tokens.mu.Unlock()
+
+	if token, ok := c.tokens.tokens[*auth]; ok && token.accessToken != stale &&
+		(token.expiry.IsZero() || time.Now().Before(token.expiry)) {
+		return token.accessToken, nil
+	}
+
+	token, err := c.fetchOAuth2Token(ctx, auth, tlsOptions)
+	if err != nil {
+		return "", fmt.Errorf("failed to get OAuth2 token from %s: %w", auth.TokenURL, err)
+	}
+	if c.tokens.tokens == nil {
+		c.tokens.tokens = make(map[config.OAuth2Auth]*oauth2Token)
+	}
+	c.tokens.tokens[*auth] = token
+	return token.accessToken, nil
+}
+
+// fetchOAuth2Token はclient credentialsグラント（RFC 6749 4.4）でトークンを取得する
+func (c *Client) fetchOAuth2Token(ctx context.Context, auth *config.OAuth2Auth, tlsOptions *config.TLSConfig) (*oauth2Token, error) {
+	client, err := c.clientFor(tlsOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if auth.Scope != "" {
+		form.Set("scope", auth.Scope)
+	}
+	if auth.ClientAuth == "body" {
+		form.Set("client_id", auth.ClientID)
+		form.Set("client_secret", auth.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, auth.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if auth.ClientAuth != "body" {
+		// RFC 6749 2.3.1: 資格情報はURLエンコードしてからBasic認証に使用する
+		req.SetBasicAuth(url.QueryEscape(auth.ClientID), url.QueryEscape(auth.ClientSecret))
+	}
+
+	requestedAt := time.Now()
+	resp, err := client.httpClient.Do(req) // #nosec G704 -- the token URL comes from the request definition
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTokenResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var tokenResponse struct {
+		AccessToken      string      `json:"access_token"`
+		ExpiresIn        json.Number `json:"expires_in"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	decodeErr := json.Unmarshal(body, &tokenResponse)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if decodeErr == nil && tokenResponse.Error != "" {
+			return nil, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(tokenResponse.Error+" "+tokenResponse.ErrorDescription))
+		}
+		return nil, fmt.Errorf("token endpoint returned %d", resp.StatusCode)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", decodeErr)
+	}
+	if tokenResponse.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+
+	token := &oauth2Token{accessToken: tokenResponse.AccessToken}
+	if expiresIn, err := tokenResponse.ExpiresIn.Int64(); err == nil && expiresIn > 0 {
+		token.expiry = requestedAt.Add(time.Duration(expiresIn)*time.Second - oauth2ExpiryDelta)
+	}
+	return token, nil
+}
@@ -0,0 +1,178 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// oauth2TestServer はトークンエンドポイント（/token）と、最新のトークンだけを受け付けるAPIを提供する
+type oauth2TestServer struct {
+	mu        sync.Mutex
+	issued    int
+	expiresIn interface{}
+	forms     []string
+	basicAuth []string
+	received  []string
+}
+
+func (s *oauth2TestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/token" {
+		_ = r.ParseForm()
+		user, pass, _ := r.BasicAuth()
+		s.forms = append(s.forms, r.PostForm.Encode())
+		s.basicAuth = append(s.basicAuth, user+":"+pass)
+		if r.PostForm.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"unsupported_grant_type"}`))
+			return
+		}
+		s.issued++
+		response := map[string]interface{}{"access_token": fmt.Sprintf("t%d", s.issued), "token_type": "bearer"}
+		if s.expiresIn != nil {
+			response["expires_in"] = s.expiresIn
+		}
+		_ = json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	s.received = append(s.received, r.URL.Path+" "+r.Header.Get("Authorization"))
+	if r.Header.Get("Authorization") != fmt.Sprintf("Bearer t%d", s.issued) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+}
+
+// revoke は発行済みのトークンを無効にする（次のトークンだけが受け付けられる）
+func (s *oauth2TestServer) revoke() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.issued++
+}
+
+func TestSendRequestWithOAuth2(t *testing.T) {
+	handler := &oauth2TestServer{expiresIn: 3600}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client, err := NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	auth := &config.OAuth2Auth{TokenURL: server.URL + "/token", ClientID: "app id", ClientSecret: "s&cret", Scope: "read write"}
+
+	requests := []*config.ProcessedRequest{
+		{Method: "GET", URL: server.URL + "/a", Headers: map[string]string{"authorization": "manual"}, OAuth2: auth},
+		{Method: "GET", URL: server.URL + "/b#frag", OAuth2: auth},
+		{Method: "GET", URL: server.URL + "/c?x", RawRequestTarget: "/c?x", OAuth2: auth},
+	}
+	for _, request := range requests {
+		response, err := client.SendRequest(context.Background(), request)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", request.URL, err)
+		}
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("Expected 200 for %s, got %d", request.URL, response.StatusCode)
+		}
+	}
+	if requests[0].Headers["authorization"] != "manual" {
+		t.Errorf("Expected the original request to be left unchanged, got %v", requests[0].Headers)
+	}
+
+	// トークンが失効した場合は取得し直して再送する
+	handler.revoke()
+	response, err := client.SendRequest(context.Background(), &config.ProcessedRequest{Method: "GET", URL: server.URL + "/d", OAuth2: auth})
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the request to succeed after refreshing the token, got %v %v", response, err)
+	}
+
+	want := []string{"/a Bearer t1", "/b#frag Bearer t1", "/c Bearer t1", "/d Bearer t1", "/d Bearer t3"}
+	if strings.Join(handler.received, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected requests:\n%s\nwant:\n%s", strings.Join(handler.received, "\n"), strings.Join(want, "\n"))
+	}
+	if len(handler.forms) != 2 || handler.forms[0] != "grant_type=client_credentials&scope=read+write" {
+		t.Errorf("Unexpected token requests: %v", handler.forms)
+	}
+	if handler.basicAuth[0] != "app+id:s%26cret" {
+		t.Errorf("Expected URL-encoded client credentials, got %q", handler.basicAuth[0])
+	}
+}
+
+func TestSendRequestWithOAuth2_Expiry(t *testing.T) {
+	// expires_inが猶予より短いトークンは毎回取得し直す。文字列のexpires_inも受け付ける。
+	handler := &oauth2TestServer{expiresIn: "5"}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client, err := NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	auth := &config.OAuth2Auth{TokenURL: server.URL + "/token", ClientID: "id", ClientSecret: "secret", ClientAuth: "body"}
+	for i := 0; i < 2; i++ {
+		if _, err := client.SendRequest(context.Background(), &config.ProcessedRequest{Method: "GET", URL: server.URL + "/api", OAuth2: auth}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if handler.issued != 2 {
+		t.Errorf("Expected a new token for each request, got %d tokens", handler.issued)
+	}
+	if handler.forms[0] != "client_id=id&client_secret=secret&grant_type=client_credentials" || handler.basicAuth[0] != ":" {
+		t.Errorf("Expected client credentials in the body, got %q (basic %q)", handler.forms[0], handler.basicAuth[0])
+	}
+}
+
+func TestSendRequestWithOAuth2_TokenError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"bad secret"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	auth := &config.OAuth2Auth{TokenURL: server.URL + "/token", ClientID: "id", ClientSecret: "wrong"}
+	_, err = client.SendRequest(context.Background(), &config.ProcessedRequest{Method: "GET", URL: server.URL + "/api", OAuth2: auth})
+	if err == nil || !strings.Contains(err.Error(), "token endpoint returned 401: invalid_client bad secret") {
+		t.Errorf("Expected a token error, got %v", err)
+	}
+}
+
+func TestSendRequestWithOAuth2_UsesRequestTLSOptions(t *testing.T) {
+	// トークンエンドポイントはmeta.tlsのCAでのみ検証できる
+	handler := &oauth2TestServer{}
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+	caFile := writePEM(t, t.TempDir(), "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	client, err := NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	request := &config.ProcessedRequest{
+		Method: "GET",
+		URL:    server.URL + "/api",
+		OAuth2: &config.OAuth2Auth{TokenURL: server.URL + "/token", ClientID: "id", ClientSecret: "secret"},
+		TLS:    &config.TLSConfig{CACert: caFile},
+	}
+	response, err := client.SendRequest(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.StatusCode != http.StatusOK || handler.issued != 1 {
+		t.Errorf("Expected status 200 with 1 token, got %d with %d", response.StatusCode, handler.issued)
+	}
+}
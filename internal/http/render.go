@@ -0,0 +1,88 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// RenderRequest は処理済みリクエストを、SendRequestが送信するHTTP/1.1のバイト列に変換する。
+// ソケットは一切開かない。HTTPSでHTTP/2がネゴシエートされる場合は、同等のHTTP/1.1表現を返す。
+// クッキージャーが設定されている場合は、送信時と同じくジャーのクッキーをCookieヘッダーに追加する。
+// OAuth2のトークンは取得せず、AuthorizationヘッダーにOAuth2TokenPlaceholderを出力する。
+func (c *Client) RenderRequest(processedRequest *config.ProcessedRequest) ([]byte, error) {
+	if processedRequest.Raw != "" {
+		return []byte(processedRequest.Raw), nil
+	}
+	if processedRequest.OAuth2 != nil {
+		processedRequest = withBearerToken(processedRequest, OAuth2TokenPlaceholder)
+	}
+	if processedRequest.RawRequestTarget != "" {
+		scheme, host, _, err := parseRawRequestOrigin(processedRequest.URL)
+		if err != nil {
+			return nil, err
+		}
+		if scheme != "http" && scheme != "https" {
+			return nil, fmt.Errorf("unsupported URL scheme for raw request target: %s", scheme)
+		}
+		if c.jar != nil {
+			processedRequest, _ = c.withJarCookies(processedRequest, rawRequestCookieURL(scheme, host, processedRequest.RawRequestTarget))
+		}
+		return []byte(buildRawRequestTargetRequest(processedRequest, host)), nil
+	}
+
+	req, err := newRequest(context.Background(), processedRequest)
+	if err != nil {
+		return nil, err
+	}
+	if c.jar != nil {
+		// cookieTransportと同じく、明示したCookieヘッダーのクッキーは上書きしない
+		if cookieHeader, sent := mergeCookieHeader(req.Header.Get("Cookie"), c.jar.Cookies(req.URL)); len(sent) > 0 {
+			req.Header.Set("Cookie", cookieHeader)
+		}
+	}
+
+	// フラグメントやTransfer-Encodingを含むリクエストはfragmentTransportが手動で構築する
+	if needsManualRequest(req) {
+		fullRequest, err := buildFragmentRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(fullRequest), nil
+	}
+
+	var buf bytes.Buffer
+	if c.proxy != "" && req.URL.Scheme == "http" {
+		// HTTPプロキシ経由の場合はabsolute-formのリクエストラインになる
+		err = req.WriteProxy(&buf)
+	} else {
+		err = req.Write(&buf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to render request: %w", err)
+	}
+
+	return addTransportHeaders(buf.Bytes(), req.Method, processedRequest.Headers), nil
+}
+
+// addTransportHeaders はhttp.Transportが書き込み時に追加するヘッダーを反映する
+func addTransportHeaders(wire []byte, method string, headers map[string]string) []byte {
+	// http.Transportはレスポンスの透過的な展開のためにAccept-Encoding: gzipを追加する
+	if method == "HEAD" || hasHeader(headers, "Accept-Encoding") || hasHeader(headers, "Range") {
+		return wire
+	}
+
+	headerEnd := bytes.Index(wire, []byte("\r\n\r\n"))
+	if headerEnd < 0 {
+		return wire
+	}
+	insertAt := headerEnd + 2
+
+	rendered := make([]byte, 0, len(wire)+len("Accept-Encoding: gzip\r\n"))
+	rendered = append(rendered, wire[:insertAt]...)
+	rendered = append(rendered, "Accept-Encoding: gzip\r\n"...)
+	rendered = append(rendered, wire[insertAt:]...)
+	return rendered
+}
@@ -0,0 +1,254 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// startCaptureServer はリクエストのバイト列をそのまま記録するTCPサーバーを起動する
+func startCaptureServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	captured := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		var raw strings.Builder
+		contentLength := 0
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			raw.WriteString(line)
+			if line == "\r\n" {
+				break
+			}
+			if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+				contentLength, _ = strconv.Atoi(strings.TrimSpace(value))
+			}
+		}
+		body := make([]byte, contentLength)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return
+		}
+		raw.Write(body)
+		captured <- raw.String()
+		_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"))
+	}()
+
+	return listener.Addr().String(), captured
+}
+
+func TestRenderRequestMatchesWireBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		request func(addr string) *config.ProcessedRequest
+	}{
+		{
+			name: "standard GET",
+			request: func(addr string) *config.ProcessedRequest {
+				return &config.ProcessedRequest{
+					Method:  "GET",
+					URL:     "http://" + addr + "/search?q=%3Cscript%3E",
+					Headers: map[string]string{"User-Agent": "s2req/test", "X-B": "2", "X-A": "1"},
+				}
+			},
+		},
+		{
+			name: "standard POST with default content type",
+			request: func(addr string) *config.ProcessedRequest {
+				return &config.ProcessedRequest{
+					Method:  "POST",
+					URL:     "http://" + addr + "/login",
+					Headers: map[string]string{"User-Agent": "s2req/test"},
+					Body:    "user=admin&pass=%27+OR+1%3D1",
+				}
+			},
+		},
+		{
+			name: "fragment",
+			request: func(addr string) *config.ProcessedRequest {
+				return &config.ProcessedRequest{
+					Method:  "POST",
+					URL:     "http://" + addr + "/page?x=1#section",
+					Headers: map[string]string{"User-Agent": "s2req/test", "Content-Type": "application/json"},
+					Body:    `{"a":1}`,
+				}
+			},
+		},
+		{
+			name: "raw request target",
+			request: func(addr string) *config.ProcessedRequest {
+				return &config.ProcessedRequest{
+					Method:           "GET",
+					URL:              "http://" + addr + "/%%32%65",
+					RawRequestTarget: "/%%32%65",
+					Headers:          map[string]string{"User-Agent": "s2req/test", "Host": "tenant.example.test", "X-Z": "z", "X-A": "a"},
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, captured := startCaptureServer(t)
+			client, err := NewClient(5*time.Second, "")
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+
+			rendered, err := client.RenderRequest(tt.request(addr))
+			if err != nil {
+				t.Fatalf("RenderRequest returned error: %v", err)
+			}
+
+			if _, err := client.SendRequest(context.Background(), tt.request(addr)); err != nil {
+				t.Fatalf("SendRequest returned error: %v", err)
+			}
+
+			select {
+			case wire := <-captured:
+				if string(rendered) != wire {
+					t.Errorf("Rendered bytes differ from wire bytes\nrendered: %q\nwire:     %q", rendered, wire)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("timed out waiting for captured request")
+			}
+		})
+	}
+}
+
+func TestRenderRequestIncludesJarCookies(t *testing.T) {
+	tests := []struct {
+		name    string
+		request func(addr string) *config.ProcessedRequest
+		cookie  string
+	}{
+		{
+			name: "standard GET",
+			request: func(addr string) *config.ProcessedRequest {
+				return &config.ProcessedRequest{Method: "GET", URL: "http://" + addr + "/app", Headers: map[string]string{"User-Agent": "s2req/test"}}
+			},
+			cookie: "Cookie: session=abc\r\n",
+		},
+		{
+			name: "fragment keeps explicit cookie",
+			request: func(addr string) *config.ProcessedRequest {
+				return &config.ProcessedRequest{Method: "GET", URL: "http://" + addr + "/app#top", Headers: map[string]string{"User-Agent": "s2req/test", "Cookie": "session=mine"}}
+			},
+			cookie: "Cookie: session=mine\r\n",
+		},
+		{
+			name: "raw request target",
+			request: func(addr string) *config.ProcessedRequest {
+				return &config.ProcessedRequest{Method: "GET", URL: "http://" + addr + "/%%32%65", RawRequestTarget: "/%%32%65", Headers: map[string]string{"User-Agent": "s2req/test", "Cookie": "theme=dark"}}
+			},
+			cookie: "Cookie: theme=dark; session=abc\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, captured := startCaptureServer(t)
+			client, err := NewClient(5*time.Second, "")
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			jar := NewCookieJar()
+			jar.SetCookies(&url.URL{Scheme: "http", Host: addr, Path: "/"}, []*http.Cookie{{Name: "session", Value: "abc", Path: "/"}})
+			client.SetCookieJar(jar)
+
+			rendered, err := client.RenderRequest(tt.request(addr))
+			if err != nil {
+				t.Fatalf("RenderRequest returned error: %v", err)
+			}
+			if !strings.Contains(string(rendered), tt.cookie) {
+				t.Errorf("Expected %q in rendered request, got %q", tt.cookie, rendered)
+			}
+
+			if _, err := client.SendRequest(context.Background(), tt.request(addr)); err != nil {
+				t.Fatalf("SendRequest returned error: %v", err)
+			}
+			select {
+			case wire := <-captured:
+				if string(rendered) != wire {
+					t.Errorf("Rendered bytes differ from wire bytes\nrendered: %q\nwire:     %q", rendered, wire)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("timed out waiting for captured request")
+			}
+		})
+	}
+}
+
+func TestRenderRequestThroughProxyUsesAbsoluteForm(t *testing.T) {
+	client, err := NewClient(5*time.Second, "http://proxy.example.test:8080")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	rendered, err := client.RenderRequest(&config.ProcessedRequest{
+		Method:  "GET",
+		URL:     "http://target.example.test/path",
+		Headers: map[string]string{"User-Agent": "s2req/test"},
+	})
+	if err != nil {
+		t.Fatalf("RenderRequest returned error: %v", err)
+	}
+
+	want := "GET http://target.example.test/path HTTP/1.1\r\n"
+	if !strings.HasPrefix(string(rendered), want) {
+		t.Errorf("Expected request line %q, got %q", want, rendered)
+	}
+}
+
+func TestRenderRequestWithOAuth2UsesPlaceholder(t *testing.T) {
+	client, err := NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// トークンエンドポイントには接続せず、プレースホルダーを出力する
+	rendered, err := client.RenderRequest(&config.ProcessedRequest{
+		Method: "GET",
+		URL:    "http://target.example.test/api",
+		OAuth2: &config.OAuth2Auth{TokenURL: "http://127.0.0.1:1/token", ClientID: "id", ClientSecret: "secret"},
+	})
+	if err != nil {
+		t.Fatalf("RenderRequest returned error: %v", err)
+	}
+	if want := "Authorization: Bearer " + OAuth2TokenPlaceholder + "\r\n"; !strings.Contains(string(rendered), want) {
+		t.Errorf("Expected %q in rendered request, got %q", want, rendered)
+	}
+}
+
+func TestRenderRequestInvalidURL(t *testing.T) {
+	client, err := NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if _, err := client.RenderRequest(&config.ProcessedRequest{Method: "GET", URL: "://bad"}); err == nil {
+		t.Errorf("Expected error for invalid URL")
+	}
+}
@@ -0,0 +1,299 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"time"
+)
+
+// wireSender は手動で構築したHTTP/1.1リクエストを、1件ごとに新しい接続で送信する。
+// フラグメントを含むリクエストとraw request targetのリクエストで共有する。
+// プロキシ経由の場合はCONNECTでトンネルを張ってから送信するため、リクエストラインは変更されない。
+type wireSender struct {
+	timeout   time.Duration                    // 接続からレスポンスボディの読み取りまで全体の上限（0は無制限）
+	proxy     func(*url.URL) (*url.URL, error) // 接続先に使うプロキシを返す（nilの場合は直接接続）
+	tlsConfig *tls.Config                      // httpsの場合のTLS設定（nilの場合はデフォルト）
+}
+
+// roundTrip はtargetのスキームとホストに接続してwireを送信し、レスポンスを返す。
+// reqはレスポンスの解釈（HEADのボディなど）にのみ使う。接続はレスポンスボディを閉じたときに閉じる。
+func (s *wireSender) roundTrip(ctx context.Context, target *url.URL, wire []byte, req *http.Request) (*http.Response, error) {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme: %s", target.Scheme)
+	}
+	cancel := context.CancelFunc(func() {})
+	if s.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+	}
+
+	conn, err := s.connect(ctx, target)
+	if err != nil {
+		// cancelで理由が取り消しに変わる前にエラーを作る
+		err = contextError(ctx, err)
+		cancel()
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// キャンセルされた場合は読み書き中の接続を閉じる
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	release := func() {
+		stop()
+		cancel()
+	}
+
+	resp, err := roundTripConn(ctx, conn, wire, req)
+	if err != nil {
+		err = contextError(ctx, err)
+		release()
+		_ = conn.Close()
+		return nil, err
+	}
+	resp.Body = &connClosingBody{ReadCloser: resp.Body, conn: conn, release: release}
+	return resp, nil
+}
+
+// connect はtargetへの接続を確立する。プロキシ経由の場合はトンネルを張り、httpsの場合はTLSハンドシェイクまで行う。
+func (s *wireSender) connect(ctx context.Context, target *url.URL) (net.Conn, error) {
+	addr := hostPort(target)
+	trace := contextTrace(ctx)
+	trace.GetConn(addr)
+
+	var proxyURL *url.URL
+	if s.proxy != nil {
+		var err error
+		if proxyURL, err = s.proxy(target); err != nil {
+			return nil, fmt.Errorf("failed to resolve proxy: %w", err)
+		}
+	}
+
+	var conn net.Conn
+	var err error
+	if proxyURL != nil {
+		conn, err = connectProxy(ctx, proxyURL, addr)
+	} else {
+		// The tool intentionally sends user-defined requests to user-specified hosts.
+		conn, err = dialTraced(ctx, addr) // #nosec G704
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if target.Scheme == "https" {
+		if conn, err = handshakeTraced(ctx, conn, manualTLSConfig(s.tlsConfig, target.Hostname())); err != nil {
+			return nil, err
+		}
+	}
+	trace.GotConn(httptrace.GotConnInfo{Conn: conn})
+	return conn, nil
+}
+
+// connectProxy はHTTP(S)プロキシに接続し、CONNECTでaddrへのトンネルを張る。
+// プロキシのURLにユーザー情報がある場合はProxy-Authorizationで送信する。
+func connectProxy(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	if proxyURL.Scheme != "http" && proxyURL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", proxyURL.Scheme)
+	}
+	conn, err := dialTraced(ctx, hostPort(proxyURL))
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %w", proxyURL.Host, err)
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, manualTLSConfig(nil, proxyURL.Hostname()))
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("proxy %s: TLS handshake failed: %w", proxyURL.Host, err)
+		}
+		conn = tlsConn
+	}
+	if err := sendConnect(ctx, conn, proxyURL, addr); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %s: %w", proxyURL.Host, err)
+	}
+	return conn, nil
+}
+
+// sendConnect はプロキシへの接続でCONNECTを送信し、トンネルが確立されたことを確認する
+func sendConnect(ctx context.Context, conn net.Conn, proxyURL *url.URL, addr string) error {
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	request := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", addr, addr)
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		request += "Proxy-Authorization: Basic " + credentials + "\r\n"
+	}
+	if _, err := io.WriteString(conn, request+"\r\n"); err != nil {
+		return fmt.Errorf("failed to write CONNECT: %w", err)
+	}
+
+	// トンネルの確立後はプロキシからデータが送られないため、読み取り用のバッファは破棄してよい
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return fmt.Errorf("failed to read CONNECT response: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("refused CONNECT to %s: %s", addr, resp.Status)
+	}
+	return nil
+}
+
+// dialTraced は名前解決とTCP接続を個別に行い、コンテキストのhttptraceのフックを呼び出す。
+// 名前解決で複数のアドレスが返された場合は順に試す。net.Dialerと同じく各アドレスには残り時間の一部だけを割り当て、
+// 応答しないアドレスがあってもタイムアウトまでに残りのアドレスを試せるようにする。
+func dialTraced(ctx context.Context, addr string) (net.Conn, error) {
+	trace := contextTrace(ctx)
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips := []string{host}
+	if net.ParseIP(host) == nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		trace.DNSDone(httptrace.DNSDoneInfo{Addrs: addrs, Err: err})
+		if err != nil {
+			return nil, fmt.Errorf("failed to dial: %w", err)
+		}
+		ips = ips[:0]
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP.String())
+		}
+	}
+
+	var dialer net.Dialer
+	var conn net.Conn
+	for i, ip := range ips {
+		dialCtx, cancel := ctx, context.CancelFunc(func() {})
+		if deadline, ok := ctx.Deadline(); ok {
+			dialCtx, cancel = context.WithDeadline(ctx, partialDeadline(time.Now(), deadline, len(ips)-i))
+		}
+		ipAddr := net.JoinHostPort(ip, port)
+		trace.ConnectStart("tcp", ipAddr)
+		conn, err = dialer.DialContext(dialCtx, "tcp", ipAddr)
+		cancel()
+		trace.ConnectDone("tcp", ipAddr, err)
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fmt.Errorf("failed to dial: %w", err)
+}
+
+// minDialTimeout はpartialDeadlineが1つのアドレスに割り当てる最短の時間（net.Dialerと同じ値）
+const minDialTimeout = 2 * time.Second
+
+// partialDeadline は残り時間を残りのアドレス数で等分し、次のアドレスへの接続の期限を返す
+func partialDeadline(now, deadline time.Time, addrsRemaining int) time.Time {
+	timeRemaining := deadline.Sub(now)
+	if timeRemaining <= 0 || addrsRemaining <= 1 {
+		return deadline
+	}
+	timeout := timeRemaining / time.Duration(addrsRemaining)
+	if timeout < minDialTimeout {
+		// 等分すると短すぎる場合は最短の時間を割り当てる（全体の期限は超えない）
+		if timeRemaining < minDialTimeout {
+			timeout = timeRemaining
+		} else {
+			timeout = minDialTimeout
+		}
+	}
+	return now.Add(timeout)
+}
+
+// handshakeTraced はconnでTLSハンドシェイクを行い、コンテキストのhttptraceのフックを呼び出す。失敗した場合はconnを閉じる。
+func handshakeTraced(ctx context.Context, conn net.Conn, tlsConfig *tls.Config) (net.Conn, error) {
+	trace := contextTrace(ctx)
+	tlsConn := tls.Client(conn, tlsConfig)
+	trace.TLSHandshakeStart()
+	err := tlsConn.HandshakeContext(ctx)
+	trace.TLSHandshakeDone(tlsConn.ConnectionState(), err)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// roundTripConn は手動で構築したリクエストを接続に書き込み、レスポンスを読み取る。
+// 書き込み完了とレスポンスの最初のバイトでhttptraceのフックを呼び出し、TLS接続の場合はresp.TLSを設定する。
+func roundTripConn(ctx context.Context, conn net.Conn, wire []byte, req *http.Request) (*http.Response, error) {
+	trace := contextTrace(ctx)
+	if _, err := conn.Write(wire); err != nil {
+		return nil, fmt.Errorf("failed to write request: %w", err)
+	}
+	trace.WroteRequest(httptrace.WroteRequestInfo{})
+
+	reader := bufio.NewReader(conn)
+	if _, err := reader.Peek(1); err == nil {
+		trace.GotFirstResponseByte()
+	}
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		resp.TLS = &state
+	}
+	return resp, nil
+}
+
+// connClosingBody はレスポンスボディと一緒に接続を閉じ、タイムアウトとキャンセルの監視を終える
+type connClosingBody struct {
+	io.ReadCloser
+	conn    net.Conn
+	release func()
+}
+
+func (b *connClosingBody) Close() error {
+	err := b.ReadCloser.Close()
+	if closeErr := b.conn.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to close connection: %w", closeErr)
+	}
+	b.release()
+	return err
+}
+
+// hostPort はURLのホストにポートがない場合、スキームのデフォルトのポートを付けて返す
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	if u.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// contextError はコンテキストが終了していた場合、その理由をエラーに含める
+func contextError(ctx context.Context, err error) error {
+	ctxErr := ctx.Err()
+	// 接続のデッドラインがコンテキストのタイマーより先に発火した場合も期限切れとして扱う
+	if ctxErr == nil && errors.Is(err, os.ErrDeadlineExceeded) {
+		if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+			ctxErr = context.DeadlineExceeded
+		}
+	}
+	if ctxErr != nil {
+		return fmt.Errorf("%w: %v", ctxErr, err)
+	}
+	return err
+}
@@ -0,0 +1,242 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// testConnectProxy はCONNECTだけを受け付けるテスト用のプロキシ
+type testConnectProxy struct {
+	listener net.Listener
+	status   int // CONNECTに返すステータス（0の場合は200でトンネルを張る）
+
+	mu       sync.Mutex
+	requests []string // "CONNECT host:port Proxy-Authorization"
+}
+
+func startConnectProxy(t *testing.T, status int) *testConnectProxy {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	proxy := &testConnectProxy{listener: listener, status: status}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go proxy.serve(conn)
+		}
+	}()
+	return proxy
+}
+
+func (p *testConnectProxy) serve(conn net.Conn) {
+	defer conn.Close()
+	req, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil {
+		return
+	}
+	p.mu.Lock()
+	p.requests = append(p.requests, strings.TrimSpace(req.Method+" "+req.Host+" "+req.Header.Get("Proxy-Authorization")))
+	p.mu.Unlock()
+
+	if req.Method != http.MethodConnect || p.status != 0 {
+		_, _ = io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+	upstream, err := net.Dial("tcp", req.Host)
+	if err != nil {
+		_, _ = io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+	defer upstream.Close()
+	_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	go func() {
+		_, _ = io.Copy(upstream, conn)
+	}()
+	_, _ = io.Copy(conn, upstream)
+}
+
+func (p *testConnectProxy) URL(userinfo string) string {
+	return "http://" + userinfo + p.listener.Addr().String()
+}
+
+func TestSendRequestThroughConnectProxy(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.RequestURI))
+	}))
+	defer server.Close()
+	plainServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.RequestURI))
+	}))
+	defer plainServer.Close()
+
+	proxy := startConnectProxy(t, 0)
+	client, err := NewClient(5*time.Second, proxy.URL("scanner:s3cret@"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := client.SetTLSConfig(config.TLSConfig{Insecure: true}); err != nil {
+		t.Fatalf("Failed to set TLS config: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		request  *config.ProcessedRequest
+		wantBody string
+	}{
+		{
+			name:     "https fragment",
+			request:  &config.ProcessedRequest{Method: "GET", URL: server.URL + "/page?x=1#section"},
+			wantBody: "/page?x=1#section",
+		},
+		{
+			name:     "https raw request target",
+			request:  &config.ProcessedRequest{Method: "GET", URL: server.URL + "/a/../b", RawRequestTarget: "/a/../b"},
+			wantBody: "/a/../b",
+		},
+		{
+			name:     "http raw request target",
+			request:  &config.ProcessedRequest{Method: "GET", URL: plainServer.URL + "/%2e%2e/", RawRequestTarget: "/%2e%2e/"},
+			wantBody: "/%2e%2e/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := client.SendRequest(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if response.Body != tt.wantBody {
+				t.Errorf("Expected the request target %q to reach the server unchanged, got %q", tt.wantBody, response.Body)
+			}
+		})
+	}
+
+	// プロキシのURLの資格情報はProxy-Authorizationで送信する
+	want := []string{
+		"CONNECT " + server.Listener.Addr().String() + " Basic c2Nhbm5lcjpzM2NyZXQ=",
+		"CONNECT " + server.Listener.Addr().String() + " Basic c2Nhbm5lcjpzM2NyZXQ=",
+		"CONNECT " + plainServer.Listener.Addr().String() + " Basic c2Nhbm5lcjpzM2NyZXQ=",
+	}
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if strings.Join(proxy.requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected proxy requests:\n%s\nwant:\n%s", strings.Join(proxy.requests, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSendRequestThroughConnectProxy_Refused(t *testing.T) {
+	proxy := startConnectProxy(t, http.StatusProxyAuthRequired)
+	client, err := NewClient(5*time.Second, proxy.URL(""))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	_, err = client.SendRequest(context.Background(), &config.ProcessedRequest{Method: "GET", URL: "https://target.example.test/#x"})
+	if err == nil || !strings.Contains(err.Error(), "refused CONNECT to target.example.test:443: 407 Proxy Authentication Required") {
+		t.Errorf("Expected the proxy to refuse the tunnel, got %v", err)
+	}
+}
+
+func TestSendRequestManualPathsStopOnCancelAndTimeout(t *testing.T) {
+	// ヘッダーを返さないサーバーと、ボディの途中で止まるサーバー
+	release := make(chan struct{})
+	stalled := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer stalled.Close()
+	stalledBody := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		_, _ = w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer stalledBody.Close()
+	defer close(release)
+
+	newRequests := func(baseURL string) map[string]*config.ProcessedRequest {
+		return map[string]*config.ProcessedRequest{
+			"fragment":           {Method: "GET", URL: baseURL + "/#x"},
+			"raw request target": {Method: "GET", URL: baseURL + "/", RawRequestTarget: "/"},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		cancel  time.Duration
+		baseURL string
+		wantErr string
+	}{
+		{name: "context cancellation", timeout: 30 * time.Second, cancel: 100 * time.Millisecond, baseURL: stalled.URL, wantErr: "context canceled"},
+		{name: "client timeout", timeout: 200 * time.Millisecond, baseURL: stalled.URL, wantErr: "exceeded"},
+		{name: "client timeout while reading the body", timeout: 200 * time.Millisecond, baseURL: stalledBody.URL, wantErr: "failed to read response body"},
+	}
+	for _, tt := range tests {
+		for path, request := range newRequests(tt.baseURL) {
+			t.Run(tt.name+"/"+path, func(t *testing.T) {
+				client, err := NewClient(tt.timeout, "")
+				if err != nil {
+					t.Fatalf("Failed to create client: %v", err)
+				}
+				if err := client.SetTLSConfig(config.TLSConfig{Insecure: true}); err != nil {
+					t.Fatalf("Failed to set TLS config: %v", err)
+				}
+				ctx := context.Background()
+				if tt.cancel > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithCancel(ctx)
+					time.AfterFunc(tt.cancel, cancel)
+				}
+
+				start := time.Now()
+				_, err = client.SendRequest(ctx, request)
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if elapsed := time.Since(start); elapsed > 2*time.Second {
+					t.Errorf("Expected the request to stop promptly, took %s", elapsed)
+				}
+			})
+		}
+	}
+}
+
+func TestPartialDeadline(t *testing.T) {
+	now := time.Unix(0, 0)
+	tests := []struct {
+		name      string
+		remaining time.Duration
+		addrs     int
+		want      time.Duration
+	}{
+		{name: "single address gets the whole timeout", remaining: 30 * time.Second, addrs: 1, want: 30 * time.Second},
+		{name: "timeout is split between addresses", remaining: 30 * time.Second, addrs: 3, want: 10 * time.Second},
+		{name: "each address gets at least the minimum", remaining: 5 * time.Second, addrs: 4, want: 2 * time.Second},
+		{name: "minimum does not exceed the deadline", remaining: time.Second, addrs: 2, want: time.Second},
+		{name: "deadline already passed", remaining: -time.Second, addrs: 2, want: -time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := partialDeadline(now, now.Add(tt.remaining), tt.addrs)
+			if got.Sub(now) != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got.Sub(now))
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// timingRecorder はhttptraceのフックで各フェーズの時刻を記録する
+type timingRecorder struct {
+	mu           sync.Mutex // 名前解決と接続のフックはダイアル用のgoroutineから呼ばれる
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	reused       bool
+}
+
+func newTimingRecorder() *timingRecorder {
+	return &timingRecorder{start: time.Now()}
+}
+
+// withTrace はレコーダーのフックを設定したコンテキストを返す
+func (r *timingRecorder) withTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			// リダイレクト先への接続では、接続に関する値を取り直す
+			r.dnsStart, r.dnsDone = time.Time{}, time.Time{}
+			r.connectStart, r.connectDone = time.Time{}, time.Time{}
+			r.tlsStart, r.tlsDone = time.Time{}, time.Time{}
+			r.wroteRequest, r.firstByte = time.Time{}, time.Time{}
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { r.mark(&r.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { r.mark(&r.dnsDone) },
+		ConnectStart: func(string, string) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			// 複数のアドレスに並行して接続する場合は最初の試行から数える
+			if r.connectStart.IsZero() {
+				r.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				r.mark(&r.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() { r.mark(&r.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { r.mark(&r.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.gotConn = time.Now()
+			r.reused = info.Reused
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { r.mark(&r.wroteRequest) },
+		GotFirstResponseByte: func() { r.mark(&r.firstByte) },
+	})
+}
+
+func (r *timingRecorder) mark(t *time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*t = time.Now()
+}
+
+// timing はendをレスポンスの読み取り完了時刻として各フェーズの時間を返す
+func (r *timingRecorder) timing(end time.Time) config.ResponseTiming {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return config.ResponseTiming{
+		Total:            end.Sub(r.start).Seconds(),
+		DNS:              span(r.dnsStart, r.dnsDone),
+		Connect:          span(r.connectStart, r.connectDone),
+		SSL:              span(r.tlsStart, r.tlsDone),
+		Send:             span(r.gotConn, r.wroteRequest),
+		Wait:             span(r.wroteRequest, r.firstByte),
+		Receive:          span(r.firstByte, end),
+		TTFB:             span(r.start, r.firstByte),
+		ConnectionReused: r.reused,
+	}
+}
+
+// span はfromからtoまでの秒数を返す。どちらかが記録されていない場合は0。
+func span(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return to.Sub(from).Seconds()
+}
+
+// contextTrace はコンテキストのClientTraceを返す。
+// 空のフックを重ねることで、未設定のフックもnilチェックなしで呼び出せるようにする。
+func contextTrace(ctx context.Context) *httptrace.ClientTrace {
+	return httptrace.ContextClientTrace(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn:              func(string) {},
+		DNSStart:             func(httptrace.DNSStartInfo) {},
+		DNSDone:              func(httptrace.DNSDoneInfo) {},
+		ConnectStart:         func(string, string) {},
+		ConnectDone:          func(string, string, error) {},
+		TLSHandshakeStart:    func() {},
+		TLSHandshakeDone:     func(tls.ConnectionState, error) {},
+		GotConn:              func(httptrace.GotConnInfo) {},
+		WroteRequest:         func(httptrace.WroteRequestInfo) {},
+		GotFirstResponseByte: func() {},
+	}))
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+func TestSendRequestTiming(t *testing.T) {
+	// ヘッダーの前と、ボディの途中で50msずつ待つ
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte("second"))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(5*time.Second, "")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	// 名前解決を計測するためにホスト名で接続する
+	baseURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	tests := []struct {
+		name    string
+		request *config.ProcessedRequest
+	}{
+		{name: "http client", request: &config.ProcessedRequest{Method: "GET", URL: baseURL + "/a"}},
+		{name: "fragment", request: &config.ProcessedRequest{Method: "GET", URL: baseURL + "/a#top"}},
+		{name: "raw request target", request: &config.ProcessedRequest{Method: "GET", URL: baseURL + "/a", RawRequestTarget: "/a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := client.SendRequest(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if response.Body != "firstsecond" {
+				t.Fatalf("Unexpected body: %q", response.Body)
+			}
+
+			timing := response.Time
+			if timing.DNS <= 0 || timing.Connect <= 0 || timing.SSL != 0 || timing.ConnectionReused {
+				t.Errorf("Expected a new connection with DNS and connect times, got %+v", timing)
+			}
+			// 最初のバイトの検出が遅れた分はreceiveが短くなるため、receiveには余裕を持たせる
+			if timing.Wait < 0.05 || timing.TTFB < timing.Wait || timing.Receive < 0.03 || timing.Wait+timing.Receive < 0.1 {
+				t.Errorf("Expected wait of at least 50ms and wait plus receive of at least 100ms, got %+v", timing)
+			}
+			if timing.Total < timing.TTFB+timing.Receive-0.001 {
+				t.Errorf("Expected total to cover ttfb and receive, got %+v", timing)
+			}
+		})
+	}
+
+	// keep-aliveで再利用した接続では名前解決と接続の時間は0
+	response, err := client.SendRequest(context.Background(), &config.ProcessedRequest{Method: "GET", URL: baseURL + "/b"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !response.Time.ConnectionReused || response.Time.DNS != 0 || response.Time.Connect != 0 || response.Time.Wait < 0.05 {
+		t.Errorf("Expected a reused connection, got %+v", response.Time)
+	}
+}
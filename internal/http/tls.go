@@ -0,0 +1,171 @@
+package http
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// tlsVersions はmin-versionとmax-versionで指定できるバージョン
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// parseTLSVersion は"1.2"や"TLS1.2"の形式のバージョンを解析する
+func parseTLSVersion(version string) (uint16, error) {
+	normalized := strings.TrimSpace(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(version)), "tls"))
+	if v, ok := tlsVersions[normalized]; ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("invalid TLS version '%s', expected 1.0, 1.1, 1.2 or 1.3", version)
+}
+
+// ValidateTLS はTLS設定のうちファイルを読まずに検証できる項目を検証し、項目名をキーとするエラーを返す
+func ValidateTLS(options *config.TLSConfig) map[string]error {
+	errs := make(map[string]error)
+	minVersion, maxVersion := uint16(0), uint16(0)
+	if options.MinVersion != "" {
+		v, err := parseTLSVersion(options.MinVersion)
+		if err != nil {
+			errs["min-version"] = err
+		}
+		minVersion = v
+	}
+	if options.MaxVersion != "" {
+		v, err := parseTLSVersion(options.MaxVersion)
+		if err != nil {
+			errs["max-version"] = err
+		}
+		maxVersion = v
+	}
+	if minVersion != 0 && maxVersion != 0 && minVersion > maxVersion {
+		errs["max-version"] = fmt.Errorf("max-version %s is lower than min-version %s", options.MaxVersion, options.MinVersion)
+	}
+	if options.Key != "" && options.Cert == "" {
+		errs["key"] = fmt.Errorf("key requires cert")
+	}
+	return errs
+}
+
+// mergeTLSOptions はコマンドラインのTLS設定にmeta.tlsの設定を重ねる。
+// meta.tlsで指定した項目はコマンドラインの値を置き換え、insecureはどちらかで有効になる。
+func mergeTLSOptions(base, override config.TLSConfig) config.TLSConfig {
+	merged := base
+	merged.Insecure = base.Insecure || override.Insecure
+	if override.CACert != "" {
+		merged.CACert = override.CACert
+	}
+	if override.Cert != "" {
+		merged.Cert, merged.Key = override.Cert, override.Key
+	}
+	if override.SNI != "" {
+		merged.SNI = override.SNI
+	}
+	if override.MinVersion != "" {
+		merged.MinVersion = override.MinVersion
+	}
+	if override.MaxVersion != "" {
+		merged.MaxVersion = override.MaxVersion
+	}
+	return merged
+}
+
+// newTLSConfig はTLS設定からtls.Configを作成する。CA証明書とクライアント証明書はここで読み込む。
+func newTLSConfig(options config.TLSConfig) (*tls.Config, error) {
+	errs := ValidateTLS(&options)
+	for _, name := range []string{"min-version", "max-version", "key"} {
+		if err := errs[name]; err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: options.SNI,
+		MinVersion: tls.VersionTLS12,
+	}
+	// --insecureは自己署名証明書を使う検証環境のために明示的に指定された場合のみ有効になる
+	tlsConfig.InsecureSkipVerify = options.Insecure // #nosec G402
+	if options.MinVersion != "" {
+		tlsConfig.MinVersion, _ = parseTLSVersion(options.MinVersion)
+	}
+	if options.MaxVersion != "" {
+		tlsConfig.MaxVersion, _ = parseTLSVersion(options.MaxVersion)
+	}
+
+	if options.CACert != "" {
+		pem, err := os.ReadFile(options.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", options.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if options.Cert != "" {
+		keyFile := options.Key
+		if keyFile == "" {
+			keyFile = options.Cert
+		}
+		certificate, err := tls.LoadX509KeyPair(options.Cert, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// manualTLSConfig はhttp.Transportを通さずに送信する経路のtls.Configを返す。
+// SNIが未指定の場合は接続先のホスト名を使い、ALPNではHTTP/1.1だけを提示する。
+func manualTLSConfig(base *tls.Config, hostname string) *tls.Config {
+	var tlsConfig *tls.Config
+	if base != nil {
+		tlsConfig = base.Clone()
+	} else {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = hostname
+	}
+	tlsConfig.NextProtos = []string{"http/1.1"}
+	return tlsConfig
+}
+
+// tlsInfo は接続の状態から結果に記録するTLSの情報を作成する
+func tlsInfo(state *tls.ConnectionState) *config.TLSInfo {
+	if state == nil {
+		return nil
+	}
+	info := &config.TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		Protocol:    state.NegotiatedProtocol,
+		ServerName:  state.ServerName,
+	}
+	if len(state.PeerCertificates) > 0 {
+		certificate := state.PeerCertificates[0]
+		fingerprint := sha256.Sum256(certificate.Raw)
+		info.PeerCertificate = &config.CertificateInfo{
+			Subject:      certificate.Subject.String(),
+			Issuer:       certificate.Issuer.String(),
+			DNSNames:     certificate.DNSNames,
+			NotBefore:    certificate.NotBefore.UTC(),
+			NotAfter:     certificate.NotAfter.UTC(),
+			SerialNumber: certificate.SerialNumber.String(),
+			SHA256:       hex.EncodeToString(fingerprint[:]),
+		}
+	}
+	return info
+}
@@ -0,0 +1,224 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// writePEM はPEMブロックをファイルに書き込み、パスを返す
+func writePEM(t *testing.T, dir, name string, blocks ...*pem.Block) string {
+	t.Helper()
+	var data []byte
+	for _, block := range blocks {
+		data = append(data, pem.EncodeToMemory(block)...)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+// newClientCertificate は自己署名のクライアント証明書を作成し、証明書と秘密鍵のPEMブロックを返す
+func newClientCertificate(t *testing.T, commonName string) (*x509.Certificate, *pem.Block, *pem.Block) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	return certificate, &pem.Block{Type: "CERTIFICATE", Bytes: der}, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}
+}
+
+func TestSendRequestWithTLSOptions(t *testing.T) {
+	// クライアント証明書を提示した場合はそのCN、SNIを受け取った場合はその名前を返す
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := "-"
+		if len(r.TLS.PeerCertificates) > 0 {
+			client = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		_, _ = w.Write([]byte(client + " " + r.TLS.ServerName))
+	}))
+	clientCertificate, certPEM, keyPEM := newClientCertificate(t, "scanner")
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCertificate)
+	server.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := writePEM(t, dir, "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	certFile := writePEM(t, dir, "client.pem", certPEM)
+	keyFile := writePEM(t, dir, "client-key.pem", keyPEM)
+	combinedFile := writePEM(t, dir, "client-combined.pem", certPEM, keyPEM)
+
+	paths := []struct {
+		name    string
+		request func() *config.ProcessedRequest
+	}{
+		{name: "http client", request: func() *config.ProcessedRequest {
+			return &config.ProcessedRequest{Method: "GET", URL: server.URL + "/"}
+		}},
+		{name: "fragment", request: func() *config.ProcessedRequest {
+			return &config.ProcessedRequest{Method: "GET", URL: server.URL + "/#top"}
+		}},
+		{name: "raw request target", request: func() *config.ProcessedRequest {
+			return &config.ProcessedRequest{Method: "GET", URL: server.URL + "/", RawRequestTarget: "/"}
+		}},
+	}
+	tests := []struct {
+		name        string
+		cli         config.TLSConfig
+		meta        *config.TLSConfig
+		wantBody    string
+		wantVersion string
+		wantErr     string
+	}{
+		{
+			name:    "untrusted certificate",
+			wantErr: "certificate",
+		},
+		{
+			name:        "insecure",
+			cli:         config.TLSConfig{Insecure: true},
+			wantBody:    "- ",
+			wantVersion: "TLS 1.3",
+		},
+		{
+			name:        "custom CA and SNI",
+			cli:         config.TLSConfig{CACert: caFile, SNI: "example.com"},
+			wantBody:    "- example.com",
+			wantVersion: "TLS 1.3",
+		},
+		{
+			name:    "SNI that does not match the certificate",
+			cli:     config.TLSConfig{CACert: caFile, SNI: "waf.internal"},
+			wantErr: "waf.internal",
+		},
+		{
+			name:        "client certificate and max version",
+			cli:         config.TLSConfig{Insecure: true, Cert: certFile, Key: keyFile, MaxVersion: "1.2"},
+			wantBody:    "scanner ",
+			wantVersion: "TLS 1.2",
+		},
+		{
+			name:        "meta.tls over command-line options",
+			cli:         config.TLSConfig{CACert: caFile},
+			meta:        &config.TLSConfig{Cert: combinedFile, SNI: "example.com"},
+			wantBody:    "scanner example.com",
+			wantVersion: "TLS 1.3",
+		},
+	}
+	for _, tt := range tests {
+		for _, path := range paths {
+			t.Run(tt.name+"/"+path.name, func(t *testing.T) {
+				client, err := NewClient(5*time.Second, "")
+				if err != nil {
+					t.Fatalf("Failed to create client: %v", err)
+				}
+				if err := client.SetTLSConfig(tt.cli); err != nil {
+					t.Fatalf("Failed to set TLS config: %v", err)
+				}
+				request := path.request()
+				request.TLS = tt.meta
+
+				response, err := client.SendRequest(context.Background(), request)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if response.Body != tt.wantBody {
+					t.Errorf("Expected body %q, got %q", tt.wantBody, response.Body)
+				}
+
+				info := response.TLS
+				if info == nil {
+					t.Fatalf("Expected TLS information in the response")
+				}
+				if info.Version != tt.wantVersion || info.CipherSuite == "" || info.Protocol != "http/1.1" {
+					t.Errorf("Unexpected TLS information: %+v", info)
+				}
+				if info.PeerCertificate == nil || info.PeerCertificate.Subject != "O=Acme Co" || len(info.PeerCertificate.SHA256) != 64 ||
+					!strings.Contains(strings.Join(info.PeerCertificate.DNSNames, ","), "example.com") {
+					t.Errorf("Unexpected peer certificate: %+v", info.PeerCertificate)
+				}
+				if response.Time.SSL <= 0 {
+					t.Errorf("Expected the TLS handshake time, got %+v", response.Time)
+				}
+			})
+		}
+	}
+}
+
+func TestSetTLSConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		options config.TLSConfig
+		wantErr string
+	}{
+		{name: "invalid version", options: config.TLSConfig{MinVersion: "1.4"}, wantErr: "min-version: invalid TLS version '1.4'"},
+		{name: "min above max", options: config.TLSConfig{MinVersion: "TLS1.3", MaxVersion: "1.2"}, wantErr: "max-version 1.2 is lower than min-version TLS1.3"},
+		{name: "key without cert", options: config.TLSConfig{Key: "client.key"}, wantErr: "key requires cert"},
+		{name: "missing CA file", options: config.TLSConfig{CACert: filepath.Join(dir, "missing.pem")}, wantErr: "failed to read CA certificate"},
+		{name: "CA file without certificates", options: config.TLSConfig{CACert: notPEM}, wantErr: "no PEM certificates found"},
+		{name: "invalid client certificate", options: config.TLSConfig{Cert: notPEM}, wantErr: "failed to load client certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(5*time.Second, "")
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			err = client.SetTLSConfig(tt.options)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
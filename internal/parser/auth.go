@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// resolvedAuth はmeta.authの値を評価した結果
+type resolvedAuth struct {
+	header      string // 設定するヘッダー名（ヘッダーを使用しない場合は空）
+	headerValue string
+	query       string // 追加するクエリパラメータ名（クエリを使用しない場合は空）
+	queryValue  string
+	digest      *config.DigestAuth
+	oauth2      *config.OAuth2Auth
+	secret      string // 結果の出力時にマスクする値
+}
+
+// resolveAuth はmeta.authの値を評価し、リクエストに追加するヘッダーやクエリパラメータを返す
+func (p *Parser) resolveAuth(ctx context.Context, auth *config.AuthConfig) (*resolvedAuth, error) {
+	value := func(name string, v interface{}) (string, error) {
+		return p.processString(ctx, "meta.auth."+name, v)
+	}
+
+	switch auth.Type {
+	case config.AuthTypeBasic, config.AuthTypeDigest:
+		username, err := value("username", auth.Username)
+		if err != nil {
+			return nil, err
+		}
+		password, err := value("password", auth.Password)
+		if err != nil {
+			return nil, err
+		}
+		if auth.Type == config.AuthTypeDigest {
+			// Digestの資格情報はチャレンジを受け取るまでリクエストに含めない
+			return &resolvedAuth{digest: &config.DigestAuth{Username: username, Password: password}}, nil
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		return &resolvedAuth{header: "Authorization", headerValue: "Basic " + credentials, secret: credentials}, nil
+	case config.AuthTypeBearer:
+		token, err := value("token", auth.Token)
+		if err != nil {
+			return nil, err
+		}
+		return &resolvedAuth{header: "Authorization", headerValue: "Bearer " + token, secret: token}, nil
+	case config.AuthTypeAPIKey:
+		key, err := value("value", auth.Value)
+		if err != nil {
+			return nil, err
+		}
+		if auth.In == "query" {
+			return &resolvedAuth{query: auth.Name, queryValue: key, secret: key}, nil
+		}
+		return &resolvedAuth{header: auth.Name, headerValue: key, secret: key}, nil
+	case config.AuthTypeOAuth2:
+		// トークンは送信時にクライアントが取得し、実行中はキャッシュする
+		oauth2 := &config.OAuth2Auth{Scope: strings.Join(auth.Scopes, " "), ClientAuth: auth.ClientAuth}
+		var err error
+		if oauth2.TokenURL, err = value("token-url", auth.TokenURL); err != nil {
+			return nil, err
+		}
+		if oauth2.ClientID, err = value("client-id", auth.ClientID); err != nil {
+			return nil, err
+		}
+		if oauth2.ClientSecret, err = value("client-secret", auth.ClientSecret); err != nil {
+			return nil, err
+		}
+		return &resolvedAuth{oauth2: oauth2}, nil
+	default:
+		return nil, fmt.Errorf("unknown auth type '%s'", auth.Type)
+	}
+}
+
+// processString はmeta.authやmeta.signの値を評価して文字列にする（未指定の場合は空文字）
+func (p *Parser) processString(ctx context.Context, field string, v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	processed, err := p.processValue(ctx, v)
+	if err != nil {
+		return "", fmt.Errorf("failed to process %s: %w", field, err)
+	}
+	if processed == nil {
+		return "", nil
+	}
+	return fmt.Sprintf("%v", processed), nil
+}
+
+// applyQuery はapi-keyをクエリパラメータに追加する。queryに同じ名前があってもmeta.authが優先される。
+func (a *resolvedAuth) applyQuery(queryParams map[string]interface{}) {
+	if a.query != "" {
+		queryParams[a.query] = a.queryValue
+	}
+}
+
+// applyHeader は認証ヘッダーを設定する。headersに同じ名前（大文字小文字を区別しない）があってもmeta.authが優先される。
+func (a *resolvedAuth) applyHeader(headerParams map[string]interface{}) {
+	name := a.header
+	if a.oauth2 != nil {
+		name = "Authorization" // トークンは送信時に設定する
+	}
+	if name == "" {
+		return
+	}
+	for key := range headerParams {
+		if strings.EqualFold(key, name) {
+			delete(headerParams, key)
+		}
+	}
+	if a.header != "" {
+		headerParams[a.header] = a.headerValue
+	}
+}
+
+// applyRequest はDigestとOAuth2の資格情報、マスクする値を処理済みリクエストに設定する
+func (a *resolvedAuth) applyRequest(request *config.ProcessedRequest) {
+	request.DigestAuth = a.digest
+	request.OAuth2 = a.oauth2
+	if a.secret != "" {
+		request.Secrets = append(request.Secrets, a.secret)
+	}
+}
+
+// validateAuth validates the meta.auth block of a request configuration
+func (p *Parser) validateAuth(requestConfig *config.RequestConfig, filePath string, fileExt string, content string) error {
+	if requestConfig.Meta == nil || requestConfig.Meta.Auth == nil {
+		return nil
+	}
+	auth := requestConfig.Meta.Auth
+
+	errs := make(map[string]error)
+	required := func(name string, value interface{}) {
+		if value == nil || value == "" {
+			errs[name] = fmt.Errorf("%s is required for %s auth", name, auth.Type)
+		}
+	}
+
+	switch auth.Type {
+	case config.AuthTypeBasic, config.AuthTypeDigest:
+		required("username", auth.Username)
+		if auth.Password == nil {
+			errs["password"] = fmt.Errorf("password is required for %s auth", auth.Type)
+		}
+	case config.AuthTypeBearer:
+		required("token", auth.Token)
+	case config.AuthTypeAPIKey:
+		required("name", auth.Name)
+		required("value", auth.Value)
+		if auth.In != "" && auth.In != "header" && auth.In != "query" {
+			errs["in"] = fmt.Errorf("unknown api-key location '%s', expected header or query", auth.In)
+		}
+	case config.AuthTypeOAuth2:
+		required("token-url", auth.TokenURL)
+		required("client-id", auth.ClientID)
+		required("client-secret", auth.ClientSecret)
+		if tokenURL, ok := auth.TokenURL.(string); ok && tokenURL != "" && !strings.HasPrefix(tokenURL, "http://") && !strings.HasPrefix(tokenURL, "https://") {
+			errs["token-url"] = fmt.Errorf("token-url must be an absolute http or https URL")
+		}
+		if auth.ClientAuth != "" && auth.ClientAuth != "basic" && auth.ClientAuth != "body" {
+			errs["client-auth"] = fmt.Errorf("unknown client-auth '%s', expected basic or body", auth.ClientAuth)
+		}
+	case "":
+		errs[""] = fmt.Errorf("auth type is required, expected one of: basic, bearer, api-key, digest, oauth2")
+	default:
+		errs["type"] = fmt.Errorf("unknown auth type '%s', expected one of: basic, bearer, api-key, digest, oauth2", auth.Type)
+	}
+
+	return p.createPropertyErrors(errs, "meta.auth", filePath, fileExt, content)
+}
@@ -0,0 +1,324 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/secureta/s2http-request/internal/config"
+	"github.com/secureta/s2http-request/pkg/functions"
+)
+
+// maxDictSourceLineSize は外部ソースの1行の最大サイズ
+const maxDictSourceLineSize = 1024 * 1024
+
+// dictSourceOptions はdictの外部ソース参照の設定を表す
+type dictSourceOptions struct {
+	Path    string
+	Comment *string     // $lines: コメント行の接頭辞（デフォルトは"#"、空文字で無効）
+	Column  interface{} // $csv: ヘッダー名または0始まりの列番号（デフォルトは0）
+	Header  *bool       // $csv: 先頭行をヘッダーとして扱うか（デフォルトはtrue）
+	Field   string      // $jsonl: ドット区切りのフィールドパス（空の場合は行全体）
+}
+
+// loadDictSources はdictの外部ソース参照（$lines, $csv, $jsonl）を読み込み、値の配列としてDictに設定する。
+// 読み込めないソースは位置情報付きのDictValidationErrorとして報告する。
+func (p *Parser) loadDictSources(requestConfig *config.RequestConfig, filePath string, fileExt string, content string) error {
+	if len(requestConfig.DictSources) == 0 {
+		return nil
+	}
+
+	var tracker *PositionTracker
+	if content != "" {
+		tracker = NewPositionTracker(filePath, []byte(content))
+	}
+
+	// FileFunctionと同じく、リクエスト定義ファイルからの相対パスとして解決する
+	ctx := context.WithValue(context.Background(), "requestFilePath", requestConfig.FilePath)
+
+	keys := make([]string, 0, len(requestConfig.DictSources))
+	for key := range requestConfig.DictSources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errorCollection := NewErrorCollection()
+	for _, key := range keys {
+		values, err := p.loadDictSource(ctx, requestConfig.DictSources[key])
+		if err != nil {
+			propertyPath := fmt.Sprintf("dict.%s", key)
+			var position *PositionInfo
+			if tracker != nil {
+				position = tracker.GetPosition(propertyPath, fileExt)
+			}
+			errorCollection.Add(p.createDictValidationError(filePath, position, propertyPath, key, err.Error()))
+			continue
+		}
+
+		if requestConfig.Dict == nil {
+			requestConfig.Dict = make(map[string][]interface{})
+		}
+		requestConfig.Dict[key] = values
+	}
+
+	return errorCollection.ToError()
+}
+
+// loadDictSource は1つの外部ソース参照から値の配列を読み込む
+func (p *Parser) loadDictSource(ctx context.Context, source interface{}) ([]interface{}, error) {
+	sourceMap, ok := source.(map[string]interface{})
+	if !ok || len(sourceMap) != 1 {
+		return nil, fmt.Errorf("value must be an array or a source reference ($lines, $csv, $jsonl)")
+	}
+
+	for name, args := range sourceMap {
+		options, err := parseDictSourceOptions(name, args)
+		if err != nil {
+			return nil, err
+		}
+
+		path, err := functions.ResolveRelativePath(ctx, options.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %w", name, options.Path, err)
+		}
+		file, err := os.Open(path) // #nosec G304
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: failed to open file: %w", name, options.Path, err)
+		}
+		defer file.Close()
+
+		var values []interface{}
+		switch name {
+		case "$lines":
+			values, err = readDictLines(file, options)
+		case "$csv":
+			values, err = readDictCSV(file, options)
+		case "$jsonl":
+			values, err = readDictJSONL(file, options)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %w", name, options.Path, err)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("%s %q: no values found", name, options.Path)
+		}
+		return values, nil
+	}
+	return nil, nil
+}
+
+// parseDictSourceOptions はソース参照の引数を解析する。
+// 引数はファイルパスの文字列、またはpathを含むオプションのマップ。
+func parseDictSourceOptions(name string, args interface{}) (*dictSourceOptions, error) {
+	allowed := map[string][]string{
+		"$lines": {"path", "comment"},
+		"$csv":   {"path", "column", "header"},
+		"$jsonl": {"path", "field"},
+	}
+	keys, known := allowed[name]
+	if !known {
+		return nil, fmt.Errorf("unknown dict source '%s', expected one of: $lines, $csv, $jsonl", name)
+	}
+
+	options := &dictSourceOptions{}
+	switch v := args.(type) {
+	case string:
+		options.Path = v
+	case map[string]interface{}:
+		for key, value := range v {
+			if !containsString(keys, key) {
+				return nil, fmt.Errorf("%s: unknown option '%s', expected one of: %s", name, key, strings.Join(keys, ", "))
+			}
+			var ok bool
+			switch key {
+			case "path":
+				options.Path, ok = value.(string)
+			case "comment":
+				var comment string
+				comment, ok = value.(string)
+				options.Comment = &comment
+			case "column":
+				options.Column = value
+				ok = isColumnValue(value)
+			case "header":
+				var header bool
+				header, ok = value.(bool)
+				options.Header = &header
+			case "field":
+				options.Field, ok = value.(string)
+			}
+			if !ok {
+				return nil, fmt.Errorf("%s: invalid value for option '%s': %v", name, key, value)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%s: argument must be a file path or an options object, got %T", name, args)
+	}
+
+	if options.Path == "" {
+		return nil, fmt.Errorf("%s: file path is required", name)
+	}
+	return options, nil
+}
+
+// isColumnValue はCSVの列指定として有効な値かを返す
+func isColumnValue(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return v != ""
+	case int:
+		return v >= 0
+	case float64:
+		return v >= 0 && v == float64(int(v))
+	default:
+		return false
+	}
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// newDictSourceScanner は長い行にも対応したScannerを作成
+func newDictSourceScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxDictSourceLineSize)
+	return scanner
+}
+
+// readDictLines は1行1値のテキストを読み込む。空行とコメント行は読み飛ばす。
+func readDictLines(r io.Reader, options *dictSourceOptions) ([]interface{}, error) {
+	comment := "#"
+	if options.Comment != nil {
+		comment = *options.Comment
+	}
+
+	var values []interface{}
+	scanner := newDictSourceScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" || (comment != "" && strings.HasPrefix(line, comment)) {
+			continue
+		}
+		values = append(values, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return values, nil
+}
+
+// readDictCSV はCSVの1列を読み込む
+func readDictCSV(r io.Reader, options *dictSourceOptions) ([]interface{}, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header := true
+	if options.Header != nil {
+		header = *options.Header
+	}
+
+	column := 0
+	switch v := options.Column.(type) {
+	case int:
+		column = v
+	case float64:
+		column = int(v)
+	case string:
+		if !header {
+			return nil, fmt.Errorf("column name '%s' requires a header row", v)
+		}
+	}
+
+	var values []interface{}
+	first := true
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if first && header {
+			first = false
+			if name, ok := options.Column.(string); ok {
+				column = -1
+				for i, field := range record {
+					if strings.TrimSpace(field) == name {
+						column = i
+						break
+					}
+				}
+				if column < 0 {
+					return nil, fmt.Errorf("column '%s' not found in header: %v", name, record)
+				}
+			}
+			continue
+		}
+		first = false
+
+		if column >= len(record) {
+			return nil, fmt.Errorf("line %d: column %d not found (record has %d fields)", line, column, len(record))
+		}
+		values = append(values, record[column])
+	}
+	return values, nil
+}
+
+// readDictJSONL はJSONLの各行から1つのフィールドを読み込む
+func readDictJSONL(r io.Reader, options *dictSourceOptions) ([]interface{}, error) {
+	var fieldPath []string
+	if options.Field != "" {
+		fieldPath = strings.Split(options.Field, ".")
+	}
+
+	var values []interface{}
+	lineNumber := 0
+	scanner := newDictSourceScanner(r)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var value interface{}
+		if err := json.Unmarshal([]byte(line), &value); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %w", lineNumber, err)
+		}
+		for _, key := range fieldPath {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("line %d: field '%s' not found", lineNumber, options.Field)
+			}
+			if value, ok = object[key]; !ok {
+				return nil, fmt.Errorf("line %d: field '%s' not found", lineNumber, options.Field)
+			}
+		}
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			return nil, fmt.Errorf("line %d: value must be a primitive value (string, number, boolean), got %T", lineNumber, value)
+		}
+		values = append(values, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeDictSourceFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestLoadDictSources(t *testing.T) {
+	dir := writeDictSourceFiles(t, map[string]string{
+		"payloads/sqli.txt":  "# comment\n' OR 1=1--\n\nadmin'--\r\n#not-a-comment-with-custom-prefix\n",
+		"payloads/users.csv": "id,name\n1,alice\n2,\"bob, jr\"\n",
+		"payloads/xss.jsonl": "{\"p\":{\"v\":\"<svg>\"}}\n\n{\"p\":{\"v\":42}}\n",
+	})
+
+	tests := []struct {
+		name     string
+		fileExt  string
+		content  string
+		expected []interface{}
+	}{
+		{
+			name:     "lines with comments and CRLF",
+			fileExt:  ".yaml",
+			content:  "method: GET\npath: /\nquery:\n  q: {$dict: v}\ndict:\n  v: {$lines: payloads/sqli.txt}\n",
+			expected: []interface{}{"' OR 1=1--", "admin'--"},
+		},
+		{
+			name:     "lines with custom comment prefix",
+			fileExt:  ".yaml",
+			content:  "method: GET\npath: /\nquery:\n  q: {$dict: v}\ndict:\n  v: {$lines: {path: payloads/sqli.txt, comment: \"#not\"}}\n",
+			expected: []interface{}{"# comment", "' OR 1=1--", "admin'--"},
+		},
+		{
+			name:     "csv column by name",
+			fileExt:  ".yaml",
+			content:  "method: GET\npath: /\nquery:\n  q: {$dict: v}\ndict:\n  v: {$csv: {path: payloads/users.csv, column: name}}\n",
+			expected: []interface{}{"alice", "bob, jr"},
+		},
+		{
+			name:     "csv column by index without header",
+			fileExt:  ".json",
+			content:  `{"method":"GET","path":"/","query":{"q":{"$dict":"v"}},"dict":{"v":{"$csv":{"path":"payloads/users.csv","column":0,"header":false}}}}`,
+			expected: []interface{}{"id", "1", "2"},
+		},
+		{
+			name:     "jsonl nested field",
+			fileExt:  ".json",
+			content:  `{"method":"GET","path":"/","query":{"q":{"$dict":"v"}},"dict":{"v":{"$jsonl":{"path":"payloads/xss.jsonl","field":"p.v"}}}}`,
+			expected: []interface{}{"<svg>", float64(42)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewParser()
+			configs, err := parser.ParseMultiple([]byte(tt.content), tt.fileExt, filepath.Join(dir, "request"+tt.fileExt))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := configs[0].Dict["v"]; !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %#v, got %#v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoadDictSources_Errors(t *testing.T) {
+	dir := writeDictSourceFiles(t, map[string]string{
+		"payloads/users.csv": "id,name\n1,alice\n",
+		"payloads/bad.jsonl": "{\"v\":\"ok\"}\n{\"v\":[1]}\n",
+		"payloads/empty.txt": "# only comments\n",
+	})
+
+	tests := []struct {
+		name          string
+		content       string
+		errorContains []string
+	}{
+		{
+			name:          "path escapes the request directory",
+			content:       "method: GET\npath: /\nquery:\n  q: {$dict: v}\ndict:\n  v: {$lines: ../secret.txt}\n",
+			errorContains: []string{"request.yaml:6:6", "dict.v", "path escapes request file directory"},
+		},
+		{
+			name:          "absolute path",
+			content:       "method: GET\npath: /\nquery:\n  q: {$dict: v}\ndict:\n  v: {$lines: /etc/passwd}\n",
+			errorContains: []string{"dict.v", "absolute paths are not allowed"},
+		},
+		{
+			name:          "missing file",
+			content:       "method: GET\npath: /\nquery:\n  q: {$dict: v}\ndict:\n  v: {$lines: missing.txt}\n",
+			errorContains: []string{"request.yaml:6:6", "failed to open file"},
+		},
+		{
+			name:          "unknown csv column",
+			content:       "method: GET\npath: /\nquery:\n  q: {$dict: v}\ndict:\n  v: {$csv: {path: payloads/users.csv, column: email}}\n",
+			errorContains: []string{"request.yaml:6:6", "column 'email' not found"},
+		},
+		{
+			name:          "non-primitive jsonl value",
+			content:       "method: GET\npath: /\nquery:\n  q: {$dict: v}\ndict:\n  v: {$jsonl: {path: payloads/bad.jsonl, field: v}}\n",
+			errorContains: []string{"request.yaml:6:6", "line 2: value must be a primitive value"},
+		},
+		{
+			name:          "unknown source",
+			content:       "method: GET\npath: /\nquery:\n  q: {$dict: v}\ndict:\n  v: {$words: payloads/users.csv}\n",
+			errorContains: []string{"unknown dict source '$words'"},
+		},
+		{
+			name:          "unknown option",
+			content:       "method: GET\npath: /\nquery:\n  q: {$dict: v}\ndict:\n  v: {$lines: {path: payloads/empty.txt, skip: 1}}\n",
+			errorContains: []string{"unknown option 'skip'"},
+		},
+		{
+			name:          "source without values",
+			content:       "method: GET\npath: /\nquery:\n  q: {$dict: v}\ndict:\n  v: {$lines: payloads/empty.txt}\n",
+			errorContains: []string{"request.yaml:6:6", "no values found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewParser()
+			_, err := parser.ParseMultiple([]byte(tt.content), ".yaml", filepath.Join(dir, "request.yaml"))
+			if err == nil {
+				t.Fatalf("Expected error but got none")
+			}
+			for _, want := range tt.errorContains {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to contain %q, got: %v", want, err)
+				}
+			}
+			if strings.Contains(err.Error(), "dict variable 'v' not found") {
+				t.Errorf("Expected no reference error for a dict source, got: %v", err)
+			}
+		})
+	}
+}
@@ -227,3 +227,67 @@ func TestParser_createDictValidationError_nilPosition(t *testing.T) {
 		t.Errorf("ColumnNumber = %d, expected %d", err.ParseError.ColumnNumber, 0)
 	}
 }
+
+func TestParser_validateDictMode(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expectError   bool
+		errorContains []string
+	}{
+		{
+			name:    "zip with equal lengths",
+			content: "method: GET\npath: /\nquery:\n  u: {$dict: user}\n  p: {$dict: pass}\nmeta:\n  dict-mode: zip\ndict:\n  user: [a, b]\n  pass: [x, y]\n",
+		},
+		{
+			name:        "pitchfork with different lengths",
+			content:     "method: GET\npath: /\nquery:\n  u: {$dict: user}\n  p: {$dict: pass}\nmeta:\n  dict-mode: pitchfork\ndict:\n  user: [a, b]\n  pass: [x]\n",
+			expectError: true,
+			errorContains: []string{
+				"test.yaml:9:9",
+				"dict.user",
+				"pitchfork mode requires arrays of the same length",
+			},
+		},
+		{
+			name:        "unknown mode",
+			content:     "method: GET\npath: /\nquery:\n  u: {$dict: user}\nmeta:\n  dict-mode: battering-ram\ndict:\n  user: [a]\n",
+			expectError: true,
+			errorContains: []string{
+				"test.yaml:6:14",
+				"unknown dict-mode 'battering-ram'",
+			},
+		},
+		{
+			name:        "sniper default for unknown key",
+			content:     "method: GET\npath: /\nquery:\n  u: {$dict: user}\nmeta:\n  dict-mode: sniper\n  dict-defaults:\n    name: guest\ndict:\n  user: [a]\n",
+			expectError: true,
+			errorContains: []string{
+				"meta.dict-defaults.name",
+				"default for unknown dict variable 'name'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewParser()
+			_, err := parser.ParseMultiple([]byte(tt.content), ".yaml", "test.yaml")
+
+			if !tt.expectError {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected error but got none")
+			}
+			for _, want := range tt.errorContains {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to contain %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
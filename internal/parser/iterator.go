@@ -0,0 +1,355 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// dictIterator はdict変数の組み合わせを1件ずつ生成する
+type dictIterator interface {
+	// Next は次の組み合わせを返す。全ての組み合わせを生成し終えた場合はfalseを返す。
+	Next() (map[string]interface{}, bool)
+}
+
+// newDictIterator はdict-modeに応じたdictIteratorを作成
+func newDictIterator(mode config.DictMode, dict map[string][]interface{}, defaults map[string]interface{}) (dictIterator, error) {
+	switch mode {
+	case "", config.DictModeProduct:
+		return newCombinationIterator(dict), nil
+	case config.DictModeZip, config.DictModePitchfork:
+		return newZipIterator(mode, dict)
+	case config.DictModeSniper:
+		return newSniperIterator(dict, defaults), nil
+	default:
+		return nil, fmt.Errorf("unknown dict-mode %q", mode)
+	}
+}
+
+// countDictCombinations はdict-modeに応じた組み合わせ数を返す。intに収まらない場合はfalseを返す。
+func countDictCombinations(mode config.DictMode, dict map[string][]interface{}) (int, bool) {
+	switch mode {
+	case config.DictModeZip, config.DictModePitchfork:
+		// 配列の長さが揃っていることはvalidateDictModeで検証済みのため、先頭のキーの配列の長さを使う
+		keys := sortedDictKeys(dict)
+		if len(keys) == 0 {
+			return 0, true
+		}
+		return len(dict[keys[0]]), true
+	case config.DictModeSniper:
+		total := 0
+		for _, array := range dict {
+			if total > math.MaxInt-len(array) {
+				return 0, false
+			}
+			total += len(array)
+		}
+		return total, true
+	default:
+		return countCombinations(dict)
+	}
+}
+
+// sortedDictKeys はdictのキーをソートして返す
+func sortedDictKeys(dict map[string][]interface{}) []string {
+	keys := make([]string, 0, len(dict))
+	for key := range dict {
+		keys = append(keys, key)
+	}
+	// 一貫した順序のためにキーをソート
+	sort.Strings(keys)
+	return keys
+}
+
+// combinationIterator はdict配列の直積を1件ずつ生成する（productモード）。
+// キーはソート順に並べ、最後のキーが最も速く変化する（オドメーター順）。
+// 生成済みの組み合わせを保持しないため、直積の大きさに関わらずメモリ使用量は一定。
+type combinationIterator struct {
+	keys    []string
+	arrays  [][]interface{}
+	indices []int
+	started bool
+	done    bool
+}
+
+// newCombinationIterator は新しいcombinationIteratorを作成
+func newCombinationIterator(dict map[string][]interface{}) *combinationIterator {
+	keys := sortedDictKeys(dict)
+	arrays := make([][]interface{}, len(keys))
+	done := false
+	for i, key := range keys {
+		arrays[i] = dict[key]
+		if len(arrays[i]) == 0 {
+			done = true // 空配列があれば組み合わせなし
+		}
+	}
+
+	return &combinationIterator{
+		keys:    keys,
+		arrays:  arrays,
+		indices: make([]int, len(keys)),
+		done:    done,
+	}
+}
+
+func (it *combinationIterator) Next() (map[string]interface{}, bool) {
+	if it.done {
+		return nil, false
+	}
+	if it.started && !it.advance() {
+		it.done = true
+		return nil, false
+	}
+	it.started = true
+
+	combination := make(map[string]interface{}, len(it.keys))
+	for i, key := range it.keys {
+		combination[key] = it.arrays[i][it.indices[i]]
+	}
+	return combination, true
+}
+
+// advance は末尾のキーから桁上がりしながら位置を進める
+func (it *combinationIterator) advance() bool {
+	for i := len(it.indices) - 1; i >= 0; i-- {
+		it.indices[i]++
+		if it.indices[i] < len(it.arrays[i]) {
+			return true
+		}
+		it.indices[i] = 0
+	}
+	return false
+}
+
+// zipIterator は全てのdict配列を同じインデックスで並行して進める（zip/pitchforkモード）。
+// 全ての配列は同じ長さである必要がある。
+type zipIterator struct {
+	dict  map[string][]interface{}
+	total int
+	index int
+}
+
+// newZipIterator は新しいzipIteratorを作成。配列の長さが異なる場合はエラーを返す。
+func newZipIterator(mode config.DictMode, dict map[string][]interface{}) (*zipIterator, error) {
+	total, _ := countDictCombinations(mode, dict)
+	for key, array := range dict {
+		if len(array) != total {
+			return nil, fmt.Errorf("%s mode requires arrays of the same length: '%s' has %d values, expected %d", mode, key, len(array), total)
+		}
+	}
+	return &zipIterator{dict: dict, total: total}, nil
+}
+
+func (it *zipIterator) Next() (map[string]interface{}, bool) {
+	if it.index >= it.total {
+		return nil, false
+	}
+	combination := make(map[string]interface{}, len(it.dict))
+	for key, array := range it.dict {
+		combination[key] = array[it.index]
+	}
+	it.index++
+	return combination, true
+}
+
+// sniperIterator はキーを1つずつ順に選び、そのキーの値だけを変化させる（sniperモード）。
+// 選ばれていないキーはdefaultsの値、未指定の場合は配列の先頭の値に固定する。
+type sniperIterator struct {
+	keys     []string
+	dict     map[string][]interface{}
+	defaults map[string]interface{}
+	key      int
+	index    int
+}
+
+// newSniperIterator は新しいsniperIteratorを作成
+func newSniperIterator(dict map[string][]interface{}, defaults map[string]interface{}) *sniperIterator {
+	base := make(map[string]interface{}, len(dict))
+	for key, array := range dict {
+		if value, ok := defaults[key]; ok {
+			base[key] = value
+		} else if len(array) > 0 {
+			base[key] = array[0]
+		}
+	}
+	return &sniperIterator{
+		keys:     sortedDictKeys(dict),
+		dict:     dict,
+		defaults: base,
+	}
+}
+
+func (it *sniperIterator) Next() (map[string]interface{}, bool) {
+	for it.key < len(it.keys) && it.index >= len(it.dict[it.keys[it.key]]) {
+		it.key++
+		it.index = 0
+	}
+	if it.key >= len(it.keys) {
+		return nil, false
+	}
+
+	combination := make(map[string]interface{}, len(it.defaults))
+	for key, value := range it.defaults {
+		combination[key] = value
+	}
+	key := it.keys[it.key]
+	combination[key] = it.dict[key][it.index]
+	it.index++
+	return combination, true
+}
+
+// countCombinations は直積の組み合わせ数を返す。intに収まらない場合はfalseを返す。
+func countCombinations(dict map[string][]interface{}) (int, bool) {
+	total := 1
+	for _, array := range dict {
+		if len(array) == 0 {
+			return 0, true
+		}
+		if total > math.MaxInt/len(array) {
+			return 0, false
+		}
+		total *= len(array)
+	}
+	return total, true
+}
+
+// RequestIterator はリクエスト定義から処理済みリクエストを1件ずつ生成する。
+// dictを使用する場合は組み合わせごとに1件、使用しない場合は1件のみを生成する。
+//
+//	for it.Next() {
+//		send(it.Index(), it.Request())
+//	}
+//	if err := it.Err(); err != nil { ... }
+type RequestIterator struct {
+	parser          *Parser
+	ctx             context.Context
+	requestConfig   *config.RequestConfig
+	baseURL         string
+	requestIDConfig *config.RequestIDConfig
+	combinations    dictIterator // dictを使用しない場合はnil
+	total           int
+	index           int
+	request         *config.ProcessedRequest
+	err             error
+	done            bool
+}
+
+// Next は次のリクエストを生成する。生成し終えた場合またはエラーの場合はfalseを返す。
+func (it *RequestIterator) Next() bool {
+	if it.done {
+		return false
+	}
+
+	if it.combinations == nil {
+		// 単一リクエストの処理
+		it.done = true
+		it.request, it.err = it.process(it.ctx)
+		return it.err == nil
+	}
+
+	combination, ok := it.combinations.Next()
+	if !ok {
+		it.done = true
+		return false
+	}
+	if it.request != nil {
+		it.index++
+	}
+
+	// 組み合わせのdict変数をコンテキストに設定してリクエストを処理
+	ctxWithDict := context.WithValue(it.ctx, "dict", combination)
+	request, err := it.process(ctxWithDict)
+	if err != nil {
+		it.done = true
+		it.err = fmt.Errorf("failed to process request with dict combination %v: %w", combination, err)
+		return false
+	}
+	request.DictValues = combination
+	it.request = request
+	return true
+}
+
+// process はリクエストを処理し、meta.signが指定されている場合は署名する
+func (it *RequestIterator) process(ctx context.Context) (*config.ProcessedRequest, error) {
+	request, err := it.parser.ProcessRequestWithRequestID(ctx, it.requestConfig, it.baseURL, it.requestIDConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := it.parser.SignRequest(ctx, it.requestConfig, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// Baseline はdictの値を無害な値に置き換えたベースラインのリクエストを生成する。
+// 値はmeta.baseline.values、meta.dict-defaults、空文字の順に決まる。
+func (it *RequestIterator) Baseline() (*config.ProcessedRequest, error) {
+	var meta config.MetaConfig
+	if it.requestConfig.Meta != nil {
+		meta = *it.requestConfig.Meta
+	}
+	var values map[string]interface{}
+	if meta.Baseline != nil {
+		values = meta.Baseline.Values
+	}
+
+	baseline := make(map[string]interface{}, len(it.requestConfig.Dict))
+	for key := range it.requestConfig.Dict {
+		if value, ok := values[key]; ok {
+			baseline[key] = value
+		} else if value, ok := meta.DictDefaults[key]; ok {
+			baseline[key] = value
+		} else {
+			baseline[key] = ""
+		}
+	}
+
+	ctxWithDict := context.WithValue(it.ctx, "dict", baseline)
+	request, err := it.process(ctxWithDict)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process baseline request: %w", err)
+	}
+	request.DictValues = baseline
+	return request, nil
+}
+
+// OAuth2 はmeta.authがoauth2の場合に、トークンの取得に使う設定とmeta.tlsを返す。
+// oauth2でない場合や、資格情報がdictの値によって変わる場合はnilを返す。
+func (it *RequestIterator) OAuth2() (*config.OAuth2Auth, *config.TLSConfig, error) {
+	meta := it.requestConfig.Meta
+	if meta == nil || meta.Auth == nil || meta.Auth.Type != config.AuthTypeOAuth2 {
+		return nil, nil, nil
+	}
+	auth := meta.Auth
+	if it.parser.hasDict(auth.TokenURL) || it.parser.hasDict(auth.ClientID) || it.parser.hasDict(auth.ClientSecret) {
+		return nil, nil, nil
+	}
+	resolved, err := it.parser.resolveAuth(it.ctx, auth)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resolved.oauth2, tlsOptions(it.requestConfig), nil
+}
+
+// Request は直前のNextで生成されたリクエストを返す
+func (it *RequestIterator) Request() *config.ProcessedRequest {
+	return it.request
+}
+
+// Index は直前のNextで生成されたリクエストの組み合わせインデックスを返す（dictなしの場合は0）
+func (it *RequestIterator) Index() int {
+	return it.index
+}
+
+// Total は生成されるリクエスト数を返す。intに収まらない場合は-1を返す。
+func (it *RequestIterator) Total() int {
+	return it.total
+}
+
+// Err は生成中に発生したエラーを返す
+func (it *RequestIterator) Err() error {
+	return it.err
+}
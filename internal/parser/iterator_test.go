@@ -0,0 +1,272 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+func TestCombinationIterator_Order(t *testing.T) {
+	dict := map[string][]interface{}{
+		"b": {"x", "y"},
+		"a": {1, 2},
+		"c": {true},
+	}
+
+	it := newCombinationIterator(dict)
+	var got []string
+	for {
+		combination, ok := it.Next()
+		if !ok {
+			break
+		}
+		got = append(got, fmt.Sprintf("%v-%v-%v", combination["a"], combination["b"], combination["c"]))
+	}
+
+	// キーのソート順で、最後のキーが最も速く変化する
+	expected := []string{"1-x-true", "1-y-true", "2-x-true", "2-y-true"}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestCombinationIterator_EmptyArray(t *testing.T) {
+	it := newCombinationIterator(map[string][]interface{}{"a": {1}, "b": {}})
+	if _, ok := it.Next(); ok {
+		t.Errorf("Expected no combinations when an array is empty")
+	}
+}
+
+func TestCountCombinations(t *testing.T) {
+	if total, ok := countCombinations(map[string][]interface{}{"a": {1, 2, 3}, "b": {1, 2}}); !ok || total != 6 {
+		t.Errorf("Expected 6, got %d (ok=%v)", total, ok)
+	}
+
+	huge := make(map[string][]interface{})
+	for i := 0; i < 40; i++ {
+		huge[fmt.Sprintf("k%02d", i)] = []interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	}
+	if _, ok := countCombinations(huge); ok {
+		t.Errorf("Expected overflow to be reported")
+	}
+}
+
+func TestIterateRequestsWithConfig(t *testing.T) {
+	newConfig := func(size int) *config.RequestConfig {
+		dict := make(map[string][]interface{})
+		query := make(map[string]interface{})
+		for i := 0; i < size; i++ {
+			key := fmt.Sprintf("k%02d", i)
+			dict[key] = []interface{}{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+			query[key] = map[string]interface{}{"$dict": key}
+		}
+		return &config.RequestConfig{Method: "GET", Path: "/", Query: query, Dict: dict}
+	}
+
+	t.Run("huge product is generated lazily", func(t *testing.T) {
+		p := NewParser()
+		// 10^30 件の直積でも、先頭から必要な分だけ生成できる
+		it, err := p.IterateRequestsWithConfig(context.Background(), newConfig(30), "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if it.Total() != -1 {
+			t.Errorf("Expected unknown total for overflowing product, got %d", it.Total())
+		}
+		for i := 0; i < 3; i++ {
+			if !it.Next() {
+				t.Fatalf("Expected request %d, got error: %v", i, it.Err())
+			}
+			if it.Index() != i {
+				t.Errorf("Expected index %d, got %d", i, it.Index())
+			}
+		}
+		if !strings.HasSuffix(it.Request().URL, "k29=c") {
+			t.Errorf("Expected last key to change fastest, got %s", it.Request().URL)
+		}
+	})
+
+	t.Run("optional cap", func(t *testing.T) {
+		p := NewParser()
+		_, err := p.IterateRequestsWithConfig(context.Background(), newConfig(3), "http://example.com", &config.CLIConfig{MaxCombinations: 999})
+		if err == nil || !strings.Contains(err.Error(), "exceed maximum limit of 999") {
+			t.Errorf("Expected limit error, got %v", err)
+		}
+
+		it, err := p.IterateRequestsWithConfig(context.Background(), newConfig(3), "http://example.com", &config.CLIConfig{MaxCombinations: 1000})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if it.Total() != 1000 {
+			t.Errorf("Expected total 1000, got %d", it.Total())
+		}
+	})
+
+	t.Run("no dict yields a single request", func(t *testing.T) {
+		p := NewParser()
+		it, err := p.IterateRequestsWithConfig(context.Background(), &config.RequestConfig{Method: "GET", Path: "/"}, "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		count := 0
+		for it.Next() {
+			count++
+			if it.Index() != 0 {
+				t.Errorf("Expected index 0, got %d", it.Index())
+			}
+		}
+		if count != 1 || it.Err() != nil {
+			t.Errorf("Expected exactly one request, got %d (err=%v)", count, it.Err())
+		}
+	})
+
+	t.Run("processing error stops iteration", func(t *testing.T) {
+		p := NewParser()
+		requestConfig := &config.RequestConfig{
+			Method: "GET",
+			Path:   map[string]interface{}{"$var": "missing"},
+			Query:  map[string]interface{}{"q": map[string]interface{}{"$dict": "q"}},
+			Dict:   map[string][]interface{}{"q": {"1", "2"}},
+		}
+		it, err := p.IterateRequestsWithConfig(context.Background(), requestConfig, "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if it.Next() {
+			t.Fatalf("Expected iteration to stop on error")
+		}
+		if it.Err() == nil {
+			t.Errorf("Expected error from Err()")
+		}
+	})
+}
+
+func TestDictModes(t *testing.T) {
+	dict := map[string][]interface{}{
+		"user": {"alice", "bob"},
+		"pass": {"p1", "p2"},
+		"id":   {1, 2, 3},
+	}
+	zipDict := map[string][]interface{}{
+		"user": {"alice", "bob"},
+		"pass": {"p1", "p2"},
+	}
+
+	tests := []struct {
+		name     string
+		mode     config.DictMode
+		dict     map[string][]interface{}
+		defaults map[string]interface{}
+		expected []string
+	}{
+		{
+			name: "product",
+			mode: config.DictModeProduct,
+			dict: zipDict,
+			// キー順（pass, user）で最後のuserが最も速く変化する
+			expected: []string{
+				"alice/p1", "bob/p1", "alice/p2", "bob/p2",
+			},
+		},
+		{
+			name:     "zip",
+			mode:     config.DictModeZip,
+			dict:     zipDict,
+			expected: []string{"alice/p1", "bob/p2"},
+		},
+		{
+			name:     "pitchfork is an alias of zip",
+			mode:     config.DictModePitchfork,
+			dict:     zipDict,
+			expected: []string{"alice/p1", "bob/p2"},
+		},
+		{
+			name: "sniper uses first values as defaults",
+			mode: config.DictModeSniper,
+			dict: dict,
+			// キー順（id, pass, user）に1つずつ変化させる
+			expected: []string{
+				"alice/p1/1", "alice/p1/2", "alice/p1/3",
+				"alice/p1/1", "alice/p2/1",
+				"alice/p1/1", "bob/p1/1",
+			},
+		},
+		{
+			name:     "sniper with explicit defaults",
+			mode:     config.DictModeSniper,
+			dict:     zipDict,
+			defaults: map[string]interface{}{"user": "guest", "pass": "x"},
+			expected: []string{
+				"guest/p1", "guest/p2", "alice/x", "bob/x",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it, err := newDictIterator(tt.mode, tt.dict, tt.defaults)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var got []string
+			for {
+				combination, ok := it.Next()
+				if !ok {
+					break
+				}
+				value := fmt.Sprintf("%v/%v", combination["user"], combination["pass"])
+				if id, ok := combination["id"]; ok {
+					value += fmt.Sprintf("/%v", id)
+				}
+				got = append(got, value)
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+			if total, ok := countDictCombinations(tt.mode, tt.dict); !ok || total != len(tt.expected) {
+				t.Errorf("Expected count %d, got %d (ok=%v)", len(tt.expected), total, ok)
+			}
+		})
+	}
+
+	if _, err := newDictIterator("battering-ram", dict, nil); err == nil {
+		t.Errorf("Expected error for unknown mode")
+	}
+	// 長さの異なる配列は検証で拒否されるため、短い配列に合わせて切り詰めずにエラーにする
+	if _, err := newDictIterator(config.DictModeZip, dict, nil); err == nil || !strings.Contains(err.Error(), "zip mode requires arrays of the same length") {
+		t.Errorf("Expected error for arrays of different lengths, got %v", err)
+	}
+}
+
+func TestIterateRequestsWithConfig_DictMode(t *testing.T) {
+	p := NewParser()
+	requestConfig := &config.RequestConfig{
+		Method: "POST",
+		Path:   "/login",
+		Params: map[string]interface{}{
+			"user": map[string]interface{}{"$dict": "user"},
+			"pass": map[string]interface{}{"$dict": "pass"},
+		},
+		Dict: map[string][]interface{}{
+			"user": {"admin", "root"},
+			"pass": {"admin123", "toor"},
+		},
+		Meta: &config.MetaConfig{DictMode: config.DictModePitchfork},
+	}
+
+	requests, err := p.ProcessRequestsWithConfig(context.Background(), requestConfig, "http://example.com", &config.CLIConfig{MaxCombinations: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	if requests[0].Body != "pass=admin123&user=admin" || requests[1].Body != "pass=toor&user=root" {
+		t.Errorf("Unexpected bodies: %q, %q", requests[0].Body, requests[1].Body)
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/secureta/s2http-request/internal/config"
+	"github.com/secureta/s2http-request/internal/response"
 	"github.com/secureta/s2http-request/pkg/functions"
 	"gopkg.in/yaml.v3"
 )
@@ -25,6 +28,16 @@ func NewParser() *Parser {
 	}
 }
 
+// RegisterFunction はプラグインなどの関数を$関数として使用できるようにする
+func (p *Parser) RegisterFunction(fn functions.Function) error {
+	return p.registry.Register(fn)
+}
+
+// Functions は使用できる関数の情報を返す
+func (p *Parser) Functions() []functions.FunctionInfo {
+	return p.registry.GetFunctionInfo()
+}
+
 // ParseMultiple はファイル内容を解析して複数のRequestConfigを返す
 func (p *Parser) ParseMultiple(data []byte, fileExt string, filePath string) ([]*config.RequestConfig, error) {
 	var configs []*config.RequestConfig
@@ -155,6 +168,10 @@ func (p *Parser) processPath(ctx context.Context, value interface{}) (path strin
 
 // ProcessRequest はリクエスト設定を処理してProcessedRequestを返す
 func (p *Parser) ProcessRequest(ctx context.Context, requestConfig *config.RequestConfig, baseURL string) (*config.ProcessedRequest, error) {
+	if requestConfig.Raw != nil {
+		return p.processRaw(ctx, requestConfig, baseURL)
+	}
+
 	// Pathの処理
 	pathStr, rawPath, err := p.processPath(ctx, requestConfig.Path)
 	if err != nil {
@@ -248,6 +265,7 @@ func (p *Parser) ProcessRequest(ctx context.Context, requestConfig *config.Reque
 		RawRequestTarget: rawRequestTarget,
 		Headers:          headers,
 		Body:             body,
+		TLS:              tlsOptions(requestConfig),
 	}, nil
 }
 
@@ -348,6 +366,11 @@ func (p *Parser) validateAllConfigurations(configs []*config.RequestConfig, file
 	errorCollection := NewErrorCollection()
 
 	for i, requestConfig := range configs {
+		// Load dict values from external sources ($lines, $csv, $jsonl)
+		if err := p.loadDictSources(requestConfig, filePath, fileExt, content); err != nil {
+			errorCollection.Add(err)
+		}
+
 		// Validate dict configuration
 		if requestConfig.Dict != nil {
 			if err := p.validateDictWithPosition(requestConfig.Dict, filePath, fileExt, content); err != nil {
@@ -359,6 +382,61 @@ func (p *Parser) validateAllConfigurations(configs []*config.RequestConfig, file
 		if err := p.validateDictReferences(requestConfig, filePath, fileExt, content, i); err != nil {
 			errorCollection.Add(err)
 		}
+
+		// Validate dict-mode and its requirements
+		if err := p.validateDictMode(requestConfig, filePath, fileExt, content); err != nil {
+			errorCollection.Add(err)
+		}
+
+		// Validate function names
+		if err := p.validateFunctions(requestConfig, filePath, fileExt, content); err != nil {
+			errorCollection.Add(err)
+		}
+
+		// Validate extract rules
+		if err := p.validateExtract(requestConfig, filePath, fileExt, content); err != nil {
+			errorCollection.Add(err)
+		}
+
+		// Validate expect assertions
+		if err := p.validateExpect(requestConfig, filePath, fileExt, content); err != nil {
+			errorCollection.Add(err)
+		}
+
+		// Validate verdict rules and load meta.verdict.rules-file
+		if err := p.validateVerdict(requestConfig, filePath, fileExt, content); err != nil {
+			errorCollection.Add(err)
+		}
+
+		// Validate authentication settings
+		if err := p.validateAuth(requestConfig, filePath, fileExt, content); err != nil {
+			errorCollection.Add(err)
+		}
+
+		// Validate request signing settings
+		if err := p.validateSign(requestConfig, filePath, fileExt, content); err != nil {
+			errorCollection.Add(err)
+		}
+
+		// Validate baseline values and thresholds
+		if err := p.validateBaseline(requestConfig, filePath, fileExt, content); err != nil {
+			errorCollection.Add(err)
+		}
+
+		// Validate hook commands
+		if err := p.validateHooks(requestConfig, filePath, fileExt, content); err != nil {
+			errorCollection.Add(err)
+		}
+
+		// Validate TLS versions and client certificate settings
+		if err := p.validateTLS(requestConfig, filePath, fileExt, content); err != nil {
+			errorCollection.Add(err)
+		}
+
+		// Validate raw options and fields that cannot be combined with raw
+		if err := p.validateRaw(requestConfig, filePath, fileExt, content); err != nil {
+			errorCollection.Add(err)
+		}
 	}
 
 	return errorCollection.ToError()
@@ -373,6 +451,11 @@ func (p *Parser) validateDictReferences(requestConfig *config.RequestConfig, fil
 
 	// Check if each reference has a corresponding definition
 	for _, ref := range dictRefs {
+		// 外部ソースの読み込みエラーはloadDictSourcesで報告済み
+		if _, isSource := requestConfig.DictSources[ref.VariableName]; isSource {
+			continue
+		}
+
 		if requestConfig.Dict == nil {
 			err := p.createDictReferenceError(filePath, ref.PropertyPath, ref.VariableName, "no dict variables are defined")
 			errorCollection.Add(err)
@@ -400,6 +483,54 @@ func (p *Parser) validateDictReferences(requestConfig *config.RequestConfig, fil
 	return errorCollection.ToError()
 }
 
+// validateFunctions reports $function calls that are neither built-in nor provided by a plugin
+func (p *Parser) validateFunctions(requestConfig *config.RequestConfig, filePath string, fileExt string, content string) error {
+	errs := make(map[string]error)
+	values := map[string]interface{}{
+		"path":    requestConfig.Path,
+		"query":   requestConfig.Query,
+		"headers": requestConfig.Headers,
+		"params":  requestConfig.Params,
+		"body":    requestConfig.Body,
+		"raw":     requestConfig.Raw,
+	}
+	for name, value := range requestConfig.Variables {
+		values["variables."+name] = value
+	}
+	for name, value := range requestConfig.Dict {
+		values["dict."+name] = value
+	}
+	for basePath, value := range values {
+		p.findUnknownFunctions(value, basePath, errs)
+	}
+	return p.createPropertyErrors(errs, "", filePath, fileExt, content)
+}
+
+// findUnknownFunctions recursively records unknown function calls in a value, keyed by property path
+func (p *Parser) findUnknownFunctions(value interface{}, basePath string, errs map[string]error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 1 {
+			for key, args := range v {
+				if strings.HasPrefix(key, "$") {
+					if _, exists := p.registry.Get(key[1:]); !exists {
+						errs[basePath] = fmt.Errorf("unknown function: %s", key[1:])
+					}
+					p.findUnknownFunctions(args, basePath+"."+key, errs)
+					return
+				}
+			}
+		}
+		for k, val := range v {
+			p.findUnknownFunctions(val, basePath+"."+k, errs)
+		}
+	case []interface{}:
+		for i, item := range v {
+			p.findUnknownFunctions(item, fmt.Sprintf("%s[%d]", basePath, i), errs)
+		}
+	}
+}
+
 // DictReference represents a reference to a dict variable
 type DictReference struct {
 	PropertyPath string
@@ -416,6 +547,7 @@ func (p *Parser) findAllDictReferences(requestConfig *config.RequestConfig) []Di
 	refs = append(refs, p.findDictReferencesInValue(requestConfig.Headers, "headers")...)
 	refs = append(refs, p.findDictReferencesInValue(requestConfig.Params, "params")...)
 	refs = append(refs, p.findDictReferencesInValue(requestConfig.Body, "body")...)
+	refs = append(refs, p.findDictReferencesInValue(requestConfig.Raw, "raw")...)
 
 	return refs
 }
@@ -470,11 +602,31 @@ func (p *Parser) createDictReferenceError(filePath string, propertyPath string,
 
 // ProcessRequestsWithConfig はCLI設定を使用してリクエストを処理する
 func (p *Parser) ProcessRequestsWithConfig(ctx context.Context, requestConfig *config.RequestConfig, baseURL string, cliConfig *config.CLIConfig) ([]*config.ProcessedRequest, error) {
+	it, err := p.IterateRequestsWithConfig(ctx, requestConfig, baseURL, cliConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*config.ProcessedRequest
+	for it.Next() {
+		results = append(results, it.Request())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// IterateRequestsWithConfig はCLI設定を使用してリクエストを1件ずつ生成するイテレーターを返す。
+// dictの組み合わせは必要になるまで生成されない。
+// cliConfig.MaxCombinationsが正の場合、組み合わせ数がそれを超えるとエラーを返す（0の場合は無制限）。
+func (p *Parser) IterateRequestsWithConfig(ctx context.Context, requestConfig *config.RequestConfig, baseURL string, cliConfig *config.CLIConfig) (*RequestIterator, error) {
 	// Request IDの設定を決定（リクエスト定義ファイル > CLI設定）
 	var requestIDConfig *config.RequestIDConfig
 	if requestConfig.Meta != nil && requestConfig.Meta.RequestID != nil {
 		requestIDConfig = requestConfig.Meta.RequestID
-	} else if cliConfig != nil && cliConfig.RequestID != nil {
+	} else if cliConfig != nil && cliConfig.RequestID != nil && requestConfig.Raw == nil {
+		// rawのリクエストは変更しないため、--request-idは適用しない（meta.request-idは検証時にエラーになる）
 		requestIDConfig = cliConfig.RequestID
 	}
 
@@ -507,24 +659,50 @@ func (p *Parser) ProcessRequestsWithConfig(ctx context.Context, requestConfig *c
 		ctxWithVars = context.WithValue(ctx, "variables", finalVars)
 	}
 
-	// Dict変数が存在し、実際に使用されている場合は複数リクエストを処理
-	if requestConfig.Dict != nil && len(requestConfig.Dict) > 0 {
-		// Dict変数が実際に使用されているかチェック
-		if p.hasDictReferences(requestConfig) {
-			maxCombinations := 1000 // デフォルト値
-			if cliConfig != nil {
-				maxCombinations = cliConfig.MaxCombinations
+	it := &RequestIterator{
+		parser:          p,
+		ctx:             ctxWithVars,
+		requestConfig:   requestConfig,
+		baseURL:         baseURL,
+		requestIDConfig: requestIDConfig,
+		total:           1,
+	}
+
+	// Dict変数が存在し、実際に使用されている場合は組み合わせごとにリクエストを生成
+	if len(requestConfig.Dict) > 0 && p.hasDictReferences(requestConfig) {
+		maxCombinations := 0 // 0は無制限
+		if cliConfig != nil {
+			maxCombinations = cliConfig.MaxCombinations
+		}
+
+		// dict-modeに応じて組み合わせ方を決定（デフォルトは直積）
+		var mode config.DictMode
+		var defaults map[string]interface{}
+		if requestConfig.Meta != nil {
+			mode = requestConfig.Meta.DictMode
+			defaults = requestConfig.Meta.DictDefaults
+		}
+
+		total, ok := countDictCombinations(mode, requestConfig.Dict)
+		if maxCombinations > 0 && (!ok || total > maxCombinations) {
+			return nil, fmt.Errorf("dict combinations exceed maximum limit of %d", maxCombinations)
+		}
+		if !ok {
+			total = -1
+		}
+
+		// 空配列で組み合わせがない場合は単一リクエストとして処理
+		if total != 0 {
+			combinations, err := newDictIterator(mode, requestConfig.Dict, defaults)
+			if err != nil {
+				return nil, err
 			}
-			return p.processRequestsWithDictCombinationsAndLimit(ctxWithVars, requestConfig, baseURL, requestIDConfig, maxCombinations)
+			it.combinations = combinations
+			it.total = total
 		}
 	}
 
-	// 単一リクエストの処理
-	pr, err := p.ProcessRequestWithRequestID(ctxWithVars, requestConfig, baseURL, requestIDConfig)
-	if err != nil {
-		return nil, err
-	}
-	return []*config.ProcessedRequest{pr}, nil
+	return it, nil
 }
 
 // ProcessRequestsWithRequestID はRequest ID機能付きでリクエストを処理する
@@ -569,11 +747,19 @@ func (p *Parser) ProcessRequestsWithRequestID(ctx context.Context, requestConfig
 	if err != nil {
 		return nil, err
 	}
+	if err := p.SignRequest(ctxWithVars, requestConfig, pr); err != nil {
+		return nil, err
+	}
 	return []*config.ProcessedRequest{pr}, nil
 }
 
 // ProcessRequestWithRequestID はRequest ID機能付きでリクエストを処理する
 func (p *Parser) ProcessRequestWithRequestID(ctx context.Context, requestConfig *config.RequestConfig, baseURL string, requestIDConfig *config.RequestIDConfig) (*config.ProcessedRequest, error) {
+	// rawのリクエストはそのまま送信するため、Request IDは付与しない
+	if requestConfig.Raw != nil {
+		return p.processRaw(ctx, requestConfig, baseURL)
+	}
+
 	// Request IDを生成
 	var requestID string
 	if requestIDConfig != nil {
@@ -603,6 +789,15 @@ func (p *Parser) ProcessRequestWithRequestID(ctx context.Context, requestConfig
 	// コンテキストにリクエストファイルのパスを設定
 	ctx = context.WithValue(ctx, "requestFilePath", requestConfig.FilePath)
 
+	// meta.authの評価（資格情報には変数を使用できる）
+	var auth *resolvedAuth
+	if requestConfig.Meta != nil && requestConfig.Meta.Auth != nil {
+		auth, err = p.resolveAuth(ctx, requestConfig.Meta.Auth)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// クエリパラメータの処理
 	queryParams := make(map[string]interface{})
 	if requestConfig.Query != nil {
@@ -617,6 +812,9 @@ func (p *Parser) ProcessRequestWithRequestID(ctx context.Context, requestConfig
 	if requestIDConfig != nil && requestIDConfig.Location == config.RequestIDLocationQuery {
 		queryParams[requestIDConfig.Key] = requestID
 	}
+	if auth != nil {
+		auth.applyQuery(queryParams)
+	}
 
 	if len(queryParams) > 0 {
 		processedQuery, err := p.processMap(ctx, queryParams)
@@ -649,6 +847,9 @@ func (p *Parser) ProcessRequestWithRequestID(ctx context.Context, requestConfig
 	if requestIDConfig != nil && requestIDConfig.Location == config.RequestIDLocationHeader {
 		headerParams[requestIDConfig.Key] = requestID
 	}
+	if auth != nil {
+		auth.applyHeader(headerParams)
+	}
 
 	if len(headerParams) > 0 {
 		processedHeaders, err := p.processMap(ctx, headerParams)
@@ -698,14 +899,19 @@ func (p *Parser) ProcessRequestWithRequestID(ctx context.Context, requestConfig
 		}
 	}
 
-	return &config.ProcessedRequest{
+	request := &config.ProcessedRequest{
 		Method:           requestConfig.Method,
 		URL:              fullURL,
 		RawRequestTarget: rawRequestTarget,
 		Headers:          headers,
 		Body:             body,
 		RequestID:        requestID,
-	}, nil
+		TLS:              tlsOptions(requestConfig),
+	}
+	if auth != nil {
+		auth.applyRequest(request)
+	}
+	return request, nil
 }
 
 // ProcessRequests はリクエストを処理して返す
@@ -809,6 +1015,176 @@ func (p *Parser) validateDictWithPosition(dict map[string][]interface{}, filePat
 	return errorCollection.ToError()
 }
 
+// validateDictMode validates meta.dict-mode and meta.dict-defaults against the dict definition
+func (p *Parser) validateDictMode(requestConfig *config.RequestConfig, filePath string, fileExt string, content string) error {
+	if requestConfig.Meta == nil {
+		return nil
+	}
+
+	var tracker *PositionTracker
+	if content != "" {
+		tracker = NewPositionTracker(filePath, []byte(content))
+	}
+	positionOf := func(propertyPath string) *PositionInfo {
+		if tracker == nil {
+			return nil
+		}
+		return tracker.GetPosition(propertyPath, fileExt)
+	}
+
+	errorCollection := NewErrorCollection()
+	mode := requestConfig.Meta.DictMode
+
+	switch mode {
+	case "", config.DictModeProduct, config.DictModeSniper:
+	case config.DictModeZip, config.DictModePitchfork:
+		// 長さの異なる配列は一部の値が送信されないため、エラーとして報告する
+		keys := make([]string, 0, len(requestConfig.Dict))
+		for key := range requestConfig.Dict {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for i := 1; i < len(keys); i++ {
+			key := keys[i]
+			first, array := requestConfig.Dict[keys[0]], requestConfig.Dict[key]
+			if len(array) != len(first) {
+				propertyPath := fmt.Sprintf("dict.%s", key)
+				errorCollection.Add(p.createDictValidationError(filePath, positionOf(propertyPath), propertyPath, key,
+					fmt.Sprintf("%s mode requires arrays of the same length: '%s' has %d values but '%s' has %d", mode, key, len(array), keys[0], len(first))))
+			}
+		}
+	default:
+		parseErr := &ParseError{
+			FilePath:     filePath,
+			PropertyPath: "meta.dict-mode",
+			Message:      fmt.Sprintf("unknown dict-mode '%s', expected one of: product, zip, pitchfork, sniper", mode),
+			Level:        ErrorLevelError,
+		}
+		if position := positionOf("meta.dict-mode"); position != nil {
+			parseErr.LineNumber = position.Line
+			parseErr.ColumnNumber = position.Column
+		}
+		errorCollection.Add(parseErr)
+	}
+
+	for key, value := range requestConfig.Meta.DictDefaults {
+		propertyPath := fmt.Sprintf("meta.dict-defaults.%s", key)
+		if _, exists := requestConfig.Dict[key]; !exists {
+			errorCollection.Add(p.createDictValidationError(filePath, positionOf(propertyPath), propertyPath, key,
+				fmt.Sprintf("default for unknown dict variable '%s'", key)))
+			continue
+		}
+		if !p.isPrimitiveValue(value) {
+			errorCollection.Add(p.createDictValidationError(filePath, positionOf(propertyPath), propertyPath, key,
+				fmt.Sprintf("default must be a primitive value (string, number, boolean), got %T", value)))
+		}
+	}
+
+	return errorCollection.ToError()
+}
+
+// validateExtract validates the extract rules of a request configuration
+func (p *Parser) validateExtract(requestConfig *config.RequestConfig, filePath string, fileExt string, content string) error {
+	errs := make(map[string]error)
+	for name, rule := range requestConfig.Extract {
+		if err := response.ValidateExtractRule(rule); err != nil {
+			errs[name] = err
+		}
+	}
+	return p.createPropertyErrors(errs, "extract", filePath, fileExt, content)
+}
+
+// validateExpect validates the expect assertions of a request configuration
+func (p *Parser) validateExpect(requestConfig *config.RequestConfig, filePath string, fileExt string, content string) error {
+	return p.createPropertyErrors(response.ValidateExpect(requestConfig.Expect), "expect", filePath, fileExt, content)
+}
+
+// validateBaseline validates meta.baseline values against the dict definition and its thresholds
+func (p *Parser) validateBaseline(requestConfig *config.RequestConfig, filePath string, fileExt string, content string) error {
+	if requestConfig.Meta == nil || requestConfig.Meta.Baseline == nil {
+		return nil
+	}
+	baselineConfig := requestConfig.Meta.Baseline
+
+	errs := response.ValidateBaseline(baselineConfig)
+	for key, value := range baselineConfig.Values {
+		if _, exists := requestConfig.Dict[key]; !exists {
+			errs["values."+key] = fmt.Errorf("baseline value for unknown dict variable '%s'", key)
+			continue
+		}
+		if !p.isPrimitiveValue(value) {
+			errs["values."+key] = fmt.Errorf("baseline value must be a primitive value (string, number, boolean), got %T", value)
+		}
+	}
+	return p.createPropertyErrors(errs, "meta.baseline", filePath, fileExt, content)
+}
+
+// validateHooks validates the commands and timeouts of meta.hooks
+func (p *Parser) validateHooks(requestConfig *config.RequestConfig, filePath string, fileExt string, content string) error {
+	if requestConfig.Meta == nil || requestConfig.Meta.Hooks == nil {
+		return nil
+	}
+	errs := make(map[string]error)
+	for name, hook := range map[string]*config.HookConfig{"pre": requestConfig.Meta.Hooks.Pre, "post": requestConfig.Meta.Hooks.Post} {
+		if hook == nil {
+			continue
+		}
+		if len(hook.Command) == 0 || hook.Command[0] == "" {
+			errs[name+".command"] = fmt.Errorf("command is required for the %s hook", name)
+		}
+		if hook.Timeout != "" {
+			if timeout, err := time.ParseDuration(hook.Timeout); err != nil || timeout <= 0 {
+				errs[name+".timeout"] = fmt.Errorf("invalid timeout '%s', expected a positive duration such as 5s", hook.Timeout)
+			}
+		}
+	}
+	return p.createPropertyErrors(errs, "meta.hooks", filePath, fileExt, content)
+}
+
+// createPropertyErrors converts errors keyed by property name under basePath into ParseErrors with position information.
+// An empty property name reports the error at basePath itself, and an empty basePath uses the property names as paths.
+func (p *Parser) createPropertyErrors(errs map[string]error, basePath string, filePath string, fileExt string, content string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	var tracker *PositionTracker
+	if content != "" {
+		tracker = NewPositionTracker(filePath, []byte(content))
+	}
+
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errorCollection := NewErrorCollection()
+	for _, name := range names {
+		propertyPath := basePath
+		if name != "" && basePath != "" {
+			propertyPath = fmt.Sprintf("%s.%s", basePath, name)
+		} else if name != "" {
+			propertyPath = name
+		}
+		parseErr := &ParseError{
+			FilePath:     filePath,
+			PropertyPath: propertyPath,
+			Message:      errs[name].Error(),
+			Level:        ErrorLevelError,
+		}
+		if tracker != nil {
+			if position := tracker.GetPosition(propertyPath, fileExt); position != nil {
+				parseErr.LineNumber = position.Line
+				parseErr.ColumnNumber = position.Column
+			}
+		}
+		errorCollection.Add(parseErr)
+	}
+
+	return errorCollection.ToError()
+}
+
 // createDictValidationError creates a DictValidationError with position information
 func (p *Parser) createDictValidationError(filePath string, position *PositionInfo, propertyPath string, dictKey string, message string) *DictValidationError {
 	var lineNumber, columnNumber int
@@ -852,63 +1228,22 @@ func (p *Parser) generateDictCombinationsWithLimit(dict map[string][]interface{}
 	}
 
 	// 組み合わせ数を事前に計算
-	totalCombinations := 1
-	for _, array := range dict {
-		if len(array) == 0 {
-			return []map[string]interface{}{} // 空配列があれば組み合わせなし
-		}
-		totalCombinations *= len(array)
-
-		// オーバーフロー防止と早期終了
-		if maxCombinations > 0 && totalCombinations > maxCombinations {
-			return nil // 制限を超える場合はnilを返す
-		}
-	}
-
-	// キーと配列を分離（メモリ効率のため事前にソート）
-	keys := make([]string, 0, len(dict))
-	for key := range dict {
-		keys = append(keys, key)
-	}
-
-	// 一貫した順序のためにキーをソート
-	for i := 0; i < len(keys)-1; i++ {
-		for j := i + 1; j < len(keys); j++ {
-			if keys[i] > keys[j] {
-				keys[i], keys[j] = keys[j], keys[i]
-			}
-		}
-	}
-
-	arrays := make([][]interface{}, len(keys))
-	for i, key := range keys {
-		arrays[i] = dict[key]
+	totalCombinations, ok := countCombinations(dict)
+	if maxCombinations > 0 && (!ok || totalCombinations > maxCombinations) {
+		return nil // 制限を超える場合はnilを返す
 	}
 
-	// 直積を計算
 	combinations := make([]map[string]interface{}, 0, totalCombinations)
-	p.generateCombinationsRecursive(keys, arrays, 0, make(map[string]interface{}), &combinations)
-
-	return combinations
-}
-
-// generateCombinationsRecursive は再帰的に組み合わせを生成する
-func (p *Parser) generateCombinationsRecursive(keys []string, arrays [][]interface{}, index int, current map[string]interface{}, result *[]map[string]interface{}) {
-	if index == len(keys) {
-		// 現在の組み合わせをコピーして結果に追加
-		combination := make(map[string]interface{})
-		for k, v := range current {
-			combination[k] = v
+	it := newCombinationIterator(dict)
+	for {
+		combination, ok := it.Next()
+		if !ok {
+			break
 		}
-		*result = append(*result, combination)
-		return
+		combinations = append(combinations, combination)
 	}
 
-	// 現在のインデックスの配列の各要素について再帰
-	for _, value := range arrays[index] {
-		current[keys[index]] = value
-		p.generateCombinationsRecursive(keys, arrays, index+1, current, result)
-	}
+	return combinations
 }
 
 // processVariables は変数を依存関係を考慮して処理
@@ -972,7 +1307,8 @@ func (p *Parser) hasDictReferences(requestConfig *config.RequestConfig) bool {
 		p.hasDict(requestConfig.Query) ||
 		p.hasDict(requestConfig.Headers) ||
 		p.hasDict(requestConfig.Params) ||
-		p.hasDict(requestConfig.Body)
+		p.hasDict(requestConfig.Body) ||
+		p.hasDict(requestConfig.Raw)
 }
 
 // hasDict recursively checks if a value contains $dict references
@@ -1004,39 +1340,6 @@ func (p *Parser) hasDict(value interface{}) bool {
 	return false
 }
 
-// processRequestsWithDictCombinationsAndLimit processes requests with dict combinations and limit
-func (p *Parser) processRequestsWithDictCombinationsAndLimit(ctx context.Context, requestConfig *config.RequestConfig, baseURL string, requestIDConfig *config.RequestIDConfig, maxCombinations int) ([]*config.ProcessedRequest, error) {
-	// Generate dict combinations with limit
-	combinations := p.generateDictCombinationsWithLimit(requestConfig.Dict, maxCombinations)
-	if combinations == nil {
-		return nil, fmt.Errorf("dict combinations exceed maximum limit of %d", maxCombinations)
-	}
-	if len(combinations) == 0 {
-		// No combinations, process as single request
-		pr, err := p.ProcessRequestWithRequestID(ctx, requestConfig, baseURL, requestIDConfig)
-		if err != nil {
-			return nil, err
-		}
-		return []*config.ProcessedRequest{pr}, nil
-	}
-
-	// Process each combination
-	var results []*config.ProcessedRequest
-	for _, combination := range combinations {
-		// Create context with dict variables for this combination
-		ctxWithDict := context.WithValue(ctx, "dict", combination)
-
-		// Process the request with this combination
-		pr, err := p.ProcessRequestWithRequestID(ctxWithDict, requestConfig, baseURL, requestIDConfig)
-		if err != nil {
-			return nil, fmt.Errorf("failed to process request with dict combination %v: %w", combination, err)
-		}
-		results = append(results, pr)
-	}
-
-	return results, nil
-}
-
 // processRequestsWithDictCombinations processes requests with dict combinations
 func (p *Parser) processRequestsWithDictCombinations(ctx context.Context, requestConfig *config.RequestConfig, baseURL string) ([]*config.ProcessedRequest, error) {
 	// Generate dict combinations
@@ -2,7 +2,13 @@ package parser
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"net/url"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/secureta/s2http-request/internal/config"
@@ -713,3 +719,576 @@ func TestVariableOverrideWithProcessRequests(t *testing.T) {
 		t.Errorf("Expected URL %s, got %s", expectedURL, result.URL)
 	}
 }
+
+func TestParseExtract(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		errorContains []string
+	}{
+		{
+			name:    "valid rules",
+			content: "method: POST\npath: /login\nextract:\n  token: {json: data.token}\n  csrf: {regex: 'name=\"csrf\" value=\"([^\"]+)\"'}\n  session: {cookie: SESSIONID}\n  next: {header: Location, regex: 'id=(\\d+)', group: 1}\n",
+		},
+		{
+			name:          "invalid regex",
+			content:       "method: GET\npath: /\nextract:\n  csrf: {regex: '('}\n",
+			errorContains: []string{"test.yaml:4:9", "extract.csrf", "invalid regex"},
+		},
+		{
+			name:          "multiple sources",
+			content:       "method: GET\npath: /\nextract:\n  token: {json: token, header: X-Token}\n",
+			errorContains: []string{"extract.token", "only one of json, header, cookie"},
+		},
+		{
+			name:          "missing source",
+			content:       "method: GET\npath: /\nextract:\n  token: {default: x}\n",
+			errorContains: []string{"extract.token", "must specify one of"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewParser()
+			configs, err := parser.ParseMultiple([]byte(tt.content), ".yaml", "test.yaml")
+			if len(tt.errorContains) == 0 {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if len(configs[0].Extract) != 4 || configs[0].Extract["next"].Group == nil {
+					t.Errorf("Expected 4 extract rules, got %+v", configs[0].Extract)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected error but got none")
+			}
+			for _, want := range tt.errorContains {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to contain %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseExpect(t *testing.T) {
+	t.Run("yaml accepts a single string or a list", func(t *testing.T) {
+		content := "method: GET\npath: /\nexpect:\n  status: [2xx, 301]\n  body-contains: welcome\n  body-regex: ['id=\\d+', 'ok']\n  json:\n    data.id: 1\n  max-time: 500ms\n"
+		configs, err := NewParser().ParseMultiple([]byte(content), ".yaml", "test.yaml")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expect := configs[0].Expect
+		if len(expect.BodyContains) != 1 || expect.BodyContains[0] != "welcome" || len(expect.BodyRegex) != 2 {
+			t.Errorf("Unexpected body assertions: %+v", expect)
+		}
+	})
+
+	t.Run("json accepts a single string or a list", func(t *testing.T) {
+		content := `{"method":"GET","path":"/","expect":{"status":200,"body-contains":["a","b"],"body-not-contains":"error"}}`
+		configs, err := NewParser().ParseMultiple([]byte(content), ".json", "test.json")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expect := configs[0].Expect
+		if len(expect.BodyContains) != 2 || len(expect.BodyNotContains) != 1 || expect.BodyNotContains[0] != "error" {
+			t.Errorf("Unexpected body assertions: %+v", expect)
+		}
+	})
+
+	t.Run("invalid assertions are reported with positions", func(t *testing.T) {
+		content := "method: GET\npath: /\nexpect:\n  status: 2x\n  max-time: soon\n"
+		_, err := NewParser().ParseMultiple([]byte(content), ".yaml", "test.yaml")
+		if err == nil {
+			t.Fatalf("Expected error but got none")
+		}
+		for _, want := range []string{"test.yaml:4:11", "expect.status", "invalid status '2x'", "test.yaml:5:13", "expect.max-time"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error to contain %q, got: %v", want, err)
+			}
+		}
+	})
+}
+
+func TestParseBaseline(t *testing.T) {
+	t.Run("baseline request uses benign values", func(t *testing.T) {
+		content := "method: GET\npath: /\nquery:\n  q: {$dict: payload}\n  lang: {$dict: lang}\n  page: {$dict: page}\ndict:\n  payload: [\"<script>\"]\n  lang: [en, ja]\n  page: [1, 2]\nmeta:\n  dict-defaults:\n    lang: ja\n  baseline:\n    values:\n      payload: hello\n"
+		p := NewParser()
+		configs, err := p.ParseMultiple([]byte(content), ".yaml", "test.yaml")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		it, err := p.IterateRequestsWithConfig(context.Background(), configs[0], "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		baseline, err := it.Baseline()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if baseline.URL != "http://example.com/?lang=ja&page=&q=hello" {
+			t.Errorf("Unexpected baseline URL: %s", baseline.URL)
+		}
+		if baseline.DictValues["payload"] != "hello" {
+			t.Errorf("Unexpected baseline dict values: %v", baseline.DictValues)
+		}
+	})
+
+	t.Run("invalid baseline is reported with positions", func(t *testing.T) {
+		content := "method: GET\npath: /\nquery:\n  q: {$dict: payload}\ndict:\n  payload: [a]\nmeta:\n  baseline:\n    values:\n      other: x\n    min-similarity: 2\n"
+		_, err := NewParser().ParseMultiple([]byte(content), ".yaml", "test.yaml")
+		if err == nil {
+			t.Fatalf("Expected error but got none")
+		}
+		for _, want := range []string{"test.yaml:10:14", "meta.baseline.values.other", "unknown dict variable 'other'", "test.yaml:11:21", "min-similarity must be between 0 and 1"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error to contain %q, got: %v", want, err)
+			}
+		}
+	})
+}
+
+func TestParseAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		auth       string
+		url        string
+		header     string
+		headerName string
+		secret     string
+		digest     *config.DigestAuth
+		oauth2     *config.OAuth2Auth
+	}{
+		{
+			name:       "basic from variables",
+			auth:       "type: basic\n    username: {$var: user}\n    password: {$var: pass}",
+			url:        "http://example.com/",
+			headerName: "Authorization",
+			header:     "Basic YWRtaW46Y2xpLXBhc3M=",
+			secret:     "YWRtaW46Y2xpLXBhc3M=",
+		},
+		{
+			name:       "bearer overrides an explicit header",
+			auth:       "type: bearer\n    token: t0ken",
+			url:        "http://example.com/",
+			headerName: "Authorization",
+			header:     "Bearer t0ken",
+			secret:     "t0ken",
+		},
+		{
+			name:       "api-key header",
+			auth:       "type: api-key\n    name: X-API-Key\n    value: {$var: pass}",
+			url:        "http://example.com/",
+			headerName: "X-API-Key",
+			header:     "cli-pass",
+			secret:     "cli-pass",
+		},
+		{
+			name:   "api-key query",
+			auth:   "type: api-key\n    name: api_key\n    value: k&v\n    in: query",
+			url:    "http://example.com/?api_key=k%26v",
+			secret: "k&v",
+		},
+		{
+			name:   "digest",
+			auth:   "type: digest\n    username: {$var: user}\n    password: {$var: pass}",
+			url:    "http://example.com/",
+			digest: &config.DigestAuth{Username: "admin", Password: "cli-pass"},
+		},
+		{
+			name:   "oauth2 replaces an explicit header",
+			auth:   "type: oauth2\n    token-url: https://auth.example.com/token\n    client-id: {$var: user}\n    client-secret: {$var: pass}\n    scopes: [read, write]",
+			url:    "http://example.com/",
+			oauth2: &config.OAuth2Auth{TokenURL: "https://auth.example.com/token", ClientID: "admin", ClientSecret: "cli-pass", Scope: "read write"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "method: GET\npath: /\nheaders:\n  authorization: manual\nvariables:\n  user: admin\n  pass: file-pass\nmeta:\n  auth:\n    " + tt.auth + "\n"
+			p := NewParser()
+			configs, err := p.ParseMultiple([]byte(content), ".yaml", "test.yaml")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			// CLIの変数で資格情報を上書きできる
+			ctx := context.WithValue(context.Background(), "variables", map[string]interface{}{"pass": "cli-pass"})
+			requests, err := p.ProcessRequestsWithConfig(ctx, configs[0], "http://example.com", nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			request := requests[0]
+
+			if request.URL != tt.url {
+				t.Errorf("Expected URL %s, got %s", tt.url, request.URL)
+			}
+			if tt.headerName != "" {
+				if request.Headers[tt.headerName] != tt.header {
+					t.Errorf("Expected %s: %s, got %v", tt.headerName, tt.header, request.Headers)
+				}
+				if tt.headerName == "Authorization" && request.Headers["authorization"] != "" {
+					t.Errorf("Expected meta.auth to replace the explicit header, got %v", request.Headers)
+				}
+			}
+			if tt.secret != "" && (len(request.Secrets) != 1 || request.Secrets[0] != tt.secret) {
+				t.Errorf("Expected secret %q, got %v", tt.secret, request.Secrets)
+			}
+			if tt.digest != nil && (request.DigestAuth == nil || *request.DigestAuth != *tt.digest) {
+				t.Errorf("Expected digest credentials %+v, got %+v", tt.digest, request.DigestAuth)
+			}
+			if tt.oauth2 != nil {
+				if request.OAuth2 == nil || *request.OAuth2 != *tt.oauth2 {
+					t.Errorf("Expected oauth2 settings %+v, got %+v", tt.oauth2, request.OAuth2)
+				}
+				if len(request.Headers) != 0 {
+					t.Errorf("Expected the explicit Authorization header to be removed, got %v", request.Headers)
+				}
+			}
+		})
+	}
+
+	t.Run("invalid auth is reported with positions", func(t *testing.T) {
+		content := "method: GET\npath: /\nmeta:\n  auth:\n    type: api-key\n    value: x\n    in: cookie\n---\nmethod: GET\npath: /\nmeta:\n  auth:\n    type: ntlm\n" +
+			"---\nmethod: GET\npath: /\nmeta:\n  auth:\n    type: oauth2\n    token-url: /token\n    client-id: app\n    client-auth: jwt\n"
+		_, err := NewParser().ParseMultiple([]byte(content), ".yaml", "test.yaml")
+		if err == nil {
+			t.Fatalf("Expected error but got none")
+		}
+		for _, want := range []string{"meta.auth.name", "name is required for api-key auth", "test.yaml:7:9", "unknown api-key location 'cookie'", "unknown auth type 'ntlm'",
+			"token-url must be an absolute http or https URL", "client-secret is required for oauth2 auth", "unknown client-auth 'jwt'"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error to contain %q, got: %v", want, err)
+			}
+		}
+	})
+}
+
+func TestParseSign(t *testing.T) {
+	t.Run("aws-sigv4 test vector", func(t *testing.T) {
+		content := "method: GET\npath: /\nheaders:\n  X-Amz-Date: \"20150830T123600Z\"\nvariables:\n  access: AKIDEXAMPLE\n  token: \"\"\n" +
+			"meta:\n  sign:\n    type: aws-sigv4\n    access-key: {$var: access}\n    secret-key: wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY\n" +
+			"    session-token: {$var: token}\n    region: us-east-1\n    service: service\n"
+		p := NewParser()
+		configs, err := p.ParseMultiple([]byte(content), ".yaml", "test.yaml")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		requests, err := p.ProcessRequestsWithConfig(context.Background(), configs[0], "https://example.amazonaws.com", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+		if got := requests[0].Headers["Authorization"]; got != want {
+			t.Errorf("Unexpected Authorization:\n got: %s\nwant: %s", got, want)
+		}
+
+		// 一時的な認証情報はヘッダーに設定され、出力時にマスクされる
+		ctx := context.WithValue(context.Background(), "variables", map[string]interface{}{"token": "session"})
+		requests, err = p.ProcessRequestsWithConfig(ctx, configs[0], "https://example.amazonaws.com", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if requests[0].Headers["X-Amz-Security-Token"] != "session" || len(requests[0].Secrets) != 1 || requests[0].Secrets[0] != "session" {
+			t.Errorf("Expected the session token header and secret, got %v %v", requests[0].Headers, requests[0].Secrets)
+		}
+	})
+
+	t.Run("hmac over the final request", func(t *testing.T) {
+		content := "method: POST\npath: /items\nquery:\n  id: {$dict: id}\nheaders:\n  X-Timestamp: \"1700000000\"\nbody:\n  id: {$dict: id}\n" +
+			"dict:\n  id: [1, 2]\nmeta:\n  sign:\n    type: hmac\n    key: {$var: secret}\n" +
+			"    template: \"{method}\\n{target}\\n{header:X-Timestamp}\\n{body}\"\n    header: X-Signature\n"
+		p := NewParser()
+		configs, err := p.ParseMultiple([]byte(content), ".yaml", "test.yaml")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ctx := context.WithValue(context.Background(), "variables", map[string]interface{}{"secret": "s3cr3t"})
+		requests, err := p.ProcessRequestsWithConfig(ctx, configs[0], "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(requests) != 2 {
+			t.Fatalf("Expected 2 requests, got %d", len(requests))
+		}
+		for i, request := range requests {
+			id := i + 1
+			mac := hmac.New(sha256.New, []byte("s3cr3t"))
+			mac.Write([]byte(fmt.Sprintf("POST\n/items?id=%d\n1700000000\n{\"id\":%d}", id, id)))
+			if want := hex.EncodeToString(mac.Sum(nil)); request.Headers["X-Signature"] != want {
+				t.Errorf("Request %d: expected signature %s, got %v", id, want, request.Headers)
+			}
+		}
+	})
+
+	t.Run("invalid sign is reported with positions", func(t *testing.T) {
+		content := "method: GET\npath: /\nmeta:\n  sign:\n    type: hmac\n    key: k\n    template: \"{method}{nonce}\"\n---\nmethod: GET\npath: /\nmeta:\n  sign:\n    type: aws-sigv4\n    access-key: a\n"
+		_, err := NewParser().ParseMultiple([]byte(content), ".yaml", "test.yaml")
+		if err == nil {
+			t.Fatalf("Expected error but got none")
+		}
+		for _, want := range []string{"meta.sign.template", "test.yaml:7:15", "unknown placeholder {nonce}", "header is required for hmac signing", "secret-key is required for aws-sigv4 signing"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error to contain %q, got: %v", want, err)
+			}
+		}
+	})
+}
+
+func TestParseHooks(t *testing.T) {
+	content := "method: GET\npath: /\nmeta:\n  hooks:\n    pre:\n      command: [python3, sign.py]\n      timeout: 2s\n    post:\n      command: ./log.sh\n"
+	configs, err := NewParser().ParseMultiple([]byte(content), ".yaml", "test.yaml")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hooks := configs[0].Meta.Hooks
+	if hooks == nil || len(hooks.Pre.Command) != 2 || hooks.Pre.Timeout != "2s" || hooks.Post.Command[0] != "./log.sh" {
+		t.Errorf("Unexpected hooks: %+v", hooks)
+	}
+
+	invalid := "method: GET\npath: /\nmeta:\n  hooks:\n    pre:\n      timeout: 2s\n    post:\n      command: ./log.sh\n      timeout: soon\n"
+	_, err = NewParser().ParseMultiple([]byte(invalid), ".yaml", "test.yaml")
+	if err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+	for _, want := range []string{"meta.hooks.pre.command", "command is required for the pre hook", "meta.hooks.post.timeout", "test.yaml:9:16", "invalid timeout 'soon'"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got: %v", want, err)
+		}
+	}
+}
+
+// testFunction はテスト用にパーサーへ登録する関数
+type testFunction struct{}
+
+func (testFunction) Name() string        { return "shout" }
+func (testFunction) Signature() string   { return "$shout <string>" }
+func (testFunction) Description() string { return "Converts a string to upper case" }
+func (testFunction) Execute(ctx context.Context, args []interface{}) (interface{}, error) {
+	return strings.ToUpper(args[0].(string)), nil
+}
+
+func TestParseFunctions(t *testing.T) {
+	content := "method: GET\npath: /\nquery:\n  q:\n    $shout: abc\n"
+
+	_, err := NewParser().ParseMultiple([]byte(content), ".yaml", "test.yaml")
+	if err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+	for _, want := range []string{"query.q", "test.yaml:5:5", "unknown function: shout"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got: %v", want, err)
+		}
+	}
+
+	// 登録した関数は検証を通り、実行される
+	p := NewParser()
+	if err := p.RegisterFunction(testFunction{}); err != nil {
+		t.Fatalf("Failed to register function: %v", err)
+	}
+	configs, err := p.ParseMultiple([]byte(content), ".yaml", "test.yaml")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	processed, err := p.ProcessRequest(context.Background(), configs[0], "http://example.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(processed.URL, "q=ABC") {
+		t.Errorf("Expected the registered function to be executed, got %s", processed.URL)
+	}
+
+	found := false
+	for _, info := range p.Functions() {
+		found = found || info.Name == "shout"
+	}
+	if !found {
+		t.Error("Expected the registered function in Functions")
+	}
+}
+
+func TestParseTLS(t *testing.T) {
+	content := "method: GET\npath: /\nmeta:\n  tls:\n    cacert: certs/ca.pem\n    cert: /etc/s2req/client.pem\n    sni: waf.example.com\n    max-version: \"1.2\"\n"
+	p := NewParser()
+	configs, err := p.ParseMultiple([]byte(content), ".yaml", filepath.Join("requests", "test.yaml"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	processed, err := p.ProcessRequestWithRequestID(context.Background(), configs[0], "https://example.com", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := config.TLSConfig{CACert: filepath.Join("requests", "certs", "ca.pem"), Cert: "/etc/s2req/client.pem", SNI: "waf.example.com", MaxVersion: "1.2"}
+	if processed.TLS == nil || *processed.TLS != want {
+		t.Errorf("Expected %+v, got %+v", want, processed.TLS)
+	}
+
+	invalid := "method: GET\npath: /\nmeta:\n  tls:\n    key: client.key\n    min-version: \"1.5\"\n"
+	_, err = NewParser().ParseMultiple([]byte(invalid), ".yaml", "test.yaml")
+	if err == nil {
+		t.Fatalf("Expected error but got none")
+	}
+	for _, want := range []string{"meta.tls.key", "key requires cert", "meta.tls.min-version", "test.yaml:6:18", "invalid TLS version '1.5'"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestParseRaw(t *testing.T) {
+	t.Run("functions and dict are evaluated inside the raw request", func(t *testing.T) {
+		content := "raw:\n  - \"POST /search HTTP/1.1\\nHost: waf.example.test\\nContent-Length : 3\\nContent-Length: 99\\n\\n\"\n" +
+			"  - {$concat: [\"q=\", {$dict: payload}]}\n" +
+			"dict:\n  payload: [\"<script>\", \"1\"]\n" +
+			"---\nraw:\n  value: |\n    GET /a/../b HTTP/1.1\n    Host: x\n     folded\n  content-length: true\n  line-endings: lf\n"
+		p := NewParser()
+		configs, err := p.ParseMultiple([]byte(content), ".yaml", "test.yaml")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		requests, err := p.ProcessRequestsWithConfig(context.Background(), configs[0], "https://example.com/api/", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := []string{
+			"POST /search HTTP/1.1\r\nHost: waf.example.test\r\nContent-Length : 3\r\nContent-Length: 99\r\n\r\nq=<script>",
+			"POST /search HTTP/1.1\r\nHost: waf.example.test\r\nContent-Length : 3\r\nContent-Length: 99\r\n\r\nq=1",
+		}
+		if len(requests) != len(want) {
+			t.Fatalf("Expected %d requests, got %d", len(want), len(requests))
+		}
+		for i, request := range requests {
+			if request.Raw != want[i] {
+				t.Errorf("Unexpected raw request %d:\n got: %q\nwant: %q", i, request.Raw, want[i])
+			}
+			if request.Method != "POST" || request.URL != "https://example.com/search" || request.RequestID != "" || len(request.Headers) != 0 {
+				t.Errorf("Unexpected request %d: %+v", i, request)
+			}
+		}
+
+		processed, err := p.ProcessRequestWithRequestID(context.Background(), configs[1], "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := "GET /a/../b HTTP/1.1\nHost: x\n folded\n\n"; processed.Raw != want {
+			t.Errorf("Unexpected raw request:\n got: %q\nwant: %q", processed.Raw, want)
+		}
+	})
+
+	t.Run("URL is built from the scheme and host of the base URL", func(t *testing.T) {
+		tests := []struct {
+			requestLine string
+			baseURL     string
+			want        string
+		}{
+			{requestLine: "GET /x?a=1 HTTP/1.1", baseURL: "http://h/api/", want: "http://h/x?a=1"},
+			{requestLine: "GET /a/../%%32%65 HTTP/1.1", baseURL: "https://h:8443", want: "https://h:8443/a/../%%32%65"},
+			{requestLine: "GET http://other.test/admin HTTP/1.1", baseURL: "http://h/api", want: "http://other.test/admin"},
+			{requestLine: "OPTIONS * HTTP/1.1", baseURL: "http://h/api", want: "http://h"},
+			{requestLine: "CONNECT other.test:443 HTTP/1.1", baseURL: "http://h", want: "http://h"},
+		}
+		p := NewParser()
+		for _, tt := range tests {
+			processed, err := p.ProcessRequestWithRequestID(context.Background(), &config.RequestConfig{Raw: tt.requestLine + "\n\n"}, tt.baseURL, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if processed.URL != tt.want || processed.RawOrigin != strings.TrimSuffix(strings.TrimSuffix(tt.baseURL, "/api/"), "/api") {
+				t.Errorf("%q with %s: expected URL %s, got %s (origin %s)", tt.requestLine, tt.baseURL, tt.want, processed.URL, processed.RawOrigin)
+			}
+		}
+	})
+
+	t.Run("request-id option is not applied", func(t *testing.T) {
+		cliConfig := &config.CLIConfig{RequestID: &config.RequestIDConfig{Location: config.RequestIDLocationHeader, Key: "X-Request-ID"}}
+		requests, err := NewParser().ProcessRequestsWithConfig(context.Background(), &config.RequestConfig{Raw: "GET / HTTP/1.1\n\n"}, "http://h", cliConfig)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(requests) != 1 || requests[0].Raw != "GET / HTTP/1.1\r\n\r\n" || requests[0].RequestID != "" {
+			t.Errorf("Expected the raw request to be unchanged, got %+v", requests)
+		}
+	})
+
+	t.Run("invalid raw options and conflicting fields", func(t *testing.T) {
+		content := "method: GET\npath: /\nraw: \"GET / HTTP/1.1\\r\\n\\r\\n\"\nmeta:\n  auth:\n    type: bearer\n    token: x\n  request-id:\n    location: header\n    key: X-Request-ID\n" +
+			"---\nraw:\n  value: x\n  line-endings: cr\n---\nraw:\n  value: x\n  content-length: yes please\n---\nraw: {$dict: missing}\n"
+		_, err := NewParser().ParseMultiple([]byte(content), ".yaml", "test.yaml")
+		if err == nil {
+			t.Fatalf("Expected error but got none")
+		}
+		for _, want := range []string{
+			"method cannot be used with raw", "path cannot be used with raw", "meta.auth cannot be used with raw", "meta.request-id cannot be used with raw", "test.yaml:1:9",
+			"invalid raw.line-endings 'cr', expected crlf, lf or keep", "raw.content-length must be a boolean",
+			"$dict reference 'missing'",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error to contain %q, got: %v", want, err)
+			}
+		}
+	})
+}
+
+func TestRenderRaw(t *testing.T) {
+	tests := []struct {
+		name             string
+		text             string
+		lineEndings      string
+		fixContentLength bool
+		want             string
+	}{
+		{
+			name:        "LF head without a blank line",
+			text:        "GET / HTTP/1.1\nHost: x\n",
+			lineEndings: rawLineEndingsCRLF,
+			want:        "GET / HTTP/1.1\r\nHost: x\r\n\r\n",
+		},
+		{
+			name:        "body line endings are not changed",
+			text:        "POST / HTTP/1.1\r\nHost: x\n\na=1\nb=2\n",
+			lineEndings: rawLineEndingsCRLF,
+			want:        "POST / HTTP/1.1\r\nHost: x\r\n\r\na=1\nb=2\n",
+		},
+		{
+			name:        "lf",
+			text:        "GET / HTTP/1.1\r\nHost: x\r\n\r\n",
+			lineEndings: rawLineEndingsLF,
+			want:        "GET / HTTP/1.1\nHost: x\n\n",
+		},
+		{
+			name:        "keep sends bare LF, CR and obs-fold as written",
+			text:        "GET / HTTP/1.1\r\nHost: x\nX-A: 1\r\n\tfolded\rX\r\n",
+			lineEndings: rawLineEndingsKeep,
+			want:        "GET / HTTP/1.1\r\nHost: x\nX-A: 1\r\n\tfolded\rX\r\n",
+		},
+		{
+			name:             "duplicate Content-Length is replaced",
+			text:             "POST / HTTP/1.1\nContent-Length: 1\nHost: x\ncontent-length : 50\n\nhello",
+			lineEndings:      rawLineEndingsCRLF,
+			fixContentLength: true,
+			want:             "POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 5\r\n\r\nhello",
+		},
+		{
+			name:             "no Content-Length without a body",
+			text:             "GET / HTTP/1.1\nHost: x\n",
+			lineEndings:      rawLineEndingsCRLF,
+			fixContentLength: true,
+			want:             "GET / HTTP/1.1\r\nHost: x\r\n\r\n",
+		},
+		{
+			name:             "keep uses the line ending of the request line",
+			text:             "POST / HTTP/1.1\nHost: x\n\nab",
+			lineEndings:      rawLineEndingsKeep,
+			fixContentLength: true,
+			want:             "POST / HTTP/1.1\nHost: x\nContent-Length: 2\n\nab",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderRaw(tt.text, tt.lineEndings, tt.fixContentLength); got != tt.want {
+				t.Errorf("Unexpected raw request:\n got: %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
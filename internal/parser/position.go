@@ -2,6 +2,7 @@ package parser
 
 import (
 	"encoding/json"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -102,6 +103,14 @@ func (pt *PositionTracker) FindYAMLPosition(propertyPath string) *PositionInfo {
 			}
 		}
 
+		// Look for the index in sequence nodes
+		if currentNode.Kind == yaml.SequenceNode {
+			if index, err := strconv.Atoi(part); err == nil && index >= 0 && index < len(currentNode.Content) {
+				currentNode = currentNode.Content[index]
+				found = true
+			}
+		}
+
 		if !found {
 			break
 		}
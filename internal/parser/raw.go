@@ -0,0 +1,245 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// rawのline-endingsで指定できる値
+const (
+	rawLineEndingsCRLF = "crlf" // ヘッダー部の改行をCRLFにそろえる（デフォルト）
+	rawLineEndingsLF   = "lf"   // ヘッダー部の改行をLFにそろえる
+	rawLineEndingsKeep = "keep" // 改行を変更しない
+)
+
+// rawOptions はrawの値と送信前の調整の設定
+type rawOptions struct {
+	value            interface{}
+	fixContentLength bool
+	lineEndings      string
+}
+
+// parseRawOptions はrawの値を解析する。
+// 文字列や$関数のほか、value、content-length、line-endingsを持つオブジェクト形式で記述できる。
+func parseRawOptions(value interface{}) (rawOptions, error) {
+	options := rawOptions{value: value, lineEndings: rawLineEndingsCRLF}
+	rawMap, ok := value.(map[string]interface{})
+	if !ok {
+		return options, nil
+	}
+	valueValue, hasValue := rawMap["value"]
+	if !hasValue {
+		// 値がない場合は$関数の呼び出しとして扱う
+		return options, nil
+	}
+	options.value = valueValue
+
+	for key, v := range rawMap {
+		switch key {
+		case "value":
+		case "content-length":
+			fix, ok := v.(bool)
+			if !ok {
+				return options, fmt.Errorf("raw.content-length must be a boolean")
+			}
+			options.fixContentLength = fix
+		case "line-endings":
+			lineEndings, _ := v.(string)
+			switch lineEndings {
+			case rawLineEndingsCRLF, rawLineEndingsLF, rawLineEndingsKeep:
+				options.lineEndings = lineEndings
+			default:
+				return options, fmt.Errorf("invalid raw.line-endings '%v', expected crlf, lf or keep", v)
+			}
+		default:
+			return options, fmt.Errorf("unknown raw option '%s', expected value, content-length or line-endings", key)
+		}
+	}
+	return options, nil
+}
+
+// processRaw はrawの$関数と$dictを評価し、送信するリクエスト全体を持つProcessedRequestを返す。
+// メソッドとURLはリクエストラインから求め、接続先はbaseURLのスキームとホストを使う。
+func (p *Parser) processRaw(ctx context.Context, requestConfig *config.RequestConfig, baseURL string) (*config.ProcessedRequest, error) {
+	options, err := parseRawOptions(requestConfig.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// コンテキストにリクエストファイルのパスを設定
+	ctx = context.WithValue(ctx, "requestFilePath", requestConfig.FilePath)
+	processed, err := p.processValue(ctx, options.value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process raw: %w", err)
+	}
+
+	// 配列の場合は要素を連結する
+	var text string
+	switch v := processed.(type) {
+	case nil:
+	case []interface{}:
+		var builder strings.Builder
+		for _, part := range v {
+			if part != nil {
+				fmt.Fprintf(&builder, "%v", part)
+			}
+		}
+		text = builder.String()
+	default:
+		text = fmt.Sprintf("%v", v)
+	}
+	if text == "" {
+		return nil, fmt.Errorf("raw request is empty")
+	}
+	text = renderRaw(text, options.lineEndings, options.fixContentLength)
+
+	origin, err := rawOrigin(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	requestLine, _, _ := strings.Cut(text, "\n")
+	fields := strings.Fields(requestLine)
+	method, requestTarget := "", ""
+	if len(fields) > 0 {
+		method = fields[0]
+	}
+	if len(fields) > 1 {
+		requestTarget = fields[1]
+	}
+
+	return &config.ProcessedRequest{
+		Method:    method,
+		URL:       rawRequestURL(origin, requestTarget),
+		Raw:       text,
+		RawOrigin: origin,
+		Headers:   make(map[string]string),
+		TLS:       tlsOptions(requestConfig),
+	}, nil
+}
+
+// rawOrigin はbaseURLのスキームとホストを返す。rawのリクエストはこの接続先に送信する。
+func rawOrigin(baseURL string) (string, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return "", fmt.Errorf("raw requests need an absolute host URL, got '%s'", baseURL)
+	}
+	return (&url.URL{Scheme: base.Scheme, Host: base.Host}).String(), nil
+}
+
+// rawRequestURL はリクエストターゲットから、結果やクッキージャー、レート制御に使うURLを求める。
+// absolute-formはそのURLを使い、origin-formはoriginに続ける。
+// origin-formのリクエストターゲットは、ドットセグメントや不正なパーセントエンコードも含めてそのまま残す。
+func rawRequestURL(origin string, requestTarget string) string {
+	if target, err := url.Parse(requestTarget); err == nil && target.IsAbs() && target.Host != "" {
+		return requestTarget
+	}
+	if strings.HasPrefix(requestTarget, "/") {
+		return origin + requestTarget
+	}
+	// authority-form（CONNECT）やasterisk-form（OPTIONS *）はパスを持たない
+	return origin
+}
+
+// renderRaw はline-endingsに従ってヘッダー部の改行をそろえ、
+// fixContentLengthがtrueの場合はContent-Lengthをボディの長さに置き換える。ボディは変更しない。
+func renderRaw(text string, lineEndings string, fixContentLength bool) string {
+	lines, separator, body := splitRawHead(text)
+
+	if lineEndings != rawLineEndingsKeep {
+		eol := "\r\n"
+		if lineEndings == rawLineEndingsLF {
+			eol = "\n"
+		}
+		for i, line := range lines {
+			lines[i] = trimLineEnding(line) + eol
+		}
+		// 空行がない場合はヘッダー部の終わりを補う
+		separator = eol
+	}
+
+	if fixContentLength {
+		eol := "\r\n"
+		if len(lines) > 0 && !strings.HasSuffix(lines[0], "\r\n") && strings.HasSuffix(lines[0], "\n") {
+			eol = "\n"
+		}
+		kept := lines[:0]
+		removed := false
+		for _, line := range lines {
+			name, _, hasColon := strings.Cut(line, ":")
+			if hasColon && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+				removed = true
+				continue
+			}
+			kept = append(kept, line)
+		}
+		lines = kept
+		if body != "" || removed {
+			if last := len(lines) - 1; last >= 0 && !strings.HasSuffix(lines[last], "\n") {
+				lines[last] += eol
+			}
+			lines = append(lines, fmt.Sprintf("Content-Length: %d%s", len(body), eol))
+		}
+	}
+
+	return strings.Join(lines, "") + separator + body
+}
+
+// splitRawHead はリクエストを改行を含むヘッダー部の行、ヘッダー部の終わりの空行、ボディに分ける。
+// 空行がない場合は全体をヘッダー部とし、separatorは空になる。
+func splitRawHead(text string) (lines []string, separator string, body string) {
+	for rest := text; rest != ""; {
+		end := strings.Index(rest, "\n")
+		if end < 0 {
+			lines = append(lines, rest)
+			break
+		}
+		line := rest[:end+1]
+		rest = rest[end+1:]
+		if trimLineEnding(line) == "" {
+			return lines, line, rest
+		}
+		lines = append(lines, line)
+	}
+	return lines, "", ""
+}
+
+// trimLineEnding は行末のLFまたはCRLFを取り除く
+func trimLineEnding(line string) string {
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r")
+}
+
+// validateRaw validates the raw options and reports fields that cannot be combined with raw
+func (p *Parser) validateRaw(requestConfig *config.RequestConfig, filePath string, fileExt string, content string) error {
+	if requestConfig.Raw == nil {
+		return nil
+	}
+	errs := make(map[string]error)
+	if _, err := parseRawOptions(requestConfig.Raw); err != nil {
+		errs["raw"] = err
+	}
+
+	conflicts := map[string]bool{
+		"method":  requestConfig.Method != "",
+		"path":    requestConfig.Path != nil,
+		"query":   requestConfig.Query != nil,
+		"headers": requestConfig.Headers != nil,
+		"params":  requestConfig.Params != nil,
+		"body":    requestConfig.Body != nil,
+	}
+	if requestConfig.Meta != nil {
+		conflicts["meta.request-id"] = requestConfig.Meta.RequestID != nil
+		conflicts["meta.auth"] = requestConfig.Meta.Auth != nil
+		conflicts["meta.sign"] = requestConfig.Meta.Sign != nil
+	}
+	for name, set := range conflicts {
+		if set {
+			errs[name] = fmt.Errorf("%s cannot be used with raw, write it in the raw request instead", name)
+		}
+	}
+	return p.createPropertyErrors(errs, "", filePath, fileExt, content)
+}
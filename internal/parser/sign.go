@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+	"github.com/secureta/s2http-request/internal/sign"
+)
+
+// SignRequest はmeta.signに従って処理済みリクエストに署名する。
+// 署名は全ての$関数を評価した後のメソッド、パス、クエリ、ヘッダー、ボディに対して計算する。
+func (p *Parser) SignRequest(ctx context.Context, requestConfig *config.RequestConfig, request *config.ProcessedRequest) error {
+	if requestConfig.Meta == nil || requestConfig.Meta.Sign == nil {
+		return nil
+	}
+	signConfig := requestConfig.Meta.Sign
+	ctx = context.WithValue(ctx, "requestFilePath", requestConfig.FilePath)
+	value := func(name string, v interface{}) (string, error) {
+		return p.processString(ctx, "meta.sign."+name, v)
+	}
+
+	switch signConfig.Type {
+	case config.SignTypeAWSSigV4:
+		credentials := sign.AWSCredentials{Region: signConfig.Region, Service: signConfig.Service}
+		var err error
+		if credentials.AccessKey, err = value("access-key", signConfig.AccessKey); err != nil {
+			return err
+		}
+		if credentials.SecretKey, err = value("secret-key", signConfig.SecretKey); err != nil {
+			return err
+		}
+		if credentials.SessionToken, err = value("session-token", signConfig.SessionToken); err != nil {
+			return err
+		}
+		if err := sign.SignAWSV4(request, credentials, time.Now()); err != nil {
+			return fmt.Errorf("failed to sign request: %w", err)
+		}
+		if credentials.SessionToken != "" {
+			request.Secrets = append(request.Secrets, credentials.SessionToken)
+		}
+	case config.SignTypeHMAC:
+		key, err := value("key", signConfig.Key)
+		if err != nil {
+			return err
+		}
+		hmacConfig := sign.HMACConfig{
+			Key:       key,
+			Algorithm: signConfig.Algorithm,
+			Template:  signConfig.Template,
+			Header:    signConfig.Header,
+			Prefix:    signConfig.Prefix,
+			Encoding:  signConfig.Encoding,
+		}
+		if err := sign.SignHMAC(request, hmacConfig); err != nil {
+			return fmt.Errorf("failed to sign request: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown sign type '%s'", signConfig.Type)
+	}
+	return nil
+}
+
+// validateSign validates the meta.sign block of a request configuration
+func (p *Parser) validateSign(requestConfig *config.RequestConfig, filePath string, fileExt string, content string) error {
+	if requestConfig.Meta == nil || requestConfig.Meta.Sign == nil {
+		return nil
+	}
+	return p.createPropertyErrors(sign.Validate(requestConfig.Meta.Sign), "meta.sign", filePath, fileExt, content)
+}
@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"path/filepath"
+
+	"github.com/secureta/s2http-request/internal/config"
+	httpClient "github.com/secureta/s2http-request/internal/http"
+)
+
+// tlsOptions はmeta.tlsの設定を返す。相対パスのファイルはリクエスト定義のディレクトリから解決する。
+func tlsOptions(requestConfig *config.RequestConfig) *config.TLSConfig {
+	if requestConfig.Meta == nil || requestConfig.Meta.TLS == nil {
+		return nil
+	}
+	options := *requestConfig.Meta.TLS
+	if requestConfig.FilePath != "" {
+		dir := filepath.Dir(requestConfig.FilePath)
+		for _, path := range []*string{&options.CACert, &options.Cert, &options.Key} {
+			if *path != "" && !filepath.IsAbs(*path) {
+				*path = filepath.Join(dir, *path)
+			}
+		}
+	}
+	return &options
+}
+
+// validateTLS validates the meta.tls block of a request configuration.
+// Certificate files are loaded when the first request is sent.
+func (p *Parser) validateTLS(requestConfig *config.RequestConfig, filePath string, fileExt string, content string) error {
+	if requestConfig.Meta == nil || requestConfig.Meta.TLS == nil {
+		return nil
+	}
+	return p.createPropertyErrors(httpClient.ValidateTLS(requestConfig.Meta.TLS), "meta.tls", filePath, fileExt, content)
+}
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/secureta/s2http-request/internal/config"
+	"github.com/secureta/s2http-request/internal/response"
+	"github.com/secureta/s2http-request/pkg/functions"
+)
+
+// validateVerdict はmeta.verdictのルールを検証し、rules-fileのルールを読み込んでRulesの末尾に追加する。
+// ファイル内のルールはファイルの読み込み時に検証し、エラーはmeta.verdict.rules-fileの位置で報告する。
+func (p *Parser) validateVerdict(requestConfig *config.RequestConfig, filePath string, fileExt string, content string) error {
+	if requestConfig.Meta == nil || requestConfig.Meta.Verdict == nil {
+		return nil
+	}
+	verdictConfig := requestConfig.Meta.Verdict
+
+	errorCollection := NewErrorCollection()
+	for i, rule := range verdictConfig.Rules {
+		basePath := fmt.Sprintf("meta.verdict.rules.%d", i)
+		if err := p.createPropertyErrors(response.ValidateVerdictRule(rule), basePath, filePath, fileExt, content); err != nil {
+			errorCollection.Add(err)
+		}
+	}
+
+	if verdictConfig.RulesFile != "" {
+		// FileFunctionと同じく、リクエスト定義ファイルからの相対パスとして解決する
+		ctx := context.WithValue(context.Background(), "requestFilePath", requestConfig.FilePath)
+		fileConfig, err := loadVerdictRulesFile(ctx, verdictConfig.RulesFile)
+		if err != nil {
+			errorCollection.Add(p.createPropertyErrors(map[string]error{"rules-file": err}, "meta.verdict", filePath, fileExt, content))
+		} else {
+			verdictConfig.Rules = append(verdictConfig.Rules, fileConfig.Rules...)
+			if verdictConfig.Builtin == nil {
+				verdictConfig.Builtin = fileConfig.Builtin
+			}
+		}
+	}
+
+	return errorCollection.ToError()
+}
+
+// loadVerdictRulesFile はリクエスト定義ファイルからの相対パスでルールファイルを読み込む
+func loadVerdictRulesFile(ctx context.Context, path string) (*config.VerdictConfig, error) {
+	resolved, err := functions.ResolveRelativePath(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", path, err)
+	}
+	return response.LoadVerdictConfig(resolved)
+}
@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateVerdict(t *testing.T) {
+	dir := writeDictSourceFiles(t, map[string]string{
+		"rules/waf.yaml": "builtin: false\nrules:\n  - name: file-rule\n    status: 418\n",
+		"rules/bad.yaml": "rules:\n  - name: bad\n    status: 4x\n",
+	})
+
+	t.Run("rules-file is appended after inline rules", func(t *testing.T) {
+		content := "method: GET\npath: /\nmeta:\n  verdict:\n    rules-file: rules/waf.yaml\n    rules:\n      - name: inline-rule\n        body-contains: denied\n"
+		configs, err := NewParser().ParseMultiple([]byte(content), ".yaml", filepath.Join(dir, "request.yaml"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		verdict := configs[0].Meta.Verdict
+		if len(verdict.Rules) != 2 || verdict.Rules[0].Name != "inline-rule" || verdict.Rules[1].Name != "file-rule" {
+			t.Errorf("Unexpected rules: %+v", verdict.Rules)
+		}
+		if verdict.Builtin == nil || *verdict.Builtin {
+			t.Errorf("Expected builtin to be taken from the rules file, got %v", verdict.Builtin)
+		}
+	})
+
+	tests := []struct {
+		name          string
+		content       string
+		errorContains []string
+	}{
+		{
+			name:          "invalid inline rule",
+			content:       "method: GET\npath: /\nmeta:\n  verdict:\n    rules:\n      - name: ok\n        status: 403\n      - name: bad\n        verdict: allowed\n        status: 4x\n",
+			errorContains: []string{"request.yaml:10:17", "meta.verdict.rules.1.status", "meta.verdict.rules.1.verdict", "unknown verdict 'allowed'"},
+		},
+		{
+			name:          "rule without conditions",
+			content:       "method: GET\npath: /\nmeta:\n  verdict:\n    rules:\n      - name: empty\n",
+			errorContains: []string{"request.yaml:6:9", "at meta.verdict.rules.0 ", "must specify at least one of"},
+		},
+		{
+			name:          "invalid rules file",
+			content:       "method: GET\npath: /\nmeta:\n  verdict:\n    rules-file: rules/bad.yaml\n",
+			errorContains: []string{"request.yaml:5:17", "meta.verdict.rules-file", "rule 0 (bad): status: invalid status"},
+		},
+		{
+			name:          "rules file outside the request directory",
+			content:       "method: GET\npath: /\nmeta:\n  verdict:\n    rules-file: ../waf.yaml\n",
+			errorContains: []string{"meta.verdict.rules-file", "path escapes request file directory"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser().ParseMultiple([]byte(tt.content), ".yaml", filepath.Join(dir, "request.yaml"))
+			if err == nil {
+				t.Fatalf("Expected error but got none")
+			}
+			for _, want := range tt.errorContains {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to contain %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
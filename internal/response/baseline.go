@@ -0,0 +1,173 @@
+package response
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// DefaultMinSimilarity はmin-similarity未指定時のボディの類似度の下限
+const DefaultMinSimilarity = 0.9
+
+// Compare はレスポンスをベースラインのレスポンスと比較し、閾値を超えた差分にフラグを立てる。
+// ステータスコードの変化とヘッダー名の増減（ignore-headersを除く）は常にフラグの対象となる。
+func Compare(baseline *config.ResponseData, data *config.ResponseData, baselineConfig *config.BaselineConfig) *config.BaselineDiff {
+	diff := &config.BaselineDiff{
+		Status:        baseline.StatusCode,
+		StatusChanged: data.StatusCode != baseline.StatusCode,
+		LengthDelta:   len(data.Body) - len(baseline.Body),
+		Similarity:    math.Round(Similarity(baseline.Body, data.Body)*10000) / 10000,
+		TimeDelta:     data.Time.Total - baseline.Time.Total,
+	}
+	flag := func(format string, args ...interface{}) {
+		diff.Flagged = true
+		diff.Reasons = append(diff.Reasons, fmt.Sprintf(format, args...))
+	}
+
+	if diff.StatusChanged {
+		flag("status changed from %d to %d", baseline.StatusCode, data.StatusCode)
+	}
+
+	if baselineConfig.MaxLengthDelta != nil && abs(diff.LengthDelta) > *baselineConfig.MaxLengthDelta {
+		flag("body length changed by %+d bytes (max %d)", diff.LengthDelta, *baselineConfig.MaxLengthDelta)
+	}
+
+	minSimilarity := DefaultMinSimilarity
+	if baselineConfig.MinSimilarity != nil {
+		minSimilarity = *baselineConfig.MinSimilarity
+	}
+	if diff.Similarity < minSimilarity {
+		flag("body similarity %.2f is below %.2f", diff.Similarity, minSimilarity)
+	}
+
+	ignored := make(map[string]bool, len(baselineConfig.IgnoreHeaders))
+	for _, name := range baselineConfig.IgnoreHeaders {
+		ignored[http.CanonicalHeaderKey(name)] = true
+	}
+	baselineHeaders := headerNames(baseline.Headers, ignored)
+	headers := headerNames(data.Headers, ignored)
+	for name := range headers {
+		if !baselineHeaders[name] {
+			diff.HeadersAdded = append(diff.HeadersAdded, name)
+		}
+	}
+	for name := range baselineHeaders {
+		if !headers[name] {
+			diff.HeadersRemoved = append(diff.HeadersRemoved, name)
+		}
+	}
+	sort.Strings(diff.HeadersAdded)
+	sort.Strings(diff.HeadersRemoved)
+	if len(diff.HeadersAdded) > 0 {
+		flag("headers added: %s", strings.Join(diff.HeadersAdded, ", "))
+	}
+	if len(diff.HeadersRemoved) > 0 {
+		flag("headers removed: %s", strings.Join(diff.HeadersRemoved, ", "))
+	}
+
+	if baselineConfig.MaxTimeDelta != "" {
+		// 不正な値はValidateBaselineで検出されるため、ここでは無視する
+		if maxTimeDelta, err := time.ParseDuration(baselineConfig.MaxTimeDelta); err == nil {
+			timeDelta := time.Duration(diff.TimeDelta * float64(time.Second))
+			if timeDelta > maxTimeDelta {
+				flag("response time increased by %s (max %s)", timeDelta.Round(time.Millisecond), baselineConfig.MaxTimeDelta)
+			}
+		}
+	}
+
+	return diff
+}
+
+// Similarity は2つのボディの類似度を0〜1で返す。
+// 英数字の連続と記号1文字ずつをトークンとし、共通するトークン数から求める（Dice係数）。
+// 文字単位の比較と異なりボディの長さに比例した時間で計算できる。
+func Similarity(a, b string) float64 {
+	tokensA, tokensB := tokenize(a), tokenize(b)
+	if len(tokensA)+len(tokensB) == 0 {
+		return 1
+	}
+
+	counts := make(map[string]int, len(tokensA))
+	for _, token := range tokensA {
+		counts[token]++
+	}
+	common := 0
+	for _, token := range tokensB {
+		if counts[token] > 0 {
+			counts[token]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(tokensA)+len(tokensB))
+}
+
+// tokenize はボディを英数字の連続と記号1文字ずつに分割する。空白は区切りとして扱う。
+func tokenize(body string) []string {
+	var tokens []string
+	start := -1
+	for i, r := range body {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, body[start:i])
+			start = -1
+		}
+		if !unicode.IsSpace(r) {
+			tokens = append(tokens, string(r))
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, body[start:])
+	}
+	return tokens
+}
+
+// headerNames はignoredを除いたヘッダー名の集合を返す
+func headerNames(headers map[string][]string, ignored map[string]bool) map[string]bool {
+	names := make(map[string]bool, len(headers))
+	for name := range headers {
+		name = http.CanonicalHeaderKey(name)
+		if !ignored[name] {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// ValidateBaseline はmeta.baselineの閾値を検証し、不正な項目をプロパティ名ごとに返す
+func ValidateBaseline(baselineConfig *config.BaselineConfig) map[string]error {
+	errs := make(map[string]error)
+	if baselineConfig == nil {
+		return errs
+	}
+
+	if baselineConfig.MinSimilarity != nil && (*baselineConfig.MinSimilarity < 0 || *baselineConfig.MinSimilarity > 1) {
+		errs["min-similarity"] = fmt.Errorf("min-similarity must be between 0 and 1, got %v", *baselineConfig.MinSimilarity)
+	}
+	if baselineConfig.MaxLengthDelta != nil && *baselineConfig.MaxLengthDelta < 0 {
+		errs["max-length-delta"] = fmt.Errorf("max-length-delta must not be negative, got %d", *baselineConfig.MaxLengthDelta)
+	}
+	if baselineConfig.MaxTimeDelta != "" {
+		if maxTimeDelta, err := time.ParseDuration(baselineConfig.MaxTimeDelta); err != nil || maxTimeDelta <= 0 {
+			errs["max-time-delta"] = fmt.Errorf("max-time-delta must be a positive duration such as 500ms or 2s, got '%s'", baselineConfig.MaxTimeDelta)
+		}
+	}
+	return errs
+}
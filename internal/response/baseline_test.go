@@ -0,0 +1,114 @@
+package response
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{name: "both empty", a: "", b: "", want: 1},
+		{name: "one empty", a: "hello", b: "", want: 0},
+		{name: "identical", a: "<p>Hello world</p>", b: "<p>Hello world</p>", want: 1},
+		{name: "whitespace is ignored", a: "a b\n c", b: "a  b c", want: 1},
+		{name: "one token changed", a: "results for hello", b: "results for world", want: 2.0 / 3.0},
+		{name: "order is ignored", a: "x y z", b: "z y x", want: 1},
+		{name: "disjoint", a: "abc", b: "xyz", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Similarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	page := func(words int) string {
+		return strings.Repeat("lorem ipsum ", words)
+	}
+	baseline := &config.ResponseData{
+		StatusCode: 200,
+		Headers:    map[string][]string{"Content-Type": {"text/html"}, "Date": {"today"}},
+		Body:       page(50) + "hello",
+		Time:       config.ResponseTiming{Total: 0.1},
+	}
+	maxLength := 20
+	minSimilarity := 0.5
+
+	tests := []struct {
+		name     string
+		response *config.ResponseData
+		config   *config.BaselineConfig
+		reasons  []string
+	}{
+		{
+			name:     "reflected payload",
+			response: &config.ResponseData{StatusCode: 200, Headers: baseline.Headers, Body: page(50) + "payload", Time: config.ResponseTiming{Total: 0.12}},
+			config:   &config.BaselineConfig{MaxLengthDelta: &maxLength, MaxTimeDelta: "1s"},
+		},
+		{
+			name:     "block page",
+			response: &config.ResponseData{StatusCode: 403, Headers: map[string][]string{"Content-Type": {"text/html"}, "X-Waf": {"1"}}, Body: "Access denied"},
+			config:   &config.BaselineConfig{MaxLengthDelta: &maxLength},
+			reasons: []string{
+				"status changed from 200 to 403",
+				"body length changed by -592 bytes (max 20)",
+				"body similarity 0.00 is below 0.90",
+				"headers added: X-Waf",
+				"headers removed: Date",
+			},
+		},
+		{
+			name:     "ignored headers and lower similarity",
+			response: &config.ResponseData{StatusCode: 200, Headers: map[string][]string{"content-type": {"text/html"}}, Body: page(30)},
+			config:   &config.BaselineConfig{MinSimilarity: &minSimilarity, IgnoreHeaders: config.StringList{"date"}},
+		},
+		{
+			name:     "slow response",
+			response: &config.ResponseData{StatusCode: 200, Headers: baseline.Headers, Body: baseline.Body, Time: config.ResponseTiming{Total: 5.1}},
+			config:   &config.BaselineConfig{MaxTimeDelta: "2s"},
+			reasons:  []string{"response time increased by 5s (max 2s)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := Compare(baseline, tt.response, tt.config)
+			if diff.Flagged != (len(tt.reasons) > 0) {
+				t.Errorf("Expected flagged=%v, got %+v", len(tt.reasons) > 0, diff)
+			}
+			if strings.Join(diff.Reasons, "|") != strings.Join(tt.reasons, "|") {
+				t.Errorf("Expected reasons %q, got %q", tt.reasons, diff.Reasons)
+			}
+			if diff.Status != 200 || diff.LengthDelta != len(tt.response.Body)-len(baseline.Body) {
+				t.Errorf("Unexpected diff: %+v", diff)
+			}
+		})
+	}
+}
+
+func TestValidateBaseline(t *testing.T) {
+	negative := -1
+	tooHigh := 1.5
+	errs := ValidateBaseline(&config.BaselineConfig{MinSimilarity: &tooHigh, MaxLengthDelta: &negative, MaxTimeDelta: "soon"})
+	for _, key := range []string{"min-similarity", "max-length-delta", "max-time-delta"} {
+		if errs[key] == nil {
+			t.Errorf("Expected error for %s, got %v", key, errs)
+		}
+	}
+
+	similarity := 0.8
+	if errs := ValidateBaseline(&config.BaselineConfig{MinSimilarity: &similarity, MaxTimeDelta: "500ms"}); len(errs) != 0 {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+}
@@ -0,0 +1,293 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// statusRange はステータスコードの範囲（両端を含む）
+type statusRange struct {
+	min, max int
+}
+
+func (r statusRange) String() string {
+	if r.min == r.max {
+		return strconv.Itoa(r.min)
+	}
+	if r.min%100 == 0 && r.max == r.min+99 {
+		return fmt.Sprintf("%dxx", r.min/100)
+	}
+	return fmt.Sprintf("%d-%d", r.min, r.max)
+}
+
+// Evaluate はexpectの各項目をレスポンスに対して評価する。
+// expectは事前にValidateExpectで検証されていることを前提とし、不正な項目は失敗として扱う。
+func Evaluate(expect *config.ExpectConfig, data *config.ResponseData) []config.AssertionResult {
+	if expect == nil {
+		return nil
+	}
+
+	var results []config.AssertionResult
+	add := func(name string, passed bool, expected string, actual string) {
+		results = append(results, config.AssertionResult{Name: name, Passed: passed, Expected: expected, Actual: actual})
+	}
+
+	if expect.Status != nil {
+		ranges, err := parseStatusRanges(expect.Status)
+		if err != nil {
+			add("status", false, err.Error(), strconv.Itoa(data.StatusCode))
+		} else {
+			passed := false
+			expected := make([]string, len(ranges))
+			for i, r := range ranges {
+				expected[i] = r.String()
+				if data.StatusCode >= r.min && data.StatusCode <= r.max {
+					passed = true
+				}
+			}
+			add("status", passed, strings.Join(expected, ", "), strconv.Itoa(data.StatusCode))
+		}
+	}
+
+	for _, name := range sortedKeys(expect.Headers) {
+		values := http.Header(data.Headers).Values(name)
+		actual := strings.Join(values, ", ")
+		if len(values) == 0 {
+			actual = "(absent)"
+		}
+		switch want := expect.Headers[name].(type) {
+		case bool:
+			expected := "(present)"
+			if !want {
+				expected = "(absent)"
+			}
+			add("header "+name, (len(values) > 0) == want, expected, actual)
+		default:
+			expected := fmt.Sprintf("%v", want)
+			passed := false
+			for _, value := range values {
+				if value == expected {
+					passed = true
+				}
+			}
+			add("header "+name, passed, expected, actual)
+		}
+	}
+
+	for _, text := range expect.BodyContains {
+		found := strings.Contains(data.Body, text)
+		add("body-contains", found, fmt.Sprintf("%q", text), foundLabel(found))
+	}
+	for _, text := range expect.BodyNotContains {
+		found := strings.Contains(data.Body, text)
+		add("body-not-contains", !found, fmt.Sprintf("%q", text), foundLabel(found))
+	}
+	for _, pattern := range expect.BodyRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			add("body-regex", false, pattern, err.Error())
+			continue
+		}
+		found := re.MatchString(data.Body)
+		add("body-regex", found, pattern, foundLabel(found))
+	}
+
+	for _, path := range sortedKeys(expect.JSON) {
+		expected := formatJSONValue(expect.JSON[path])
+		actualValue, err := LookupJSONPath(data.Body, path)
+		if err != nil {
+			add("json "+path, false, expected, err.Error())
+			continue
+		}
+		add("json "+path, jsonEqual(expect.JSON[path], actualValue), expected, formatJSONValue(actualValue))
+	}
+
+	if expect.MaxTime != "" {
+		actual := time.Duration(data.Time.Total * float64(time.Second))
+		maxTime, err := time.ParseDuration(expect.MaxTime)
+		if err != nil {
+			add("max-time", false, expect.MaxTime, err.Error())
+		} else {
+			add("max-time", actual <= maxTime, "<= "+maxTime.String(), actual.Round(time.Millisecond).String())
+		}
+	}
+
+	return results
+}
+
+// Passed は全てのアサーションが成功したかを返す
+func Passed(results []config.AssertionResult) bool {
+	for _, result := range results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateExpect はexpectの各項目を検証し、不正な項目をプロパティ名（"status", "json.data.id" など）ごとに返す
+func ValidateExpect(expect *config.ExpectConfig) map[string]error {
+	errs := make(map[string]error)
+	if expect == nil {
+		return errs
+	}
+
+	if expect.Status != nil {
+		if _, err := parseStatusRanges(expect.Status); err != nil {
+			errs["status"] = err
+		}
+	}
+	for name, value := range expect.Headers {
+		switch value.(type) {
+		case string, bool, int, float64:
+		default:
+			errs["headers."+name] = fmt.Errorf("header value must be a string or boolean, got %T", value)
+		}
+	}
+	for _, pattern := range expect.BodyRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs["body-regex"] = fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	for path := range expect.JSON {
+		if _, err := parseJSONPath(path); err != nil {
+			errs["json."+path] = err
+		}
+	}
+	if expect.MaxTime != "" {
+		if maxTime, err := time.ParseDuration(expect.MaxTime); err != nil || maxTime <= 0 {
+			errs["max-time"] = fmt.Errorf("max-time must be a positive duration such as 500ms or 2s, got '%s'", expect.MaxTime)
+		}
+	}
+	return errs
+}
+
+// parseStatusRanges はステータスの期待値を範囲の配列に変換する。
+// 200のような数値、"2xx"、"200-299"、またはそれらの配列を受け付ける。
+func parseStatusRanges(value interface{}) ([]statusRange, error) {
+	if list, ok := value.([]interface{}); ok {
+		if len(list) == 0 {
+			return nil, fmt.Errorf("status list cannot be empty")
+		}
+		var ranges []statusRange
+		for _, item := range list {
+			r, err := parseStatusRange(item)
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, r)
+		}
+		return ranges, nil
+	}
+
+	r, err := parseStatusRange(value)
+	if err != nil {
+		return nil, err
+	}
+	return []statusRange{r}, nil
+}
+
+func parseStatusRange(value interface{}) (statusRange, error) {
+	invalid := fmt.Errorf("invalid status '%v', expected a code (200), a class (2xx) or a range (200-299)", value)
+
+	var text string
+	switch v := value.(type) {
+	case int:
+		text = strconv.Itoa(v)
+	case float64:
+		if v != float64(int(v)) {
+			return statusRange{}, invalid
+		}
+		text = strconv.Itoa(int(v))
+	case string:
+		text = strings.TrimSpace(v)
+	default:
+		return statusRange{}, invalid
+	}
+
+	var r statusRange
+	switch {
+	case len(text) == 3 && strings.HasSuffix(strings.ToLower(text), "xx"):
+		class, err := strconv.Atoi(text[:1])
+		if err != nil || class < 1 {
+			return statusRange{}, invalid
+		}
+		r = statusRange{min: class * 100, max: class*100 + 99}
+	case strings.Contains(text, "-"):
+		parts := strings.SplitN(text, "-", 2)
+		low, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+		high, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err1 != nil || err2 != nil || low > high {
+			return statusRange{}, invalid
+		}
+		r = statusRange{min: low, max: high}
+	default:
+		code, err := strconv.Atoi(text)
+		if err != nil {
+			return statusRange{}, invalid
+		}
+		r = statusRange{min: code, max: code}
+	}
+
+	if r.min < 100 || r.max > 599 {
+		return statusRange{}, invalid
+	}
+	return r, nil
+}
+
+// jsonEqual はJSONとしての値が等しいかを返す。数値は表記や型（int, float64, json.Number）によらず比較する。
+func jsonEqual(expected interface{}, actual interface{}) bool {
+	normalizedExpected, err1 := normalizeJSONValue(expected)
+	normalizedActual, err2 := normalizeJSONValue(actual)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return reflect.DeepEqual(normalizedExpected, normalizedActual)
+}
+
+// normalizeJSONValue は値をJSONに変換して読み直し、比較できる形に揃える
+func normalizeJSONValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// formatJSONValue は値をJSONとして表示する
+func formatJSONValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func foundLabel(found bool) string {
+	if found {
+		return "found"
+	}
+	return "not found"
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
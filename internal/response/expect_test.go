@@ -0,0 +1,118 @@
+package response
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+func TestEvaluate(t *testing.T) {
+	data := &config.ResponseData{
+		StatusCode: 403,
+		Headers: map[string][]string{
+			"Content-Type":    {"application/json"},
+			"X-Frame-Options": {"DENY"},
+		},
+		Body: `{"error":{"code":403,"blocked":true,"rules":["942100"]},"message":"Request blocked"}`,
+		Time: config.ResponseTiming{Total: 0.25},
+	}
+
+	tests := []struct {
+		name     string
+		expect   *config.ExpectConfig
+		passed   []bool
+		expected string
+		actual   string
+	}{
+		{name: "status code", expect: &config.ExpectConfig{Status: 403}, passed: []bool{true}},
+		{name: "status code from JSON", expect: &config.ExpectConfig{Status: float64(200)}, passed: []bool{false}, expected: "200", actual: "403"},
+		{name: "status class", expect: &config.ExpectConfig{Status: "4xx"}, passed: []bool{true}},
+		{name: "status range", expect: &config.ExpectConfig{Status: "200-399"}, passed: []bool{false}, expected: "200-399"},
+		{name: "status list", expect: &config.ExpectConfig{Status: []interface{}{200, "403-406"}}, passed: []bool{true}, expected: "200, 403-406"},
+		{name: "header value", expect: &config.ExpectConfig{Headers: map[string]interface{}{"x-frame-options": "DENY"}}, passed: []bool{true}},
+		{name: "header value mismatch", expect: &config.ExpectConfig{Headers: map[string]interface{}{"X-Frame-Options": "SAMEORIGIN"}}, passed: []bool{false}, actual: "DENY"},
+		{
+			name:   "header presence",
+			expect: &config.ExpectConfig{Headers: map[string]interface{}{"Content-Type": true, "Server": false, "X-Request-Id": true}},
+			passed: []bool{true, true, false},
+		},
+		{
+			name:   "body contains and not contains",
+			expect: &config.ExpectConfig{BodyContains: config.StringList{"blocked", "welcome"}, BodyNotContains: config.StringList{"stack trace"}},
+			passed: []bool{true, false, true},
+		},
+		{name: "body regex", expect: &config.ExpectConfig{BodyRegex: config.StringList{`"rules":\["94\d+"\]`}}, passed: []bool{true}},
+		{
+			name: "json equality across number types",
+			expect: &config.ExpectConfig{JSON: map[string]interface{}{
+				"error.code":    403,
+				"error.blocked": true,
+				"error.rules":   []interface{}{"942100"},
+				"message":       "Request allowed",
+			}},
+			passed: []bool{true, true, true, false},
+		},
+		{name: "json missing path", expect: &config.ExpectConfig{JSON: map[string]interface{}{"data.id": 1}}, passed: []bool{false}, actual: "key 'data' not found"},
+		{name: "max time", expect: &config.ExpectConfig{MaxTime: "500ms"}, passed: []bool{true}},
+		{name: "max time exceeded", expect: &config.ExpectConfig{MaxTime: "100ms"}, passed: []bool{false}, expected: "<= 100ms", actual: "250ms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := Evaluate(tt.expect, data)
+			if len(results) != len(tt.passed) {
+				t.Fatalf("Expected %d assertions, got %d: %+v", len(tt.passed), len(results), results)
+			}
+			allPassed := true
+			for i, result := range results {
+				if result.Passed != tt.passed[i] {
+					t.Errorf("Assertion %d (%s): expected passed=%v, got %+v", i, result.Name, tt.passed[i], result)
+				}
+				allPassed = allPassed && tt.passed[i]
+			}
+			if Passed(results) != allPassed {
+				t.Errorf("Expected Passed() = %v", allPassed)
+			}
+			if tt.expected != "" && results[0].Expected != tt.expected {
+				t.Errorf("Expected expected=%q, got %q", tt.expected, results[0].Expected)
+			}
+			if tt.actual != "" && !strings.Contains(results[0].Actual, tt.actual) {
+				t.Errorf("Expected actual to contain %q, got %q", tt.actual, results[0].Actual)
+			}
+		})
+	}
+}
+
+func TestValidateExpect(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect *config.ExpectConfig
+		fields []string
+	}{
+		{name: "valid", expect: &config.ExpectConfig{Status: []interface{}{"2xx", 301, "400-404"}, MaxTime: "2s", JSON: map[string]interface{}{"a[0].b": 1}}},
+		{name: "invalid status", expect: &config.ExpectConfig{Status: "20x"}, fields: []string{"status"}},
+		{name: "status out of range", expect: &config.ExpectConfig{Status: 99}, fields: []string{"status"}},
+		{name: "reversed status range", expect: &config.ExpectConfig{Status: "299-200"}, fields: []string{"status"}},
+		{name: "empty status list", expect: &config.ExpectConfig{Status: []interface{}{}}, fields: []string{"status"}},
+		{name: "invalid header value", expect: &config.ExpectConfig{Headers: map[string]interface{}{"X-A": []interface{}{"a"}}}, fields: []string{"headers.X-A"}},
+		{name: "invalid regex", expect: &config.ExpectConfig{BodyRegex: config.StringList{"("}}, fields: []string{"body-regex"}},
+		{name: "invalid json path", expect: &config.ExpectConfig{JSON: map[string]interface{}{"a[": 1}}, fields: []string{"json.a["}},
+		{name: "invalid max time", expect: &config.ExpectConfig{MaxTime: "fast"}, fields: []string{"max-time"}},
+		{name: "non-positive max time", expect: &config.ExpectConfig{MaxTime: "0s"}, fields: []string{"max-time"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateExpect(tt.expect)
+			if len(errs) != len(tt.fields) {
+				t.Fatalf("Expected errors for %v, got %v", tt.fields, errs)
+			}
+			for _, field := range tt.fields {
+				if errs[field] == nil {
+					t.Errorf("Expected error for %s, got %v", field, errs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// Extract はルールに従ってレスポンスから値を取り出す。
+// 取り出せなかったルールはdefaultがあればその値を使い、なければエラーとして集約する。
+// エラーがあっても取り出せた値は返す。
+func Extract(rules map[string]*config.ExtractRule, data *config.ResponseData) (map[string]interface{}, error) {
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]interface{})
+	var errs []error
+	for _, name := range names {
+		rule := rules[name]
+		value, err := extractValue(rule, data)
+		if err != nil {
+			if rule.Default != nil {
+				values[name] = rule.Default
+				continue
+			}
+			errs = append(errs, fmt.Errorf("extract '%s': %w", name, err))
+			continue
+		}
+		values[name] = value
+	}
+	return values, errors.Join(errs...)
+}
+
+// ValidateExtractRule はルールの取り出し元と正規表現、JSONパスを検証する
+func ValidateExtractRule(rule *config.ExtractRule) error {
+	if rule == nil {
+		return fmt.Errorf("rule must specify one of: json, header, cookie, regex")
+	}
+
+	sources := 0
+	for _, source := range []string{rule.JSON, rule.Header, rule.Cookie} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("only one of json, header, cookie can be specified")
+	}
+	if sources == 0 && rule.Regex == "" {
+		return fmt.Errorf("rule must specify one of: json, header, cookie, regex")
+	}
+
+	if rule.JSON != "" {
+		if _, err := parseJSONPath(rule.JSON); err != nil {
+			return err
+		}
+	}
+
+	if rule.Group != nil && rule.Regex == "" {
+		return fmt.Errorf("group requires regex")
+	}
+	if rule.Regex != "" {
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+		if rule.Group != nil && (*rule.Group < 0 || *rule.Group > re.NumSubexp()) {
+			return fmt.Errorf("group %d out of range, regex has %d group(s)", *rule.Group, re.NumSubexp())
+		}
+	}
+	return nil
+}
+
+// extractValue は1つのルールで値を取り出す
+func extractValue(rule *config.ExtractRule, data *config.ResponseData) (interface{}, error) {
+	if err := ValidateExtractRule(rule); err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	switch {
+	case rule.JSON != "":
+		v, err := LookupJSONPath(data.Body, rule.JSON)
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	case rule.Header != "":
+		values := http.Header(data.Headers).Values(rule.Header)
+		if len(values) == 0 {
+			return nil, fmt.Errorf("header '%s' not found", rule.Header)
+		}
+		value = values[0]
+	case rule.Cookie != "":
+		cookie, ok := findCookie(data.Headers, rule.Cookie)
+		if !ok {
+			return nil, fmt.Errorf("cookie '%s' not found", rule.Cookie)
+		}
+		value = cookie
+	default:
+		value = data.Body
+	}
+
+	if rule.Regex == "" {
+		return value, nil
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("regex requires a string value, got %T", value)
+	}
+	re := regexp.MustCompile(rule.Regex)
+	match := re.FindStringSubmatch(text)
+	if match == nil {
+		return nil, fmt.Errorf("regex did not match")
+	}
+	group := 0
+	if rule.Group != nil {
+		group = *rule.Group
+	} else if re.NumSubexp() > 0 {
+		group = 1
+	}
+	return match[group], nil
+}
+
+// findCookie はSet-Cookieヘッダーから指定した名前のクッキーの値を探す。
+// 同じ名前が複数ある場合は後のものを優先する。
+func findCookie(headers map[string][]string, name string) (string, bool) {
+	var value string
+	found := false
+	for _, line := range http.Header(headers).Values("Set-Cookie") {
+		cookie, err := http.ParseSetCookie(line)
+		if err != nil || cookie.Name != name {
+			continue
+		}
+		value = cookie.Value
+		found = true
+	}
+	return value, found
+}
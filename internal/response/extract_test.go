@@ -0,0 +1,127 @@
+package response
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestExtract(t *testing.T) {
+	data := &config.ResponseData{
+		StatusCode: 200,
+		Headers: map[string][]string{
+			"Location": {"/dashboard?sid=abc123"},
+			"Set-Cookie": {
+				"theme=dark; Path=/",
+				"SESSIONID=old; Path=/",
+				"SESSIONID=s3cr3t; Path=/; HttpOnly",
+			},
+		},
+		Body: `{"data":{"token":"t0k3n","items":[{"id":17},{"id":18}]},"html":"<input name=\"csrf\" value=\"c5rf\">"}`,
+	}
+
+	tests := []struct {
+		name     string
+		rule     *config.ExtractRule
+		expected interface{}
+		errorMsg string
+	}{
+		{name: "json path", rule: &config.ExtractRule{JSON: "data.token"}, expected: "t0k3n"},
+		{name: "json path with index keeps number text", rule: &config.ExtractRule{JSON: "$.data.items[1].id"}, expected: json.Number("18")},
+		{name: "json object", rule: &config.ExtractRule{JSON: "data.items[0]"}, expected: map[string]interface{}{"id": json.Number("17")}},
+		{name: "regex on body uses first group", rule: &config.ExtractRule{Regex: `name=\\"csrf\\" value=\\"([^\\]+)`}, expected: "c5rf"},
+		{name: "regex on json value", rule: &config.ExtractRule{JSON: "html", Regex: `value="(\w+)"`}, expected: "c5rf"},
+		{name: "regex without groups returns whole match", rule: &config.ExtractRule{Regex: `t0k\d+n`}, expected: "t0k3n"},
+		{name: "regex explicit group", rule: &config.ExtractRule{Header: "location", Regex: `(\w+)=(\w+)`, Group: intPtr(2)}, expected: "abc123"},
+		{name: "header is case insensitive", rule: &config.ExtractRule{Header: "location"}, expected: "/dashboard?sid=abc123"},
+		{name: "cookie uses last value", rule: &config.ExtractRule{Cookie: "SESSIONID"}, expected: "s3cr3t"},
+		{name: "default on miss", rule: &config.ExtractRule{Cookie: "missing", Default: "none"}, expected: "none"},
+		{name: "missing header", rule: &config.ExtractRule{Header: "X-Token"}, errorMsg: "header 'X-Token' not found"},
+		{name: "missing cookie", rule: &config.ExtractRule{Cookie: "missing"}, errorMsg: "cookie 'missing' not found"},
+		{name: "missing json key", rule: &config.ExtractRule{JSON: "data.missing"}, errorMsg: "key 'missing' not found"},
+		{name: "index out of range", rule: &config.ExtractRule{JSON: "data.items[5]"}, errorMsg: "index 5 out of range"},
+		{name: "regex no match", rule: &config.ExtractRule{Regex: `nonce=(\d+)`}, errorMsg: "regex did not match"},
+		{name: "regex on non-string", rule: &config.ExtractRule{JSON: "data.items", Regex: `\d+`}, errorMsg: "regex requires a string value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := Extract(map[string]*config.ExtractRule{"v": tt.rule}, data)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				if !strings.Contains(err.Error(), "extract 'v'") {
+					t.Errorf("Expected error to name the variable, got %v", err)
+				}
+				if _, exists := values["v"]; exists {
+					t.Errorf("Expected no value on error, got %v", values["v"])
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(values["v"], tt.expected) {
+				t.Errorf("Expected %#v, got %#v", tt.expected, values["v"])
+			}
+		})
+	}
+}
+
+func TestExtract_KeepsValuesOnPartialFailure(t *testing.T) {
+	data := &config.ResponseData{Body: `{"token":"abc"}`}
+	values, err := Extract(map[string]*config.ExtractRule{
+		"token": {JSON: "token"},
+		"csrf":  {JSON: "csrf"},
+	}, data)
+	if err == nil || !strings.Contains(err.Error(), "extract 'csrf'") {
+		t.Errorf("Expected error for csrf, got %v", err)
+	}
+	if values["token"] != "abc" {
+		t.Errorf("Expected token to be extracted, got %v", values)
+	}
+}
+
+func TestValidateExtractRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     *config.ExtractRule
+		errorMsg string
+	}{
+		{name: "json", rule: &config.ExtractRule{JSON: "a.b[0]"}},
+		{name: "whole body", rule: &config.ExtractRule{JSON: "$"}},
+		{name: "header with regex", rule: &config.ExtractRule{Header: "Location", Regex: `id=(\d+)`, Group: intPtr(1)}},
+		{name: "no source", rule: &config.ExtractRule{}, errorMsg: "must specify one of"},
+		{name: "nil rule", rule: nil, errorMsg: "must specify one of"},
+		{name: "multiple sources", rule: &config.ExtractRule{JSON: "a", Cookie: "b"}, errorMsg: "only one of"},
+		{name: "invalid regex", rule: &config.ExtractRule{Regex: "("}, errorMsg: "invalid regex"},
+		{name: "group out of range", rule: &config.ExtractRule{Regex: `(a)`, Group: intPtr(2)}, errorMsg: "group 2 out of range"},
+		{name: "group without regex", rule: &config.ExtractRule{JSON: "a", Group: intPtr(1)}, errorMsg: "group requires regex"},
+		{name: "empty json key", rule: &config.ExtractRule{JSON: "a..b"}, errorMsg: "empty key"},
+		{name: "malformed index", rule: &config.ExtractRule{JSON: "a[x]"}, errorMsg: "non-negative integer"},
+		{name: "unclosed index", rule: &config.ExtractRule{JSON: "a[0"}, errorMsg: "malformed index"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateExtractRule(tt.rule)
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
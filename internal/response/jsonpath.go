@@ -0,0 +1,90 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LookupJSONPath はJSONのボディからパスで指定した値を取り出す。
+// パスはドット区切りのキーと[n]形式の配列インデックスで指定する（"data.items[0].id"）。
+// 先頭の"$"は省略できる。数値は元の表記を保つためjson.Numberとして返す。
+func LookupJSONPath(body string, path string) (interface{}, error) {
+	tokens, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("response body is not valid JSON: %w", err)
+	}
+
+	for _, token := range tokens {
+		switch key := token.(type) {
+		case string:
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("json path '%s': '%s' is not an object", path, key)
+			}
+			if value, ok = object[key]; !ok {
+				return nil, fmt.Errorf("json path '%s': key '%s' not found", path, key)
+			}
+		case int:
+			array, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("json path '%s': index %d is not an array", path, key)
+			}
+			if key >= len(array) {
+				return nil, fmt.Errorf("json path '%s': index %d out of range (length %d)", path, key, len(array))
+			}
+			value = array[key]
+		}
+	}
+	return value, nil
+}
+
+// parseJSONPath はJSONパスをキー（string）とインデックス（int）の列に分解する
+func parseJSONPath(path string) ([]interface{}, error) {
+	rest := strings.TrimPrefix(path, "$")
+	rest = strings.TrimPrefix(rest, ".")
+	if rest == "" {
+		// "$"のみの場合はボディ全体
+		if path == "$" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("json path cannot be empty")
+	}
+
+	var tokens []interface{}
+	for _, segment := range strings.Split(rest, ".") {
+		key := segment
+		var indexes string
+		if i := strings.Index(segment, "["); i >= 0 {
+			key, indexes = segment[:i], segment[i:]
+		}
+		if key == "" && indexes == "" {
+			return nil, fmt.Errorf("invalid json path '%s': empty key", path)
+		}
+		if key != "" {
+			tokens = append(tokens, key)
+		}
+
+		for indexes != "" {
+			end := strings.Index(indexes, "]")
+			if !strings.HasPrefix(indexes, "[") || end < 0 {
+				return nil, fmt.Errorf("invalid json path '%s': malformed index", path)
+			}
+			index, err := strconv.Atoi(indexes[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid json path '%s': index must be a non-negative integer", path)
+			}
+			tokens = append(tokens, index)
+			indexes = indexes[end+1:]
+		}
+	}
+	return tokens, nil
+}
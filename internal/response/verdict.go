@@ -0,0 +1,210 @@
+package response
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/secureta/s2http-request/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed verdict_rules.yaml
+var builtinVerdictRules []byte
+
+// BuiltinVerdictRules は組み込みのブロックページのシグネチャを返す
+func BuiltinVerdictRules() []config.VerdictRule {
+	var rules config.VerdictConfig
+	if err := yaml.Unmarshal(builtinVerdictRules, &rules); err != nil {
+		panic(fmt.Sprintf("invalid built-in verdict rules: %v", err))
+	}
+	return rules.Rules
+}
+
+// LoadVerdictConfig は判定ルールのファイル（YAMLまたはJSON）を読み込んで検証する
+func LoadVerdictConfig(path string) (*config.VerdictConfig, error) {
+	// The CLI intentionally reads user-supplied rule files.
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("failed to read verdict rules: %w", err)
+	}
+
+	var verdictConfig config.VerdictConfig
+	if err := yaml.Unmarshal(data, &verdictConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse verdict rules: %w", err)
+	}
+	if verdictConfig.RulesFile != "" {
+		return nil, fmt.Errorf("rules-file cannot be used inside a verdict rules file")
+	}
+	if _, err := NewClassifier(verdictConfig.Rules); err != nil {
+		return nil, fmt.Errorf("invalid verdict rules: %w", err)
+	}
+	return &verdictConfig, nil
+}
+
+// Classifier はルールを順に適用してレスポンスを判定する
+type Classifier struct {
+	rules []compiledVerdictRule
+}
+
+// compiledVerdictRule は正規表現をコンパイル済みのルール
+type compiledVerdictRule struct {
+	name         string
+	verdict      config.Verdict
+	status       []statusRange
+	headers      map[string]*regexp.Regexp // nilの値は存在のみを確認する
+	bodyContains []string
+	bodyRegex    []*regexp.Regexp
+}
+
+// NewClassifier はルールをコンパイルしてClassifierを作成する。ルールは先頭から順に適用される。
+func NewClassifier(rules []config.VerdictRule) (*Classifier, error) {
+	classifier := &Classifier{}
+	for i, rule := range rules {
+		if errs := ValidateVerdictRule(rule); len(errs) > 0 {
+			messages := make([]string, 0, len(errs))
+			for _, field := range sortedErrorKeys(errs) {
+				if field == "" {
+					messages = append(messages, errs[field].Error())
+					continue
+				}
+				messages = append(messages, fmt.Sprintf("%s: %v", field, errs[field]))
+			}
+			return nil, fmt.Errorf("rule %d (%s): %s", i, rule.Name, strings.Join(messages, "; "))
+		}
+
+		compiled := compiledVerdictRule{
+			name:         rule.Name,
+			verdict:      rule.Verdict,
+			bodyContains: rule.BodyContains,
+			headers:      make(map[string]*regexp.Regexp),
+		}
+		if compiled.verdict == "" {
+			compiled.verdict = config.VerdictBlocked
+		}
+		if rule.Status != nil {
+			compiled.status, _ = parseStatusRanges(rule.Status)
+		}
+		for name, pattern := range rule.Headers {
+			var re *regexp.Regexp
+			if pattern != "" {
+				re = regexp.MustCompile(pattern)
+			}
+			compiled.headers[name] = re
+		}
+		for _, pattern := range rule.BodyRegex {
+			compiled.bodyRegex = append(compiled.bodyRegex, regexp.MustCompile(pattern))
+		}
+		classifier.rules = append(classifier.rules, compiled)
+	}
+	return classifier, nil
+}
+
+// Classify はレスポンスを判定し、判定と該当したルール名を返す。
+// どのルールにも該当しない場合、5xxはerror、それ以外はpassedとなる（ルール名は空）。
+func (c *Classifier) Classify(data *config.ResponseData) (config.Verdict, string) {
+	for _, rule := range c.rules {
+		if rule.matches(data) {
+			return rule.verdict, rule.name
+		}
+	}
+	if data.StatusCode >= 500 {
+		return config.VerdictError, ""
+	}
+	return config.VerdictPassed, ""
+}
+
+// matches はルールの全ての条件を満たすかを返す
+func (r *compiledVerdictRule) matches(data *config.ResponseData) bool {
+	if len(r.status) > 0 {
+		matched := false
+		for _, status := range r.status {
+			if data.StatusCode >= status.min && data.StatusCode <= status.max {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	for name, re := range r.headers {
+		values := http.Header(data.Headers).Values(name)
+		if len(values) == 0 {
+			return false
+		}
+		if re == nil {
+			continue
+		}
+		matched := false
+		for _, value := range values {
+			if re.MatchString(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	for _, text := range r.bodyContains {
+		if !strings.Contains(data.Body, text) {
+			return false
+		}
+	}
+	for _, re := range r.bodyRegex {
+		if !re.MatchString(data.Body) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateVerdictRule はルールを検証し、不正な項目をプロパティ名ごとに返す（ルール全体に対するエラーは空文字のキー）
+func ValidateVerdictRule(rule config.VerdictRule) map[string]error {
+	errs := make(map[string]error)
+
+	if rule.Name == "" {
+		errs["name"] = fmt.Errorf("rule name is required")
+	}
+	switch rule.Verdict {
+	case "", config.VerdictBlocked, config.VerdictPassed, config.VerdictError:
+	default:
+		errs["verdict"] = fmt.Errorf("unknown verdict '%s', expected one of: blocked, passed, error", rule.Verdict)
+	}
+	if rule.Status == nil && len(rule.Headers) == 0 && len(rule.BodyContains) == 0 && len(rule.BodyRegex) == 0 {
+		errs[""] = fmt.Errorf("rule must specify at least one of: status, headers, body-contains, body-regex")
+	}
+
+	if rule.Status != nil {
+		if _, err := parseStatusRanges(rule.Status); err != nil {
+			errs["status"] = err
+		}
+	}
+	for name, pattern := range rule.Headers {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs["headers."+name] = fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	for _, pattern := range rule.BodyRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs["body-regex"] = fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	return errs
+}
+
+func sortedErrorKeys(errs map[string]error) []string {
+	keys := make([]string, 0, len(errs))
+	for key := range errs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
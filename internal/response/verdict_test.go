@@ -0,0 +1,203 @@
+package response
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+func TestClassifier_BuiltinRules(t *testing.T) {
+	classifier, err := NewClassifier(BuiltinVerdictRules())
+	if err != nil {
+		t.Fatalf("Built-in rules must be valid: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    *config.ResponseData
+		verdict config.Verdict
+		rule    string
+	}{
+		{
+			name: "cloudflare block page",
+			data: &config.ResponseData{
+				StatusCode: 403,
+				Headers:    map[string][]string{"Server": {"cloudflare"}},
+				Body:       "<title>Attention Required! | Cloudflare</title>",
+			},
+			verdict: config.VerdictBlocked,
+			rule:    "cloudflare",
+		},
+		{
+			name: "aws waf behind cloudfront",
+			data: &config.ResponseData{
+				StatusCode: 403,
+				Body:       "<H1>403 ERROR</H1><H2>The request could not be satisfied.</H2>Request blocked.\nGenerated by cloudfront (CloudFront)",
+			},
+			verdict: config.VerdictBlocked,
+			rule:    "aws-waf-cloudfront",
+		},
+		{
+			name: "aws waf on alb",
+			data: &config.ResponseData{
+				StatusCode: 403,
+				Headers:    map[string][]string{"Server": {"awselb/2.0"}},
+				Body:       "<html><head><title>403 Forbidden</title></head></html>",
+			},
+			verdict: config.VerdictBlocked,
+			rule:    "aws-waf-alb",
+		},
+		{
+			name: "modsecurity",
+			data: &config.ResponseData{
+				StatusCode: 406,
+				Body:       "<h1>Not Acceptable!</h1><p>An appropriate representation of the requested resource could not be found on this server. This error was generated by Mod_Security.</p>",
+			},
+			verdict: config.VerdictBlocked,
+			rule:    "modsecurity",
+		},
+		{
+			name:    "generic forbidden",
+			data:    &config.ResponseData{StatusCode: 403, Body: "Forbidden"},
+			verdict: config.VerdictBlocked,
+			rule:    "generic-block-status",
+		},
+		{
+			name:    "passed",
+			data:    &config.ResponseData{StatusCode: 200, Body: "<html>results</html>"},
+			verdict: config.VerdictPassed,
+		},
+		{
+			name:    "unmatched server error",
+			data:    &config.ResponseData{StatusCode: 502, Body: "Bad Gateway"},
+			verdict: config.VerdictError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, rule := classifier.Classify(tt.data)
+			if verdict != tt.verdict || rule != tt.rule {
+				t.Errorf("Expected %s (%q), got %s (%q)", tt.verdict, tt.rule, verdict, rule)
+			}
+		})
+	}
+}
+
+func TestClassifier_CustomRules(t *testing.T) {
+	classifier, err := NewClassifier([]config.VerdictRule{
+		{
+			Name:         "app-error-page",
+			Verdict:      config.VerdictPassed,
+			Status:       403,
+			BodyContains: config.StringList{"You do not have access to this project"},
+		},
+		{
+			Name:    "custom-waf",
+			Status:  "4xx",
+			Headers: map[string]string{"X-Waf-Event": "", "X-Waf-Action": "(?i)^block$"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    *config.ResponseData
+		verdict config.Verdict
+		rule    string
+	}{
+		{
+			name:    "first matching rule wins",
+			data:    &config.ResponseData{StatusCode: 403, Body: "You do not have access to this project"},
+			verdict: config.VerdictPassed,
+			rule:    "app-error-page",
+		},
+		{
+			name:    "all conditions must match",
+			data:    &config.ResponseData{StatusCode: 429, Headers: map[string][]string{"X-Waf-Event": {"1"}, "X-Waf-Action": {"BLOCK"}}},
+			verdict: config.VerdictBlocked,
+			rule:    "custom-waf",
+		},
+		{
+			name:    "missing header does not match",
+			data:    &config.ResponseData{StatusCode: 429, Headers: map[string][]string{"X-Waf-Action": {"block"}}},
+			verdict: config.VerdictPassed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, rule := classifier.Classify(tt.data)
+			if verdict != tt.verdict || rule != tt.rule {
+				t.Errorf("Expected %s (%q), got %s (%q)", tt.verdict, tt.rule, verdict, rule)
+			}
+		})
+	}
+}
+
+func TestValidateVerdictRule(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   config.VerdictRule
+		fields []string
+	}{
+		{name: "valid", rule: config.VerdictRule{Name: "a", Status: []interface{}{403, "5xx"}, BodyRegex: config.StringList{"(?i)denied"}}},
+		{name: "missing name and conditions", rule: config.VerdictRule{}, fields: []string{"name", ""}},
+		{name: "unknown verdict", rule: config.VerdictRule{Name: "a", Verdict: "allowed", Status: 200}, fields: []string{"verdict"}},
+		{name: "invalid status", rule: config.VerdictRule{Name: "a", Status: "4x"}, fields: []string{"status"}},
+		{name: "invalid header regex", rule: config.VerdictRule{Name: "a", Headers: map[string]string{"Server": "("}}, fields: []string{"headers.Server"}},
+		{name: "invalid body regex", rule: config.VerdictRule{Name: "a", BodyRegex: config.StringList{"["}}, fields: []string{"body-regex"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateVerdictRule(tt.rule)
+			if len(errs) != len(tt.fields) {
+				t.Fatalf("Expected errors for %q, got %v", tt.fields, errs)
+			}
+			for _, field := range tt.fields {
+				if errs[field] == nil {
+					t.Errorf("Expected error for %q, got %v", field, errs)
+				}
+			}
+		})
+	}
+
+	if _, err := NewClassifier([]config.VerdictRule{{Name: "ok", Status: 403}, {Name: "bad", Status: "4x"}}); err == nil || !strings.Contains(err.Error(), "rule 1 (bad): status: invalid status") {
+		t.Errorf("Expected NewClassifier to report the invalid rule, got %v", err)
+	}
+}
+
+func TestLoadVerdictConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	verdictConfig, err := LoadVerdictConfig(write("rules.yaml", "builtin: false\nrules:\n  - name: custom\n    status: 418\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if verdictConfig.Builtin == nil || *verdictConfig.Builtin || len(verdictConfig.Rules) != 1 {
+		t.Errorf("Unexpected config: %+v", verdictConfig)
+	}
+
+	if _, err := LoadVerdictConfig(write("bad.yaml", "rules:\n  - name: bad\n    body-regex: '('\n")); err == nil || !strings.Contains(err.Error(), "body-regex: invalid regex") {
+		t.Errorf("Expected invalid rule error, got %v", err)
+	}
+	if _, err := LoadVerdictConfig(write("nested.yaml", "rules-file: other.yaml\n")); err == nil {
+		t.Errorf("Expected error for rules-file inside a rules file")
+	}
+	if _, err := LoadVerdictConfig(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Errorf("Expected error for missing file")
+	}
+}
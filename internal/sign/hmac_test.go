@@ -0,0 +1,142 @@
+package sign
+
+import (
+	"testing"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+func TestSignHMAC(t *testing.T) {
+	// RFC 4231 / RFC 2202 のテストケース（key="Jefe"）
+	const message = "what do ya want for nothing?"
+	tests := []struct {
+		name       string
+		hmacConfig HMACConfig
+		want       string
+	}{
+		{
+			name:       "sha256 default",
+			hmacConfig: HMACConfig{Key: "Jefe", Template: message, Header: "X-Signature"},
+			want:       "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		},
+		{
+			name:       "sha1",
+			hmacConfig: HMACConfig{Key: "Jefe", Algorithm: "sha1", Template: message, Header: "X-Signature"},
+			want:       "effcdf6ae5eb2fa2d27416d5f184df9c259a7c79",
+		},
+		{
+			name:       "sha512",
+			hmacConfig: HMACConfig{Key: "Jefe", Algorithm: "SHA512", Template: message, Header: "X-Signature"},
+			want:       "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea2505549758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737",
+		},
+		{
+			name:       "base64 with prefix",
+			hmacConfig: HMACConfig{Key: "Jefe", Template: message, Header: "Authorization", Prefix: "HMAC ", Encoding: "base64"},
+			want:       "HMAC W9zBRr9gdU5qBCQmCJV1x1oAPwidJzmDnexYuWTsOEM=",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := config.ProcessedRequest{Method: "GET", URL: "https://example.com/", Headers: map[string]string{"authorization": "stale"}}
+			if err := SignHMAC(&request, tt.hmacConfig); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := request.Headers[tt.hmacConfig.Header]; got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+			if tt.hmacConfig.Header == "Authorization" && len(request.Headers) != 1 {
+				t.Errorf("Expected the existing header to be replaced, got %v", request.Headers)
+			}
+		})
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	request := &config.ProcessedRequest{
+		Method:  "POST",
+		URL:     "https://api.example.com:8443/v1/items%20x?b=2&a=1#frag",
+		Headers: map[string]string{"x-timestamp": "1700000000"},
+		Body:    "{}",
+	}
+	template := "{method}\n{host}\n{path}\n{query}\n{target}\n{header:X-Timestamp}\n{header:Missing}\n{body}\n{body-sha256}\n{literal"
+	want := "POST\napi.example.com:8443\n/v1/items%20x\nb=2&a=1\n/v1/items%20x?b=2&a=1\n1700000000\n\n{}\n" +
+		"44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a\n{literal"
+	got, err := expandTemplate(template, request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("Unexpected expansion:\n got: %q\nwant: %q", got, want)
+	}
+
+	// raw request targetとHostヘッダーは送信される値を使用する
+	raw := &config.ProcessedRequest{Method: "GET", URL: "http://origin.test/%zz?x", RawRequestTarget: "/%zz?x", Headers: map[string]string{"Host": "virtual.test"}}
+	if got, err := expandTemplate("{host} {path} {query}", raw); err != nil || got != "virtual.test /%zz x" {
+		t.Errorf("Unexpected raw expansion: %q, %v", got, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		signConfig config.SignConfig
+		want       map[string]string
+	}{
+		{
+			name:       "valid aws",
+			signConfig: config.SignConfig{Type: config.SignTypeAWSSigV4, AccessKey: "a", SecretKey: "s", Region: "us-east-1", Service: "execute-api"},
+			want:       map[string]string{},
+		},
+		{
+			name:       "aws missing fields",
+			signConfig: config.SignConfig{Type: config.SignTypeAWSSigV4, AccessKey: "a"},
+			want: map[string]string{
+				"secret-key": "secret-key is required for aws-sigv4 signing",
+				"region":     "region is required for aws-sigv4 signing",
+				"service":    "service is required for aws-sigv4 signing",
+			},
+		},
+		{
+			name:       "valid hmac",
+			signConfig: config.SignConfig{Type: config.SignTypeHMAC, Key: "k", Template: "{method}\n{header:Date}", Header: "X-Signature", Algorithm: "sha512", Encoding: "base64"},
+			want:       map[string]string{},
+		},
+		{
+			name:       "hmac invalid fields",
+			signConfig: config.SignConfig{Type: config.SignTypeHMAC, Key: "k", Template: "{method}{nonce}", Header: "X-Signature", Algorithm: "md5", Encoding: "base32"},
+			want: map[string]string{
+				"template":  "unknown placeholder {nonce}, expected one of: method, host, path, query, target, body, body-sha256, header:Name",
+				"algorithm": "unknown algorithm 'md5', expected one of: sha1, sha256, sha384, sha512",
+				"encoding":  "unknown encoding 'base32', expected hex or base64",
+			},
+		},
+		{
+			name:       "hmac header placeholder without name",
+			signConfig: config.SignConfig{Type: config.SignTypeHMAC, Key: "k", Template: "{header}", Header: "X-Signature"},
+			want:       map[string]string{"template": "placeholder {header:Name} requires a header name"},
+		},
+		{
+			name:       "missing type",
+			signConfig: config.SignConfig{},
+			want:       map[string]string{"": "sign type is required, expected one of: aws-sigv4, hmac"},
+		},
+		{
+			name:       "unknown type",
+			signConfig: config.SignConfig{Type: "oauth1"},
+			want:       map[string]string{"type": "unknown sign type 'oauth1', expected one of: aws-sigv4, hmac"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Validate(&tt.signConfig)
+			if len(errs) != len(tt.want) {
+				t.Fatalf("Expected %d errors, got %v", len(tt.want), errs)
+			}
+			for key, message := range tt.want {
+				if errs[key] == nil || errs[key].Error() != message {
+					t.Errorf("Expected %s error %q, got %v", key, message, errs[key])
+				}
+			}
+		})
+	}
+}
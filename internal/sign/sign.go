@@ -0,0 +1,114 @@
+package sign
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// target は署名の対象となるリクエストの送信先
+type target struct {
+	host  string // Hostヘッダー（明示されていない場合はURLのホスト）
+	path  string // 送信されるエスケープ済みのパス
+	query string // 送信されるクエリ文字列（?を含まない）
+}
+
+// requestTarget は処理済みリクエストが実際に送信される際のホスト、パス、クエリを返す
+func requestTarget(request *config.ProcessedRequest) (target, error) {
+	var t target
+	requestURI := request.RawRequestTarget
+	if parsedURL, err := url.Parse(request.URL); err == nil {
+		t.host = parsedURL.Host
+		if requestURI == "" {
+			requestURI = parsedURL.EscapedPath()
+			if parsedURL.RawQuery != "" {
+				requestURI += "?" + parsedURL.RawQuery
+			}
+		}
+	} else if request.RawRequestTarget != "" {
+		// raw request targetのURLは解析できない場合があるため、ホストだけを取り出す
+		_, rest, ok := strings.Cut(request.URL, "://")
+		if !ok {
+			return t, fmt.Errorf("failed to parse URL: %w", err)
+		}
+		if end := strings.IndexAny(rest, "/?#"); end >= 0 {
+			rest = rest[:end]
+		}
+		t.host = rest
+	} else {
+		return t, fmt.Errorf("failed to parse URL: %w", err)
+	}
+
+	if host, ok := headerValue(request.Headers, "Host"); ok {
+		t.host = strings.TrimSpace(host)
+	}
+	requestURI, _, _ = strings.Cut(requestURI, "#")
+	t.path, t.query, _ = strings.Cut(requestURI, "?")
+	if t.path == "" {
+		t.path = "/"
+	}
+	return t, nil
+}
+
+// headerValue はヘッダーの値を大文字小文字を区別せずに返す
+func headerValue(headers map[string]string, name string) (string, bool) {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// setHeader は同じ名前（大文字小文字を区別しない）のヘッダーを置き換えて設定する
+func setHeader(request *config.ProcessedRequest, name, value string) {
+	if request.Headers == nil {
+		request.Headers = make(map[string]string)
+	}
+	for key := range request.Headers {
+		if strings.EqualFold(key, name) {
+			delete(request.Headers, key)
+		}
+	}
+	request.Headers[name] = value
+}
+
+// Validate はmeta.signの設定を検証する。キーは問題のあるフィールド名（typeが未指定の場合は空文字）。
+func Validate(signConfig *config.SignConfig) map[string]error {
+	errs := make(map[string]error)
+	required := func(name string, value interface{}) {
+		if value == nil || value == "" {
+			errs[name] = fmt.Errorf("%s is required for %s signing", name, signConfig.Type)
+		}
+	}
+
+	switch signConfig.Type {
+	case config.SignTypeAWSSigV4:
+		required("access-key", signConfig.AccessKey)
+		required("secret-key", signConfig.SecretKey)
+		required("region", signConfig.Region)
+		required("service", signConfig.Service)
+	case config.SignTypeHMAC:
+		required("key", signConfig.Key)
+		required("template", signConfig.Template)
+		required("header", signConfig.Header)
+		if signConfig.Template != "" {
+			if err := validateTemplate(signConfig.Template); err != nil {
+				errs["template"] = err
+			}
+		}
+		if newHMACHash(signConfig.Algorithm) == nil {
+			errs["algorithm"] = fmt.Errorf("unknown algorithm '%s', expected one of: sha1, sha256, sha384, sha512", signConfig.Algorithm)
+		}
+		if signConfig.Encoding != "" && signConfig.Encoding != "hex" && signConfig.Encoding != "base64" {
+			errs["encoding"] = fmt.Errorf("unknown encoding '%s', expected hex or base64", signConfig.Encoding)
+		}
+	case "":
+		errs[""] = fmt.Errorf("sign type is required, expected one of: aws-sigv4, hmac")
+	default:
+		errs["type"] = fmt.Errorf("unknown sign type '%s', expected one of: aws-sigv4, hmac", signConfig.Type)
+	}
+	return errs
+}
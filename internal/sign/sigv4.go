@@ -0,0 +1,199 @@
+package sign
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+const (
+	awsAlgorithm  = "AWS4-HMAC-SHA256"
+	awsTimeFormat = "20060102T150405Z"
+)
+
+// AWSCredentials はAWS Signature Version 4の署名に使用する認証情報
+type AWSCredentials struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Region       string
+	Service      string
+}
+
+// SignAWSV4 はリクエストにAWS Signature Version 4のAuthorizationヘッダーを設定する。
+// X-Amz-Dateが指定されている場合はその日時で署名し、指定されていない場合はnowを設定する。
+// 署名の対象はAuthorizationを除く全てのヘッダーとHost。
+func SignAWSV4(request *config.ProcessedRequest, credentials AWSCredentials, now time.Time) error {
+	t, err := requestTarget(request)
+	if err != nil {
+		return err
+	}
+
+	amzDate, ok := headerValue(request.Headers, "X-Amz-Date")
+	if ok {
+		if _, err := time.Parse(awsTimeFormat, amzDate); err != nil {
+			return fmt.Errorf("invalid X-Amz-Date '%s', expected format %s", amzDate, awsTimeFormat)
+		}
+	} else {
+		amzDate = now.UTC().Format(awsTimeFormat)
+		setHeader(request, "X-Amz-Date", amzDate)
+	}
+	if credentials.SessionToken != "" {
+		setHeader(request, "X-Amz-Security-Token", credentials.SessionToken)
+	}
+	payloadHash := hashHex(request.Body)
+	if credentials.Service == "s3" {
+		if _, ok := headerValue(request.Headers, "X-Amz-Content-Sha256"); !ok {
+			setHeader(request, "X-Amz-Content-Sha256", payloadHash)
+		}
+	}
+	for key := range request.Headers {
+		if strings.EqualFold(key, "Authorization") {
+			delete(request.Headers, key)
+		}
+	}
+
+	canonicalHeaders, signedHeaders := awsCanonicalHeaders(request.Headers, t.host)
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		awsCanonicalURI(t.path, credentials.Service),
+		awsCanonicalQuery(t.query),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{amzDate[:8], credentials.Region, credentials.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{awsAlgorithm, amzDate, scope, hashHex(canonicalRequest)}, "\n")
+
+	key := []byte("AWS4" + credentials.SecretKey)
+	for _, part := range []string{amzDate[:8], credentials.Region, credentials.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Headers["Authorization"] = fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsAlgorithm, credentials.AccessKey, scope, signedHeaders, signature)
+	return nil
+}
+
+// awsCanonicalURI は正規化したパスを返す。S3以外のサービスではドットセグメントを除去し、
+// 送信されるエスケープ済みのパスを更にエスケープする（AWS SDKと同じ二重エンコード）。
+func awsCanonicalURI(path, service string) string {
+	if service == "s3" {
+		return path
+	}
+	return awsEscape(removeDotSegments(path), false)
+}
+
+// awsCanonicalQuery はクエリパラメータをデコードして再エンコードし、キーと値の順にソートして連結する
+func awsCanonicalQuery(query string) string {
+	var params []string
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		params = append(params, awsEscape(queryUnescape(key), true)+"="+awsEscape(queryUnescape(value), true))
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// awsCanonicalHeaders は正規化したヘッダーと署名するヘッダー名の一覧を返す。
+// 値の前後の空白を除き、連続する空白を1つにまとめる。
+func awsCanonicalHeaders(headers map[string]string, host string) (string, string) {
+	values := map[string][]string{"host": {host}}
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := strings.ToLower(key)
+		if name == "host" {
+			continue // requestTargetでHostヘッダーを反映済み
+		}
+		values[name] = append(values[name], strings.Join(strings.Fields(headers[key]), " "))
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + strings.Join(values[name], ",") + "\n")
+	}
+	return canonical.String(), strings.Join(names, ";")
+}
+
+// awsEscape はRFC 3986の非予約文字以外をパーセントエンコードする
+func awsEscape(s string, escapeSlash bool) string {
+	var escaped strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !escapeSlash {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
+
+// queryUnescape はクエリの値をデコードする。不正なエスケープの場合はそのまま返す。
+func queryUnescape(s string) string {
+	if unescaped, err := url.QueryUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+// removeDotSegments はパスから"."と".."のセグメントを除去する（RFC 3986 5.2.4）
+func removeDotSegments(path string) string {
+	segments := strings.Split(path, "/")
+	result := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		switch segment {
+		case ".":
+		case "..":
+			if len(result) > 1 {
+				result = result[:len(result)-1]
+			}
+		default:
+			result = append(result, segment)
+			continue
+		}
+		// 末尾のドットセグメントはディレクトリとして扱う
+		if i == len(segments)-1 {
+			result = append(result, "")
+		}
+	}
+	normalized := strings.Join(result, "/")
+	if !strings.HasPrefix(normalized, "/") {
+		normalized = "/" + normalized
+	}
+	return normalized
+}
+
+func hashHex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
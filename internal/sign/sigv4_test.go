@@ -0,0 +1,141 @@
+package sign
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secureta/s2http-request/internal/config"
+)
+
+// AWSのSignature Version 4テストスイートの認証情報
+var testCredentials = AWSCredentials{
+	AccessKey: "AKIDEXAMPLE",
+	SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	Region:    "us-east-1",
+	Service:   "service",
+}
+
+func TestSignAWSV4_TestSuite(t *testing.T) {
+	tests := []struct {
+		name          string
+		request       config.ProcessedRequest
+		signedHeaders string
+		signature     string
+	}{
+		{
+			name:          "get-vanilla",
+			request:       config.ProcessedRequest{Method: "GET", URL: "https://example.amazonaws.com/"},
+			signedHeaders: "host;x-amz-date",
+			signature:     "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:          "get-vanilla-query-order-key-case",
+			request:       config.ProcessedRequest{Method: "GET", URL: "https://example.amazonaws.com/?Param2=value2&Param1=value1"},
+			signedHeaders: "host;x-amz-date",
+			signature:     "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			name: "get-header-value-trim",
+			request: config.ProcessedRequest{Method: "GET", URL: "https://example.amazonaws.com/", Headers: map[string]string{
+				"My-Header1": " value1",
+				"My-Header2": ` "a   b   c"`,
+			}},
+			signedHeaders: "host;my-header1;my-header2;x-amz-date",
+			signature:     "acc3ed3afb60bb290fc8d2dd0098b9911fcaa05412b367055dee359757a9c736",
+		},
+		{
+			name:          "post-vanilla",
+			request:       config.ProcessedRequest{Method: "POST", URL: "https://example.amazonaws.com/"},
+			signedHeaders: "host;x-amz-date",
+			signature:     "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			name: "post-x-www-form-urlencoded",
+			request: config.ProcessedRequest{Method: "POST", URL: "https://example.amazonaws.com/", Body: "Param1=value1", Headers: map[string]string{
+				"Content-Type": "application/x-www-form-urlencoded",
+			}},
+			signedHeaders: "content-type;host;x-amz-date",
+			signature:     "ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+		{
+			name:          "raw request target",
+			request:       config.ProcessedRequest{Method: "GET", URL: "https://example.amazonaws.com/?Param2=value2&Param1=value1", RawRequestTarget: "/?Param2=value2&Param1=value1"},
+			signedHeaders: "host;x-amz-date",
+			signature:     "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := tt.request
+			if request.Headers == nil {
+				request.Headers = map[string]string{}
+			}
+			request.Headers["X-Amz-Date"] = "20150830T123600Z"
+
+			if err := SignAWSV4(&request, testCredentials, time.Now()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=" + tt.signedHeaders + ", Signature=" + tt.signature
+			if got := request.Headers["Authorization"]; got != want {
+				t.Errorf("Unexpected Authorization:\n got: %s\nwant: %s", got, want)
+			}
+		})
+	}
+}
+
+func TestSignAWSV4_SetsHeaders(t *testing.T) {
+	request := config.ProcessedRequest{Method: "PUT", URL: "https://bucket.s3.amazonaws.com/key", Body: "data", Headers: map[string]string{
+		"authorization": "Bearer stale",
+	}}
+	credentials := testCredentials
+	credentials.Service = "s3"
+	credentials.SessionToken = "token"
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("JST", 9*60*60))
+	if err := SignAWSV4(&request, credentials, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := request.Headers["X-Amz-Date"]; got != "20240101T180405Z" {
+		t.Errorf("Expected X-Amz-Date in UTC, got %q", got)
+	}
+	if got := request.Headers["X-Amz-Security-Token"]; got != "token" {
+		t.Errorf("Expected session token header, got %q", got)
+	}
+	if got := request.Headers["X-Amz-Content-Sha256"]; got != hashHex("data") {
+		t.Errorf("Expected payload hash header for s3, got %q", got)
+	}
+	if _, ok := request.Headers["authorization"]; ok {
+		t.Errorf("Expected the existing Authorization header to be replaced")
+	}
+	if got := request.Headers["Authorization"]; !strings.Contains(got, "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,") {
+		t.Errorf("Unexpected Authorization: %s", got)
+	}
+
+	invalid := config.ProcessedRequest{Method: "GET", URL: "https://example.com/", Headers: map[string]string{"X-Amz-Date": "2024-01-02"}}
+	if err := SignAWSV4(&invalid, credentials, now); err == nil {
+		t.Errorf("Expected an error for an invalid X-Amz-Date")
+	}
+}
+
+func TestAWSCanonical(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "path dot segments", got: awsCanonicalURI("/a/./b/../c/", "service"), want: "/a/c/"},
+		{name: "path trailing dot", got: awsCanonicalURI("/a/..", "service"), want: "/"},
+		{name: "path double encoding", got: awsCanonicalURI("/a%20b", "service"), want: "/a%2520b"},
+		{name: "s3 path", got: awsCanonicalURI("/a/../b%20c", "s3"), want: "/a/../b%20c"},
+		{name: "query sort and encoding", got: awsCanonicalQuery("b=2&a=x+y&a=1&c&d=%7E"), want: "a=1&a=x%20y&b=2&c=&d=~"},
+		{name: "empty query", got: awsCanonicalQuery(""), want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, tt.got)
+			}
+		})
+	}
+}
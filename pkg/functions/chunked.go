@@ -0,0 +1,159 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChunkedFunction はchunked転送エンコーディングのボディを構築する関数。
+// 不正なサイズや終端チャンクの欠落など、仕様に反するボディも構築できる。
+type ChunkedFunction struct{}
+
+func (f *ChunkedFunction) Name() string {
+	return "chunked"
+}
+
+func (f *ChunkedFunction) Signature() string {
+	return "$chunked {chunks: [<string> | {data, size, extension}], trailers: <map>, uppercase: <bool>, terminator: <bool>}"
+}
+
+func (f *ChunkedFunction) Description() string {
+	return "チャンクのリストをTransfer-Encoding: chunkedのボディに変換します。チャンクごとにサイズの宣言と拡張を指定でき、trailersでトレーラー、uppercaseで大文字の16進数、terminator: falseで終端チャンクの省略を指定します"
+}
+
+func (f *ChunkedFunction) Execute(_ context.Context, args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("chunked function expects at least 1 argument")
+	}
+
+	// chunksを持つマップでない場合は、各引数をチャンクとして扱う
+	config, ok := args[0].(map[string]interface{})
+	if _, hasChunks := config["chunks"]; !ok || !hasChunks || len(args) != 1 {
+		config = map[string]interface{}{"chunks": args}
+	}
+
+	for key := range config {
+		switch key {
+		case "chunks", "trailers", "uppercase", "terminator":
+		default:
+			return nil, fmt.Errorf("chunked function does not support '%s' field", key)
+		}
+	}
+
+	chunks, ok := config["chunks"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("chunked function expects 'chunks' to be an array")
+	}
+	uppercase, err := chunkedBoolField(config, "uppercase", false)
+	if err != nil {
+		return nil, err
+	}
+	terminator, err := chunkedBoolField(config, "terminator", true)
+	if err != nil {
+		return nil, err
+	}
+
+	var body strings.Builder
+	for i, chunk := range chunks {
+		data, size, extension, err := parseChunk(chunk, uppercase)
+		if err != nil {
+			return nil, fmt.Errorf("chunked function: chunk %d: %w", i, err)
+		}
+		body.WriteString(size + extension + "\r\n" + data + "\r\n")
+	}
+
+	trailers, hasTrailers := config["trailers"]
+	if !terminator {
+		if hasTrailers {
+			return nil, fmt.Errorf("chunked function cannot send 'trailers' without the terminating chunk")
+		}
+		return body.String(), nil
+	}
+
+	// 終端チャンク、トレーラー、空行
+	body.WriteString("0\r\n")
+	if hasTrailers {
+		trailerMap, ok := trailers.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("chunked function expects 'trailers' to be map[string]interface{}")
+		}
+		names := make([]string, 0, len(trailerMap))
+		for name := range trailerMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			body.WriteString(fmt.Sprintf("%s: %v\r\n", name, trailerMap[name]))
+		}
+	}
+	body.WriteString("\r\n")
+	return body.String(), nil
+}
+
+// parseChunk はチャンクのデータ、サイズの行、拡張を返す。
+// sizeが数値の場合は16進数に変換し、文字列の場合はそのまま使う。省略した場合はデータの長さになる。
+func parseChunk(chunk interface{}, uppercase bool) (data string, size string, extension string, err error) {
+	var declared interface{}
+	switch c := chunk.(type) {
+	case string:
+		data = c
+	case map[string]interface{}:
+		for key := range c {
+			switch key {
+			case "data", "size", "extension":
+			default:
+				return "", "", "", fmt.Errorf("unsupported field '%s'", key)
+			}
+		}
+		if value, ok := c["data"]; ok && value != nil {
+			data = fmt.Sprintf("%v", value)
+		}
+		declared = c["size"]
+		if value, ok := c["extension"]; ok {
+			if extension, ok = value.(string); !ok {
+				return "", "", "", fmt.Errorf("extension must be a string")
+			}
+		}
+	default:
+		data = fmt.Sprintf("%v", c)
+	}
+
+	format := "%x"
+	if uppercase {
+		format = "%X"
+	}
+	switch s := declared.(type) {
+	case nil:
+		size = fmt.Sprintf(format, len(data))
+	case string:
+		size = s
+	case int:
+		if s < 0 {
+			return "", "", "", fmt.Errorf("size must not be negative, use a string such as \"-1\" to send it as written")
+		}
+		size = fmt.Sprintf(format, s)
+	case float64:
+		if s < 0 || s != float64(int64(s)) {
+			return "", "", "", fmt.Errorf("size must be a non-negative integer, use a string to send it as written")
+		}
+		size = fmt.Sprintf(format, int64(s))
+	default:
+		return "", "", "", fmt.Errorf("size must be a number or a string")
+	}
+	return data, size, extension, nil
+}
+
+// chunkedBoolField はマップのbool値を返す。キーがない場合はdefaultValueを返す。
+func chunkedBoolField(config map[string]interface{}, key string, defaultValue bool) (bool, error) {
+	value, ok := config[key]
+	if !ok {
+		return defaultValue, nil
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("chunked function expects '%s' to be a boolean", key)
+	}
+	return b, nil
+}
@@ -0,0 +1,101 @@
+package functions
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestChunkedFunction(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []interface{}
+		want    string
+		wantErr string
+	}{
+		{
+			name: "chunks as arguments",
+			args: []interface{}{"Wiki", "pedia in chunks"},
+			want: "4\r\nWiki\r\nf\r\npedia in chunks\r\n0\r\n\r\n",
+		},
+		{
+			name: "uppercase hex, extensions and trailers",
+			args: []interface{}{map[string]interface{}{
+				"chunks": []interface{}{
+					map[string]interface{}{"data": strings.Repeat("a", 26), "extension": ";name=value"},
+					"b",
+				},
+				"uppercase": true,
+				"trailers":  map[string]interface{}{"X-Trailer-B": "2", "X-Trailer-A": 1},
+			}},
+			want: "1A;name=value\r\n" + strings.Repeat("a", 26) + "\r\n1\r\nb\r\n0\r\nX-Trailer-A: 1\r\nX-Trailer-B: 2\r\n\r\n",
+		},
+		{
+			name: "wrong size declarations",
+			args: []interface{}{map[string]interface{}{
+				"chunks": []interface{}{
+					map[string]interface{}{"data": "hello", "size": 3},
+					map[string]interface{}{"data": "world", "size": float64(255)},
+					map[string]interface{}{"data": "!", "size": "0001 "},
+				},
+			}},
+			want: "3\r\nhello\r\nff\r\nworld\r\n0001 \r\n!\r\n0\r\n\r\n",
+		},
+		{
+			name: "missing terminating chunk",
+			args: []interface{}{map[string]interface{}{
+				"chunks":     []interface{}{"0\r\n\r\nGET /admin HTTP/1.1\r\n"},
+				"terminator": false,
+			}},
+			want: "1a\r\n0\r\n\r\nGET /admin HTTP/1.1\r\n\r\n",
+		},
+		{
+			name: "a single chunk object",
+			args: []interface{}{map[string]interface{}{"data": "abc", "size": "5"}},
+			want: "5\r\nabc\r\n0\r\n\r\n",
+		},
+		{
+			name:    "negative size",
+			args:    []interface{}{map[string]interface{}{"chunks": []interface{}{map[string]interface{}{"data": "a", "size": -1}}}},
+			wantErr: "chunk 0: size must not be negative",
+		},
+		{
+			name:    "trailers without the terminating chunk",
+			args:    []interface{}{map[string]interface{}{"chunks": []interface{}{"a"}, "terminator": false, "trailers": map[string]interface{}{"X": "1"}}},
+			wantErr: "cannot send 'trailers' without the terminating chunk",
+		},
+		{
+			name:    "unknown field",
+			args:    []interface{}{map[string]interface{}{"chunks": []interface{}{"a"}, "lowercase": true}},
+			wantErr: "does not support 'lowercase' field",
+		},
+		{
+			name:    "non-boolean option",
+			args:    []interface{}{map[string]interface{}{"chunks": []interface{}{"a"}, "uppercase": "yes"}},
+			wantErr: "expects 'uppercase' to be a boolean",
+		},
+		{
+			name:    "no arguments",
+			wantErr: "expects at least 1 argument",
+		},
+	}
+
+	fn := &ChunkedFunction{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fn.Execute(context.Background(), tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, result)
+			}
+		})
+	}
+}
@@ -39,9 +39,35 @@ func (f *FileFunction) Execute(ctx context.Context, args []interface{}) (interfa
 		return nil, fmt.Errorf("file function argument must be a string, got %T", args[0])
 	}
 
+	cleanPath, err := ResolveRelativePath(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the file
+	file, err := os.Open(cleanPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	// Read file content and return as string
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// Always return content as string
+	return string(content), nil
+}
+
+// ResolveRelativePath resolves a path relative to the request definition file
+// (or the working directory in stdin mode) and rejects absolute paths and
+// paths that escape that directory.
+func ResolveRelativePath(ctx context.Context, filePath string) (string, error) {
 	// Security check: reject absolute paths
 	if filepath.IsAbs(filePath) {
-		return nil, fmt.Errorf("absolute paths are not allowed")
+		return "", fmt.Errorf("absolute paths are not allowed")
 	}
 
 	// Determine the base directory for resolving relative paths
@@ -54,7 +80,7 @@ func (f *FileFunction) Execute(ctx context.Context, args []interface{}) (interfa
 		var err error
 		baseDir, err = os.Getwd()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get current working directory: %w", err)
+			return "", fmt.Errorf("failed to get current working directory: %w", err)
 		}
 	}
 
@@ -68,22 +94,8 @@ func (f *FileFunction) Execute(ctx context.Context, args []interface{}) (interfa
 	// Check if the clean path is within the base directory
 	relPath, err := filepath.Rel(cleanBase, cleanPath)
 	if err != nil || strings.HasPrefix(relPath, "..") {
-		return nil, fmt.Errorf("path escapes request file directory")
+		return "", fmt.Errorf("path escapes request file directory")
 	}
 
-	// Read the file
-	file, err := os.Open(cleanPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
-	// Read file content and return as string
-	content, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
-	}
-
-	// Always return content as string
-	return string(content), nil
+	return cleanPath, nil
 }
@@ -0,0 +1,164 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pluginTimeout はプラグインの1回の呼び出しにかける時間の上限
+const pluginTimeout = 10 * time.Second
+
+// pluginNamePattern はプラグインが宣言できる関数名
+var pluginNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// pluginRequest はプラグインの標準入力に渡すJSON
+type pluginRequest struct {
+	Method string        `json:"method"` // describe または execute
+	Args   []interface{} `json:"args"`   // describeではnull
+}
+
+// pluginResponse はプラグインが標準出力に返すJSON
+type pluginResponse struct {
+	Name        string      `json:"name"`
+	Signature   string      `json:"signature"`
+	Description string      `json:"description"`
+	Result      interface{} `json:"result"`
+	Error       string      `json:"error"`
+}
+
+// PluginFunction は外部の実行ファイルで実装された関数。
+// 呼び出しごとに実行ファイルを起動し、標準入力と標準出力でJSONをやり取りする。
+//
+//	{"method":"describe"}            -> {"name":"rot13","signature":"$rot13 <string>","description":"..."}
+//	{"method":"execute","args":[..]} -> {"result":...} または {"error":"..."}
+type PluginFunction struct {
+	path        string
+	name        string
+	signature   string
+	description string
+}
+
+// LoadPlugin はプラグインを起動して関数名、シグネチャ、説明を問い合わせる
+func LoadPlugin(ctx context.Context, path string) (*PluginFunction, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", path, err)
+	}
+	response, err := callPlugin(ctx, absPath, pluginRequest{Method: "describe"})
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: describe failed: %w", path, err)
+	}
+	if !pluginNamePattern.MatchString(response.Name) {
+		return nil, fmt.Errorf("plugin %s: invalid function name %q, expected lowercase letters, digits and underscores", path, response.Name)
+	}
+
+	fn := &PluginFunction{
+		path:        absPath,
+		name:        response.Name,
+		signature:   response.Signature,
+		description: response.Description,
+	}
+	if fn.signature == "" {
+		fn.signature = "$" + fn.name
+	}
+	return fn, nil
+}
+
+// FindPlugins はディレクトリ内の実行可能なファイルをプラグインとして名前順に返す。
+// ドットで始まるファイルは無視する。
+func FindPlugins(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+	var paths []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (f *PluginFunction) Name() string {
+	return f.name
+}
+
+func (f *PluginFunction) Signature() string {
+	return f.signature
+}
+
+func (f *PluginFunction) Description() string {
+	return f.description
+}
+
+// Path はプラグインの実行ファイルのパスを返す
+func (f *PluginFunction) Path() string {
+	return f.path
+}
+
+func (f *PluginFunction) Execute(ctx context.Context, args []interface{}) (interface{}, error) {
+	if args == nil {
+		args = []interface{}{}
+	}
+	response, err := callPlugin(ctx, f.path, pluginRequest{Method: "execute", Args: args})
+	if err != nil {
+		return nil, fmt.Errorf("%s function (plugin %s): %w", f.name, f.path, err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("%s function: %s", f.name, response.Error)
+	}
+	return response.Result, nil
+}
+
+// callPlugin はプラグインを1回起動し、リクエストを渡してレスポンスを読み取る
+func callPlugin(ctx context.Context, path string, request pluginRequest) (*pluginResponse, error) {
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pluginTimeout)
+	defer cancel()
+
+	// プラグインはコマンドラインやプラグインディレクトリで指定された実行ファイル
+	cmd := exec.CommandContext(ctx, path) // #nosec G204
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("timed out after %s", pluginTimeout)
+	}
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%w: %s", err, message)
+		}
+		return nil, err
+	}
+
+	var response pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("invalid JSON on stdout: %w", err)
+	}
+	return &response, nil
+}
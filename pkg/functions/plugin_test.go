@@ -0,0 +1,139 @@
+package functions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writePlugin はdirに実行可能なシェルスクリプトのプラグインを作成する
+func writePlugin(t *testing.T, dir, name, script string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), mode); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+	return path
+}
+
+// upperPlugin は引数の文字列を大文字にするプラグイン
+const upperPlugin = `input=$(cat)
+case "$input" in
+  *'"describe"'*) echo '{"name":"upper","signature":"$upper <string>","description":"Converts a string to upper case"}' ;;
+  *'"args":[]'*) echo '{"error":"upper requires 1 argument"}' ;;
+  *) printf '%s' "$input" | sed 's/.*"args":\["\(.*\)"\].*/\1/' | tr a-z A-Z | sed 's/.*/{"result":"&"}/' ;;
+esac`
+
+func TestPluginFunction(t *testing.T) {
+	dir := t.TempDir()
+	path := writePlugin(t, dir, "upper", upperPlugin, 0700)
+
+	fn, err := LoadPlugin(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Failed to load plugin: %v", err)
+	}
+	if fn.Name() != "upper" || fn.Signature() != "$upper <string>" || fn.Description() != "Converts a string to upper case" || fn.Path() != path {
+		t.Errorf("Unexpected plugin: %+v", fn)
+	}
+
+	result, err := fn.Execute(context.Background(), []interface{}{"abc"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != "ABC" {
+		t.Errorf("Expected %q, got %v", "ABC", result)
+	}
+
+	_, err = fn.Execute(context.Background(), nil)
+	if err == nil || err.Error() != "upper function: upper requires 1 argument" {
+		t.Errorf("Expected the plugin error, got %v", err)
+	}
+}
+
+func TestLoadPlugin_Errors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+	}{
+		{
+			name:    "exit status",
+			script:  `echo "missing dependency" >&2; exit 2`,
+			wantErr: "describe failed: exit status 2: missing dependency",
+		},
+		{
+			name:    "invalid JSON",
+			script:  `echo "upper"`,
+			wantErr: "describe failed: invalid JSON on stdout",
+		},
+		{
+			name:    "invalid name",
+			script:  `echo '{"name":"Bad-Name"}'`,
+			wantErr: `invalid function name "Bad-Name"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writePlugin(t, dir, strings.ReplaceAll(tt.name, " ", "_"), tt.script, 0700)
+			_, err := LoadPlugin(context.Background(), path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFindPlugins(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "b", "", 0700)
+	writePlugin(t, dir, "a", "", 0755)
+	writePlugin(t, dir, "README", "", 0644)
+	writePlugin(t, dir, ".hidden", "", 0700)
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	paths, err := FindPlugins(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Expected %v, got %v", want, paths)
+	}
+
+	if _, err := FindPlugins(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	registry := NewRegistry()
+	plugin := &PluginFunction{path: "/plugins/upper", name: "upper", signature: "$upper <string>"}
+	if err := registry.Register(plugin); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	found := false
+	for _, info := range registry.GetFunctionInfo() {
+		if info.Name == "upper" && info.Signature == "$upper <string>" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected the plugin in GetFunctionInfo")
+	}
+
+	err := registry.Register(&PluginFunction{path: "/other/upper", name: "upper"})
+	if err == nil || err.Error() != "function $upper is already defined by plugin /plugins/upper" {
+		t.Errorf("Expected a plugin conflict, got %v", err)
+	}
+	err = registry.Register(&PluginFunction{path: "/plugins/concat", name: "concat"})
+	if err == nil || err.Error() != "function $concat conflicts with a built-in function" {
+		t.Errorf("Expected a built-in conflict, got %v", err)
+	}
+}
@@ -70,6 +70,19 @@ func (r *Registry) registerBuiltinFunctions() {
 	r.functions["form"] = &FormFunction{}
 	r.functions["json"] = &JSONFunction{}
 	r.functions["multipart"] = &MultipartFunction{}
+	r.functions["chunked"] = &ChunkedFunction{}
+}
+
+// Register はプラグインなどの関数を追加する。同じ名前の関数が既にある場合はエラーを返す。
+func (r *Registry) Register(fn Function) error {
+	if existing, exists := r.functions[fn.Name()]; exists {
+		if plugin, ok := existing.(*PluginFunction); ok {
+			return fmt.Errorf("function $%s is already defined by plugin %s", fn.Name(), plugin.Path())
+		}
+		return fmt.Errorf("function $%s conflicts with a built-in function", fn.Name())
+	}
+	r.functions[fn.Name()] = fn
+	return nil
 }
 
 // Get は関数を取得